@@ -1,12 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 
 	"talpa/internal/app/analyze"
 	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/filesystem"
 )
 
 var analyzeDepth int
@@ -16,9 +24,17 @@ var analyzeMinSize int64
 var analyzeQuery string
 var analyzeOnlyCandidates bool
 var analyzeAction string
+var analyzeTUI bool
+var analyzeSizeMode string
+var analyzeDuplicates bool
+var analyzeRefresh bool
+var analyzeReport bool
+var analyzeSaveSnapshot string
+var analyzeDiff string
+var analyzeFormat string
 
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze [path]",
+	Use:   "analyze [path | --diff <snapshot> [snapshot]]",
 	Short: "Analyze disk usage under a path",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -26,7 +42,11 @@ var analyzeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		if analyzeDepth < 1 {
+		depth := analyzeDepth
+		if app.Profile != nil {
+			depth = profileInt(cmd, "depth", depth, app.Profile.Defaults.AnalyzeDepth)
+		}
+		if depth < 1 {
 			return fmt.Errorf("--depth must be >= 1")
 		}
 		if analyzeLimit < 0 {
@@ -42,8 +62,15 @@ var analyzeCmd = &cobra.Command{
 		}
 		switch analyzeAction {
 		case "inspect", "trash", "delete":
+		case "hardlink", "reflink":
+			if !analyzeDuplicates {
+				return fmt.Errorf("--action %s requires --duplicates", analyzeAction)
+			}
 		default:
-			return fmt.Errorf("--action must be one of: inspect, trash, delete")
+			return fmt.Errorf("--action must be one of: inspect, trash, delete, hardlink, reflink")
+		}
+		if err := filesystem.ValidateSizeMode(analyzeSizeMode); err != nil {
+			return fmt.Errorf("--size-mode must be one of: apparent, disk")
 		}
 
 		root := ""
@@ -51,29 +78,162 @@ var analyzeCmd = &cobra.Command{
 			root = args[0]
 		}
 
+		if analyzeFormat != "" {
+			if !slices.Contains(exportFormats, analyzeFormat) {
+				return fmt.Errorf("--format must be one of: %s", strings.Join(exportFormats, ", "))
+			}
+			if opts.JSON || analyzeTUI || analyzeReport || analyzeDiff != "" || analyzeAction != "inspect" {
+				return fmt.Errorf("--format cannot be combined with --json, --tui, --report, --diff, or --action")
+			}
+			if analyzeDuplicates && analyzeFormat != "csv" {
+				return fmt.Errorf("--duplicates only supports --format csv")
+			}
+		}
+
+		app.ScanProgress = scanProgressReporter()
 		svc := analyze.NewService()
+		if analyzeFormat == "ncdu" || analyzeFormat == "html" {
+			return exportTree(cmd, app, svc, root, depth)
+		}
+		if analyzeDiff != "" {
+			if analyzeTUI || analyzeDuplicates || analyzeReport || analyzeSaveSnapshot != "" || analyzeAction != "inspect" {
+				return fmt.Errorf("--diff cannot be combined with --tui, --duplicates, --report, --save-snapshot, or --action")
+			}
+			result, err := svc.Diff(cmd.Context(), app, analyzeDiff, root, analyze.DiffOptions{
+				Limit:    analyzeLimit,
+				SizeMode: analyzeSizeMode,
+				Refresh:  analyzeRefresh,
+			})
+			if err != nil {
+				return err
+			}
+			return printResult(result)
+		}
+		if analyzeSaveSnapshot != "" && (analyzeTUI || analyzeDuplicates || analyzeReport) {
+			return fmt.Errorf("--save-snapshot cannot be combined with --tui, --duplicates, or --report")
+		}
+		if analyzeDuplicates {
+			if analyzeTUI || analyzeReport {
+				return fmt.Errorf("--duplicates cannot be combined with --tui or --report")
+			}
+			result, err := svc.Duplicates(cmd.Context(), app, root, analyze.DuplicatesOptions{
+				MinSizeBytes: analyzeMinSize,
+				Limit:        analyzeLimit,
+				Action:       analyzeAction,
+				TrashDir:     app.Config.TrashDir,
+				SizeMode:     analyzeSizeMode,
+				Refresh:      analyzeRefresh,
+			})
+			if err != nil {
+				return err
+			}
+			return printAnalyzeResult(result)
+		}
+		if analyzeReport {
+			if analyzeTUI || analyzeAction != "inspect" {
+				return fmt.Errorf("--report cannot be combined with --tui or --action")
+			}
+			result, err := svc.Report(cmd.Context(), app, root, analyze.ReportOptions{
+				Limit:    analyzeLimit,
+				Query:    analyzeQuery,
+				SizeMode: analyzeSizeMode,
+				Refresh:  analyzeRefresh,
+			})
+			if err != nil {
+				return err
+			}
+			return printResult(result)
+		}
+		if analyzeTUI {
+			if analyzeAction != "inspect" {
+				return fmt.Errorf("--tui cannot be combined with --action; mark nodes inside the explorer instead")
+			}
+			return runExplorer(cmd, app, svc, root)
+		}
 		result, err := svc.Run(cmd.Context(), app, root, analyze.Options{
-			Depth:          analyzeDepth,
+			Depth:          depth,
 			Limit:          analyzeLimit,
 			SortBy:         analyzeSort,
 			MinSizeBytes:   analyzeMinSize,
 			Query:          analyzeQuery,
 			OnlyCandidates: analyzeOnlyCandidates,
 			Action:         analyzeAction,
+			TrashDir:       app.Config.TrashDir,
+			SizeMode:       analyzeSizeMode,
+			Refresh:        analyzeRefresh,
+			SaveSnapshot:   analyzeSaveSnapshot,
 		})
 		if err != nil {
 			return err
 		}
-		return printResult(result)
+		return printAnalyzeResult(result)
 	},
 }
 
 func init() {
-	analyzeCmd.Flags().IntVar(&analyzeDepth, "depth", 4, "Maximum scan depth")
-	analyzeCmd.Flags().IntVar(&analyzeLimit, "limit", 50, "Maximum number of nodes (0 = unlimited)")
+	analyzeCmd.Flags().IntVar(&analyzeDepth, "depth", 4, "Maximum tree depth to report (deeper sizes are folded into their ancestor)")
+	analyzeCmd.Flags().IntVar(&analyzeLimit, "limit", 50, "Maximum entries per directory level (0 = unlimited)")
 	analyzeCmd.Flags().StringVar(&analyzeSort, "sort", "size", "Sort by: size, path, mtime")
-	analyzeCmd.Flags().Int64Var(&analyzeMinSize, "min-size", 0, "Minimum item size in bytes")
-	analyzeCmd.Flags().StringVar(&analyzeQuery, "query", "", "Filter item paths by substring")
+	analyzeCmd.Flags().Var(newSizeFlag(&analyzeMinSize, 0), "min-size", "Minimum node size, e.g. 500M or 2GiB (bare numbers are bytes), applied per level")
+	analyzeCmd.Flags().StringVar(&analyzeQuery, "query", "", "Filter expression, e.g. 'ext in (mp4,mkv) and size > 1GB and mtime > 6mo' (a bare word matches a path substring)")
 	analyzeCmd.Flags().BoolVar(&analyzeOnlyCandidates, "only-candidates", false, "Show only cleanup candidates")
-	analyzeCmd.Flags().StringVar(&analyzeAction, "action", "inspect", "Action mode: inspect, trash, delete")
+	analyzeCmd.Flags().StringVar(&analyzeAction, "action", "inspect", "Action mode: inspect, trash, delete (with --duplicates also hardlink, reflink)")
+	analyzeCmd.Flags().BoolVar(&analyzeTUI, "tui", false, "Browse the scan in a full-screen explorer")
+	analyzeCmd.Flags().BoolVar(&analyzeDuplicates, "duplicates", false, "Report sets of identical files instead of the directory tree")
+	analyzeCmd.Flags().BoolVar(&analyzeReport, "report", false, "Summarize files as histograms by type, extension, age, and owner")
+	analyzeCmd.Flags().StringVar(&analyzeSaveSnapshot, "save-snapshot", "", "Save the scanned tree under this name for a later --diff")
+	analyzeCmd.Flags().StringVar(&analyzeDiff, "diff", "", "Compare a saved snapshot with a second snapshot given as the argument, or with a fresh scan")
+	analyzeCmd.Flags().StringVar(&analyzeFormat, "format", "", "Export instead of printing: ncdu (for ncdu -f), csv, or html (offline treemap)")
+	analyzeCmd.Flags().BoolVar(&analyzeRefresh, "refresh", false, "Ignore the scan index and walk every directory again")
+	analyzeCmd.Flags().StringVar(&analyzeSizeMode, "size-mode", filesystem.SizeModeApparent, "Size accounting: apparent (file length) or disk (allocated blocks)")
+}
+
+func runExplorer(cmd *cobra.Command, app *common.AppContext, svc analyze.Service, root string) error {
+	ctx := cmd.Context()
+	tree, err := svc.Tree(ctx, app, root, 0, analyzeRefresh)
+	var partial *filesystem.PartialScanError
+	if err != nil && !errors.As(err, &partial) {
+		return err
+	}
+	act := func(nodes []*filesystem.TreeNode, action, confirm string) (model.CommandResult, error) {
+		actApp := *app
+		actApp.Options.Yes = true
+		actApp.Options.Confirm = confirm
+		return svc.ActOn(ctx, &actApp, tree.Path, nodes, analyze.ActOptions{
+			Action:   action,
+			TrashDir: app.Config.TrashDir,
+			SizeMode: analyzeSizeMode,
+		})
+	}
+	explorer := newExplorerModel(tree, analyzeSizeMode, act)
+	if partial != nil {
+		explorer.status = fmt.Sprintf("Scan timed out: %d directories were not visited, sizes are incomplete", len(partial.Unvisited))
+	}
+	_, err = tea.NewProgram(explorer, tea.WithAltScreen()).Run()
+	return err
+}
+
+func printAnalyzeResult(result model.CommandResult) error {
+	if analyzeFormat == "csv" {
+		if result.Partial {
+			fmt.Fprintf(os.Stderr, "warning: scan timed out with %d directories not visited; export is incomplete\n", len(result.Unvisited))
+		}
+		return writeCSV(os.Stdout, result)
+	}
+	return printResult(result)
+}
+
+func exportTree(cmd *cobra.Command, app *common.AppContext, svc analyze.Service, root string, depth int) error {
+	tree, err := svc.Tree(cmd.Context(), app, root, 0, analyzeRefresh)
+	var partial *filesystem.PartialScanError
+	if err != nil && !errors.As(err, &partial) {
+		return err
+	}
+	if partial != nil {
+		fmt.Fprintf(os.Stderr, "warning: scan timed out with %d directories not visited; export is incomplete\n", len(partial.Unvisited))
+	}
+	if analyzeFormat == "ncdu" {
+		return writeNcdu(os.Stdout, tree, time.Now())
+	}
+	return writeTreemapHTML(os.Stdout, tree, analyzeSizeMode, depth, analyzeLimit, time.Now())
 }
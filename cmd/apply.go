@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"talpa/internal/app/apply"
+	"talpa/internal/app/common"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan-id>",
+	Short: "Execute a plan saved by a previous --dry-run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := common.FromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		svc := apply.NewService()
+		result, err := svc.Run(cmd.Context(), app, args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	},
+}
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 
 	"talpa/internal/app/clean"
 	"talpa/internal/app/common"
+	"talpa/internal/infra/filesystem"
 )
 
 var cleanSystem bool
+var cleanSizeMode string
 
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
@@ -18,8 +22,17 @@ var cleanCmd = &cobra.Command{
 			return err
 		}
 
+		system := cleanSystem
+		if app.Profile != nil {
+			system = profileBool(cmd, "system", system, app.Profile.Defaults.System)
+		}
+
+		if err := filesystem.ValidateSizeMode(cleanSizeMode); err != nil {
+			return fmt.Errorf("--size-mode must be one of: apparent, disk")
+		}
+
 		svc := clean.NewService()
-		result, err := svc.Run(cmd.Context(), app, clean.Options{System: cleanSystem})
+		result, err := svc.Run(cmd.Context(), app, clean.Options{System: system, SizeMode: cleanSizeMode})
 		if err != nil {
 			return err
 		}
@@ -29,4 +42,5 @@ var cleanCmd = &cobra.Command{
 
 func init() {
 	cleanCmd.Flags().BoolVar(&cleanSystem, "system", false, "Include opt-in system-level cleanup candidates")
+	cleanCmd.Flags().StringVar(&cleanSizeMode, "size-mode", filesystem.SizeModeApparent, "Size accounting: apparent (file length) or disk (allocated blocks)")
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"talpa/internal/app/common"
+	"talpa/internal/app/settings"
+)
+
+var configEffective bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect Talpa configuration",
+	Args:  cobra.NoArgs,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show configured values and where each one came from",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := common.FromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		svc := settings.NewService()
+		result, err := svc.Show(cmd.Context(), app, settings.Options{Effective: configEffective})
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	configShowCmd.Flags().BoolVar(&configEffective, "effective", false, "Include defaults to show every merged value (system, user, env, flags)")
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"talpa/internal/app/analyze"
+)
+
+func writeDiff(b *strings.Builder, m analyze.DiffMetrics, bold func(string) string) {
+	fmt.Fprintf(b, "%s  %s (%s) -> %s (%s)\n", m.Root, m.From, m.FromTime.Local().Format(time.DateTime), m.To, m.ToTime.Local().Format(time.DateTime))
+	fmt.Fprintf(b, "%s -> %s  %s\n", humanBytes(m.BeforeBytes), humanBytes(m.AfterBytes), signedBytes(m.DeltaBytes))
+
+	deltaSection := func(title string, list []analyze.PathDelta) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintln(b)
+		fmt.Fprintln(b, bold(title))
+		for _, d := range list {
+			path := d.Path
+			if d.Kind == "dir" {
+				path += "/"
+			}
+			fmt.Fprintf(b, "  %11s  %10s -> %-10s  %s\n", signedBytes(d.DeltaBytes), humanBytes(d.BeforeBytes), humanBytes(d.AfterBytes), path)
+		}
+	}
+	fileSection := func(title, sign string, list []analyze.FileChange) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintln(b)
+		fmt.Fprintln(b, bold(title))
+		for _, f := range list {
+			fmt.Fprintf(b, "  %s%10s  %s\n", sign, humanBytes(f.SizeBytes), f.Path)
+		}
+	}
+	deltaSection("Grew", m.Grew)
+	deltaSection("Shrank", m.Shrank)
+	fileSection("Appeared", "+", m.Appeared)
+	fileSection("Disappeared", "-", m.Disappeared)
+}
+
+func signedBytes(n int64) string {
+	if n < 0 {
+		return "-" + humanBytes(-n)
+	}
+	return "+" + humanBytes(n)
+}
@@ -0,0 +1,401 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/filesystem"
+)
+
+const (
+	explorerBarWidth   = 20
+	explorerHighRisk   = "HIGH-RISK"
+	explorerPageHeight = 20
+)
+
+var explorerSortModes = []string{"size", "name", "mtime"}
+
+type explorerActFunc func(nodes []*filesystem.TreeNode, action, confirm string) (model.CommandResult, error)
+
+type explorerActDoneMsg struct {
+	action string
+	nodes  []*filesystem.TreeNode
+	result model.CommandResult
+	err    error
+}
+
+type explorerModel struct {
+	root      *filesystem.TreeNode
+	parents   map[*filesystem.TreeNode]*filesystem.TreeNode
+	dir       *filesystem.TreeNode
+	cursor    int
+	offset    int
+	sortMode  int
+	sizeMode  string
+	filter    string
+	filtering bool
+	marked    map[*filesystem.TreeNode]bool
+	confirm   string
+	input     string
+	status    string
+	busy      bool
+	act       explorerActFunc
+}
+
+func newExplorerModel(root *filesystem.TreeNode, sizeMode string, act explorerActFunc) explorerModel {
+	m := explorerModel{
+		root:     root,
+		parents:  map[*filesystem.TreeNode]*filesystem.TreeNode{},
+		dir:      root,
+		sizeMode: sizeMode,
+		marked:   map[*filesystem.TreeNode]bool{},
+		act:      act,
+	}
+	var index func(n *filesystem.TreeNode)
+	index = func(n *filesystem.TreeNode) {
+		for _, c := range n.Children {
+			m.parents[c] = n
+			index(c)
+		}
+	}
+	index(root)
+	return m
+}
+
+func (m explorerModel) Init() tea.Cmd { return nil }
+
+func (m explorerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case explorerActDoneMsg:
+		return m.finishAction(msg), nil
+	case tea.KeyMsg:
+		if m.busy {
+			return m, nil
+		}
+		if m.confirm != "" {
+			return m.updateConfirm(msg)
+		}
+		if m.filtering {
+			return m.updateFilter(msg), nil
+		}
+		return m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+func (m explorerModel) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	entries := m.entries()
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(entries)-1 {
+			m.cursor++
+		}
+	case "enter", "right", "l":
+		if m.cursor < len(entries) && entries[m.cursor].IsDir {
+			m.dir = entries[m.cursor]
+			m.cursor, m.offset, m.filter = 0, 0, ""
+		}
+	case "backspace", "left", "h":
+		if parent, ok := m.parents[m.dir]; ok {
+			from := m.dir
+			m.dir = parent
+			m.filter = ""
+			m.cursor, m.offset = 0, 0
+			for i, e := range m.entries() {
+				if e == from {
+					m.cursor = i
+				}
+			}
+		}
+	case "s":
+		m.sortMode = (m.sortMode + 1) % len(explorerSortModes)
+		m.cursor, m.offset = 0, 0
+	case "/":
+		m.filtering = true
+	case " ":
+		if m.cursor < len(entries) {
+			n := entries[m.cursor]
+			if m.marked[n] {
+				delete(m.marked, n)
+			} else {
+				m.marked[n] = true
+			}
+			if m.cursor < len(entries)-1 {
+				m.cursor++
+			}
+		}
+	case "t":
+		if len(m.marked) > 0 {
+			m.confirm, m.input = "trash", ""
+		}
+	case "D":
+		if len(m.marked) > 0 {
+			m.confirm, m.input = "delete", ""
+		}
+	}
+	m.scrollToCursor()
+	return m, nil
+}
+
+func (m explorerModel) updateFilter(msg tea.KeyMsg) explorerModel {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.filter = ""
+		m.filtering = false
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyBackspace:
+		if r := []rune(m.filter); len(r) > 0 {
+			m.filter = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.filter += string(msg.Runes)
+	}
+	m.cursor, m.offset = 0, 0
+	return m
+}
+
+func (m explorerModel) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.confirm, m.input = "", ""
+		m.status = "Cancelled"
+		return m, nil
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+		return m, nil
+	case tea.KeyRunes:
+		if m.confirm == "trash" {
+			if strings.EqualFold(string(msg.Runes), "y") {
+				return m.startAction("")
+			}
+			m.confirm = ""
+			m.status = "Cancelled"
+			return m, nil
+		}
+		m.input += string(msg.Runes)
+		return m, nil
+	case tea.KeyEnter:
+		if m.confirm == "delete" {
+			return m.startAction(m.input)
+		}
+	}
+	return m, nil
+}
+
+func (m explorerModel) startAction(confirm string) (tea.Model, tea.Cmd) {
+	action := m.confirm
+	nodes := m.markedNodes()
+	m.confirm, m.input = "", ""
+	m.busy = true
+	m.status = fmt.Sprintf("Running %s on %d item(s)...", action, len(nodes))
+	act := m.act
+	return m, func() tea.Msg {
+		res, err := act(nodes, action, confirm)
+		return explorerActDoneMsg{action: action, nodes: nodes, result: res, err: err}
+	}
+}
+
+func (m explorerModel) finishAction(msg explorerActDoneMsg) explorerModel {
+	m.busy = false
+	if msg.err != nil {
+		m.status = "Error: " + msg.err.Error()
+		return m
+	}
+	byPath := make(map[string]*filesystem.TreeNode, len(msg.nodes))
+	for _, n := range msg.nodes {
+		byPath[n.Path] = n
+	}
+	removed := 0
+	for _, it := range msg.result.Items {
+		n := byPath[it.Path]
+		if n == nil {
+			continue
+		}
+		delete(m.marked, n)
+		if it.Result == "deleted" || it.Result == "trashed" {
+			m.detach(n)
+			removed++
+		}
+	}
+	if msg.result.DryRun {
+		m.status = fmt.Sprintf("Dry run: %d item(s) would be %s (%s)", msg.result.Summary.ItemsSelected, pastTense(msg.action), humanBytes(msg.result.Summary.EstimatedFreedBytes))
+	} else {
+		m.status = fmt.Sprintf("%d item(s) %s, %s freed, %d error(s)", removed, pastTense(msg.action), humanBytes(msg.result.Summary.EstimatedFreedBytes), msg.result.Summary.Errors)
+	}
+	if entries := m.entries(); m.cursor >= len(entries) {
+		m.cursor = len(entries) - 1
+		if m.cursor < 0 {
+			m.cursor = 0
+		}
+	}
+	m.scrollToCursor()
+	return m
+}
+
+func (m *explorerModel) detach(n *filesystem.TreeNode) {
+	parent, ok := m.parents[n]
+	if !ok {
+		return
+	}
+	for i, c := range parent.Children {
+		if c == n {
+			parent.Children = append(parent.Children[:i:i], parent.Children[i+1:]...)
+			break
+		}
+	}
+	for p := parent; p != nil; p = m.parents[p] {
+		p.SizeBytes -= n.SizeBytes
+		p.AllocatedBytes -= n.AllocatedBytes
+		p.FileCount -= n.FileCount
+		if p == m.root {
+			break
+		}
+	}
+	for cur := m.dir; cur != nil; cur = m.parents[cur] {
+		if cur == n {
+			m.dir = parent
+			m.cursor, m.offset = 0, 0
+			break
+		}
+		if cur == m.root {
+			break
+		}
+	}
+	delete(m.parents, n)
+}
+
+func (m explorerModel) markedNodes() []*filesystem.TreeNode {
+	out := make([]*filesystem.TreeNode, 0, len(m.marked))
+	for n := range m.marked {
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+func (m explorerModel) entries() []*filesystem.TreeNode {
+	q := strings.ToLower(m.filter)
+	out := make([]*filesystem.TreeNode, 0, len(m.dir.Children))
+	for _, c := range m.dir.Children {
+		if q != "" && !strings.Contains(strings.ToLower(filepath.Base(c.Path)), q) {
+			continue
+		}
+		out = append(out, c)
+	}
+	mode := explorerSortModes[m.sortMode]
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		switch mode {
+		case "mtime":
+			if !a.LastModified.Equal(b.LastModified) {
+				return a.LastModified.After(b.LastModified)
+			}
+		case "size":
+			if a.Bytes(m.sizeMode) != b.Bytes(m.sizeMode) {
+				return a.Bytes(m.sizeMode) > b.Bytes(m.sizeMode)
+			}
+		}
+		return a.Path < b.Path
+	})
+	return out
+}
+
+func (m *explorerModel) scrollToCursor() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+explorerPageHeight {
+		m.offset = m.cursor - explorerPageHeight + 1
+	}
+}
+
+func (m explorerModel) View() string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39")).Render("Talpa Explorer")
+	header := fmt.Sprintf("%s  %s in %d file(s)  sort: %s", m.dir.Path, humanBytes(m.dir.Bytes(m.sizeMode)), m.dir.FileCount, explorerSortModes[m.sortMode])
+	lines := []string{title, header}
+	if m.filtering || m.filter != "" {
+		lines = append(lines, "filter: "+m.filter)
+	}
+	lines = append(lines, "")
+
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
+	markStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	entries := m.entries()
+	if len(entries) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("  (empty)"))
+	}
+	end := m.offset + explorerPageHeight
+	if end > len(entries) {
+		end = len(entries)
+	}
+	for i := m.offset; i < end; i++ {
+		n := entries[i]
+		cursor, mark := "  ", " "
+		if m.marked[n] {
+			mark = markStyle.Render("*")
+		}
+		name := filepath.Base(n.Path)
+		if n.IsDir {
+			name += "/"
+		}
+		pct := 0.0
+		if total := m.dir.Bytes(m.sizeMode); total > 0 {
+			pct = float64(n.Bytes(m.sizeMode)) / float64(total)
+		}
+		row := fmt.Sprintf("%10s %5.1f%% [%s] %s", humanBytes(n.Bytes(m.sizeMode)), pct*100, sizeBar(pct, explorerBarWidth), name)
+		if i == m.cursor {
+			cursor = "> "
+			row = selectedStyle.Render(row)
+		}
+		lines = append(lines, cursor+mark+row)
+	}
+
+	lines = append(lines, "")
+	switch {
+	case m.confirm == "trash":
+		lines = append(lines, fmt.Sprintf("Move %d marked item(s) to trash? [y/N]", len(m.marked)))
+	case m.confirm == "delete":
+		lines = append(lines, fmt.Sprintf("Permanently delete %d marked item(s). Type %s and press Enter: %s", len(m.marked), explorerHighRisk, m.input))
+	case m.status != "":
+		lines = append(lines, m.status)
+	}
+	hint := "↑/↓ move  enter/→ open  ←/backspace up  s sort  / filter  space mark  t trash  D delete  q quit"
+	if len(m.marked) > 0 {
+		hint = fmt.Sprintf("%d marked  ", len(m.marked)) + hint
+	}
+	lines = append(lines, lipgloss.NewStyle().Faint(true).Render(hint))
+	return lipgloss.NewStyle().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+func sizeBar(frac float64, width int) string {
+	filled := int(frac*float64(width) + 0.5)
+	if filled > width {
+		filled = width
+	}
+	if filled < 0 {
+		filled = 0
+	}
+	return strings.Repeat("#", filled) + strings.Repeat(" ", width-filled)
+}
+
+func pastTense(action string) string {
+	if action == "trash" {
+		return "trashed"
+	}
+	return "deleted"
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/filesystem"
+)
+
+func testExplorerTree() *filesystem.TreeNode {
+	modules := &filesystem.TreeNode{Path: "/r/app/node_modules", IsDir: true, SizeBytes: 600, FileCount: 6}
+	src := &filesystem.TreeNode{Path: "/r/app/src", IsDir: true, SizeBytes: 100, FileCount: 2}
+	app := &filesystem.TreeNode{Path: "/r/app", IsDir: true, SizeBytes: 700, FileCount: 8, Children: []*filesystem.TreeNode{src, modules}}
+	notes := &filesystem.TreeNode{Path: "/r/notes.txt", SizeBytes: 300, FileCount: 1}
+	return &filesystem.TreeNode{Path: "/r", IsDir: true, SizeBytes: 1000, FileCount: 9, Children: []*filesystem.TreeNode{notes, app}}
+}
+
+func press(t *testing.T, m explorerModel, keys ...tea.KeyMsg) explorerModel {
+	t.Helper()
+	for _, k := range keys {
+		updated, cmd := m.Update(k)
+		m = updated.(explorerModel)
+		if cmd != nil {
+			if msg := cmd(); msg != nil {
+				if _, quit := msg.(tea.QuitMsg); !quit {
+					updated, _ = m.Update(msg)
+					m = updated.(explorerModel)
+				}
+			}
+		}
+	}
+	return m
+}
+
+func runes(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+func TestExplorerDrillSortAndFilter(t *testing.T) {
+	m := newExplorerModel(testExplorerTree(), "", nil)
+	if e := m.entries(); e[0].Path != "/r/app" {
+		t.Fatalf("expected size sort to put app first, got %s", e[0].Path)
+	}
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.dir.Path != "/r/app" || m.entries()[0].Path != "/r/app/node_modules" {
+		t.Fatalf("expected to drill into app, got %s", m.dir.Path)
+	}
+	view := m.View()
+	if !strings.Contains(view, "85.7%") || !strings.Contains(view, "node_modules/") {
+		t.Fatalf("expected percentage and dir name in view:\n%s", view)
+	}
+
+	m = press(t, m, runes("s"))
+	if e := m.entries(); e[0].Path != "/r/app/node_modules" || explorerSortModes[m.sortMode] != "name" {
+		t.Fatalf("expected name sort, got %s by %s", e[0].Path, explorerSortModes[m.sortMode])
+	}
+	m = press(t, m, runes("/"), runes("s"), runes("r"), tea.KeyMsg{Type: tea.KeyEnter})
+	if e := m.entries(); len(e) != 1 || e[0].Path != "/r/app/src" {
+		t.Fatalf("expected filter to keep src only, got %+v", e)
+	}
+
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyLeft})
+	if m.dir.Path != "/r" || m.filter != "" || m.entries()[m.cursor].Path != "/r/app" {
+		t.Fatalf("expected to return to root with cursor on app, got %s", m.dir.Path)
+	}
+}
+
+func TestExplorerDeleteRequiresTypedConfirmation(t *testing.T) {
+	var gotConfirm, gotAction string
+	act := func(nodes []*filesystem.TreeNode, action, confirm string) (model.CommandResult, error) {
+		gotAction, gotConfirm = action, confirm
+		res := model.CommandResult{Summary: model.Summary{ItemsSelected: len(nodes)}}
+		for _, n := range nodes {
+			res.Items = append(res.Items, model.CandidateItem{Path: n.Path, Result: "deleted"})
+			res.Summary.EstimatedFreedBytes += n.SizeBytes
+		}
+		return res, nil
+	}
+	m := newExplorerModel(testExplorerTree(), "", act)
+	m = press(t, m, tea.KeyMsg{Type: tea.KeyEnter}, tea.KeyMsg{Type: tea.KeySpace})
+	if len(m.marked) != 1 {
+		t.Fatalf("expected one marked node")
+	}
+	m = press(t, m, runes("D"))
+	if m.confirm != "delete" || !strings.Contains(m.View(), "Type HIGH-RISK") {
+		t.Fatalf("expected delete confirmation prompt")
+	}
+	m = press(t, m, runes("HIGH-RISK"), tea.KeyMsg{Type: tea.KeyEnter})
+
+	if gotAction != "delete" || gotConfirm != "HIGH-RISK" {
+		t.Fatalf("expected confirmation to be forwarded, got %q %q", gotAction, gotConfirm)
+	}
+	if len(m.marked) != 0 || len(m.dir.Children) != 1 {
+		t.Fatalf("expected deleted node to be removed, got %+v", m.dir.Children)
+	}
+	if m.root.SizeBytes != 400 || m.dir.SizeBytes != 100 || m.root.FileCount != 3 {
+		t.Fatalf("expected ancestor totals to shrink, got root=%d app=%d", m.root.SizeBytes, m.dir.SizeBytes)
+	}
+}
+
+func TestExplorerTrashCancel(t *testing.T) {
+	called := false
+	act := func([]*filesystem.TreeNode, string, string) (model.CommandResult, error) {
+		called = true
+		return model.CommandResult{}, nil
+	}
+	m := newExplorerModel(testExplorerTree(), "", act)
+	m = press(t, m, tea.KeyMsg{Type: tea.KeySpace}, runes("t"), runes("n"))
+	if called || m.confirm != "" || m.status != "Cancelled" {
+		t.Fatalf("expected trash to be cancelled, called=%v status=%q", called, m.status)
+	}
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"time"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/filesystem"
+)
+
+var exportFormats = []string{"ncdu", "csv", "html"}
+
+var csvHeader = []string{"path", "kind", "depth", "size_bytes", "file_count", "last_modified", "category", "risk", "selected", "result", "shared_links", "duplicate_of", "reason"}
+
+type ncduInfo struct {
+	Name  string `json:"name"`
+	Asize int64  `json:"asize,omitempty"`
+	Dsize int64  `json:"dsize,omitempty"`
+	Dev   uint64 `json:"dev,omitempty"`
+	Ino   uint64 `json:"ino,omitempty"`
+	Hlnkc bool   `json:"hlnkc,omitempty"`
+	Nlink uint64 `json:"nlink,omitempty"`
+	Mtime int64  `json:"mtime,omitempty"`
+}
+
+type treemapNode struct {
+	Name     string         `json:"n"`
+	Size     int64          `json:"s"`
+	Children []*treemapNode `json:"c,omitempty"`
+}
+
+func programVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+func writeCSV(w io.Writer, r model.CommandResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, it := range r.Items {
+		modified := ""
+		if !it.LastModified.IsZero() {
+			modified = it.LastModified.UTC().Format(time.RFC3339)
+		}
+		record := []string{
+			it.Path,
+			it.Kind,
+			strconv.Itoa(it.Depth),
+			strconv.FormatInt(it.SizeBytes, 10),
+			strconv.FormatInt(it.FileCount, 10),
+			modified,
+			it.Category,
+			string(it.Risk),
+			strconv.FormatBool(it.Selected),
+			it.Result,
+			strconv.FormatInt(it.SharedLinks, 10),
+			it.DuplicateOf,
+			it.Reason,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeNcdu(w io.Writer, root *filesystem.TreeNode, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	meta, err := json.Marshal(map[string]any{"progname": "talpa", "progver": programVersion(), "timestamp": now.Unix()})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(bw, "[1,2,%s,\n", meta)
+	if err := writeNcduNode(bw, root, root.Path, 0); err != nil {
+		return err
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+func writeNcduNode(bw *bufio.Writer, n *filesystem.TreeNode, name string, parentDev uint64) error {
+	info := ncduInfo{Name: name, Ino: n.Inode}
+	if !n.LastModified.IsZero() {
+		info.Mtime = n.LastModified.Unix()
+	}
+	if !n.IsDir {
+		info.Asize, info.Dsize = n.SizeBytes, n.AllocatedBytes
+		if n.Links > 1 {
+			info.Hlnkc, info.Nlink = true, n.Links
+		}
+		b, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		_, err = bw.Write(b)
+		return err
+	}
+	if n.Device != parentDev {
+		info.Dev = n.Device
+	}
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	bw.WriteByte('[')
+	bw.Write(b)
+	children := append([]*filesystem.TreeNode(nil), n.Children...)
+	sort.Slice(children, func(i, j int) bool { return children[i].Path < children[j].Path })
+	for _, c := range children {
+		bw.WriteString(",\n")
+		if err := writeNcduNode(bw, c, filepath.Base(c.Path), n.Device); err != nil {
+			return err
+		}
+	}
+	_, err = bw.WriteString("]")
+	return err
+}
+
+func buildTreemap(n *filesystem.TreeNode, name, sizeMode string, depth, limit int) *treemapNode {
+	out := &treemapNode{Name: name, Size: n.Bytes(sizeMode)}
+	if depth <= 0 || len(n.Children) == 0 {
+		return out
+	}
+	children := append([]*filesystem.TreeNode(nil), n.Children...)
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Bytes(sizeMode) != children[j].Bytes(sizeMode) {
+			return children[i].Bytes(sizeMode) > children[j].Bytes(sizeMode)
+		}
+		return children[i].Path < children[j].Path
+	})
+	shown := children
+	if limit > 0 && len(children) > limit {
+		shown = children[:limit]
+	}
+	for _, c := range shown {
+		if c.Bytes(sizeMode) <= 0 {
+			continue
+		}
+		label := filepath.Base(c.Path)
+		if c.IsDir {
+			label += "/"
+		}
+		out.Children = append(out.Children, buildTreemap(c, label, sizeMode, depth-1, limit))
+	}
+	if rest := children[len(shown):]; len(rest) > 0 {
+		var size int64
+		for _, c := range rest {
+			size += c.Bytes(sizeMode)
+		}
+		if size > 0 {
+			out.Children = append(out.Children, &treemapNode{Name: fmt.Sprintf("(%d more)", len(rest)), Size: size})
+		}
+	}
+	return out
+}
+
+func writeTreemapHTML(w io.Writer, root *filesystem.TreeNode, sizeMode string, depth, limit int, now time.Time) error {
+	mode := sizeMode
+	if mode == "" {
+		mode = filesystem.SizeModeApparent
+	}
+	return treemapTemplate.Execute(w, map[string]any{
+		"Root":      root.Path,
+		"Total":     humanBytes(root.Bytes(sizeMode)),
+		"SizeMode":  mode,
+		"Generated": now.UTC().Format(time.RFC3339),
+		"Data":      buildTreemap(root, root.Path, sizeMode, depth, limit),
+	})
+}
+
+var treemapTemplate = template.Must(template.New("treemap").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Root}} - talpa disk usage</title>
+<style>
+body{margin:0;font:13px system-ui,sans-serif;background:#1e1e1e;color:#ddd;display:flex;flex-direction:column;height:100vh}
+header{padding:8px 12px;background:#2a2a2a}
+header h1{font-size:15px;margin:0 0 4px}
+#crumbs span{cursor:pointer;color:#8ab4f8}
+#crumbs span:hover{text-decoration:underline}
+#map{position:relative;flex:1;margin:8px;overflow:hidden}
+.cell{position:absolute;box-sizing:border-box;border:1px solid #1e1e1e;overflow:hidden;padding:2px 4px;color:#111;cursor:pointer;white-space:nowrap;text-overflow:ellipsis}
+.cell:hover{filter:brightness(1.15)}
+#tip{position:fixed;pointer-events:none;background:#000c;color:#fff;padding:4px 8px;border-radius:3px;display:none}
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Root}}</h1>
+<div>{{.Total}} {{.SizeMode}} &middot; generated {{.Generated}} by talpa</div>
+<div id="crumbs"></div>
+</header>
+<div id="map"></div>
+<div id="tip"></div>
+<script>
+var data = {{.Data}};
+var palette = ["#8dd3c7","#ffffb3","#bebada","#fb8072","#80b1d3","#fdb462","#b3de69","#fccde5","#d9d9d9","#bc80bd","#ccebc5","#ffed6f"];
+var mapEl = document.getElementById("map"), tipEl = document.getElementById("tip"), crumbsEl = document.getElementById("crumbs");
+var stack = [data];
+
+function human(n) {
+  var units = ["B","KiB","MiB","GiB","TiB","PiB"], i = 0;
+  while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+  return (i ? n.toFixed(1) : n) + " " + units[i];
+}
+
+function worst(row, side) {
+  var sum = 0, lo = Infinity, hi = 0;
+  row.forEach(function (r) { sum += r.a; lo = Math.min(lo, r.a); hi = Math.max(hi, r.a); });
+  return Math.max(side * side * hi / (sum * sum), sum * sum / (side * side * lo));
+}
+
+function squarify(items, x, y, w, h, out) {
+  var row = [];
+  while (items.length) {
+    var side = Math.min(w, h), next = row.concat([items[0]]);
+    if (!row.length || worst(next, side) <= worst(row, side)) { row = next; items = items.slice(1); continue; }
+    var r = layoutRow(row, x, y, w, h, out); x = r[0]; y = r[1]; w = r[2]; h = r[3]; row = [];
+  }
+  if (row.length) layoutRow(row, x, y, w, h, out);
+}
+
+function layoutRow(row, x, y, w, h, out) {
+  var sum = 0;
+  row.forEach(function (r) { sum += r.a; });
+  if (w >= h) {
+    var rw = sum / h, cy = y;
+    row.forEach(function (r) { var rh = r.a / rw; out.push({n: r.n, x: x, y: cy, w: rw, h: rh}); cy += rh; });
+    return [x + rw, y, w - rw, h];
+  }
+  var rh = sum / w, cx = x;
+  row.forEach(function (r) { var cw = r.a / rh; out.push({n: r.n, x: cx, y: y, w: cw, h: rh}); cx += cw; });
+  return [x, y + rh, w, h - rh];
+}
+
+function render() {
+  var node = stack[stack.length - 1], W = mapEl.clientWidth, H = mapEl.clientHeight;
+  mapEl.innerHTML = "";
+  crumbsEl.innerHTML = "";
+  stack.forEach(function (n, i) {
+    var s = document.createElement("span");
+    s.textContent = n.n;
+    s.onclick = function () { stack = stack.slice(0, i + 1); render(); };
+    crumbsEl.appendChild(s);
+    if (i < stack.length - 1) crumbsEl.appendChild(document.createTextNode(" / "));
+  });
+  var kids = (node.c || []).filter(function (c) { return c.s > 0; });
+  if (!kids.length) kids = [node];
+  var total = 0;
+  kids.forEach(function (c) { total += c.s; });
+  var items = kids.map(function (c) { return {n: c, a: c.s / total * W * H}; });
+  var rects = [];
+  squarify(items, 0, 0, W, H, rects);
+  rects.forEach(function (r, i) {
+    var el = document.createElement("div");
+    el.className = "cell";
+    el.style.left = r.x + "px"; el.style.top = r.y + "px";
+    el.style.width = r.w + "px"; el.style.height = r.h + "px";
+    el.style.background = palette[i % palette.length];
+    if (r.w > 40 && r.h > 14) el.textContent = r.n.n + " " + human(r.n.s);
+    el.onmousemove = function (e) {
+      tipEl.style.display = "block";
+      tipEl.style.left = e.clientX + 12 + "px"; tipEl.style.top = e.clientY + 12 + "px";
+      tipEl.textContent = r.n.n + "  " + human(r.n.s) + "  " + (r.n.s / node.s * 100).toFixed(1) + "%";
+    };
+    el.onmouseleave = function () { tipEl.style.display = "none"; };
+    el.onclick = function () { if (r.n.c && r.n.c.length) { stack.push(r.n); tipEl.style.display = "none"; render(); } };
+    mapEl.appendChild(el);
+  });
+}
+
+window.addEventListener("resize", render);
+render();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"talpa/internal/infra/filesystem"
+)
+
+func sampleTree() *filesystem.TreeNode {
+	mtime := time.Date(2026, 2, 16, 10, 0, 0, 0, time.UTC)
+	return filesystem.BuildTree("/data", []filesystem.ScanItem{
+		{Path: "/data/media", IsDir: true, Device: 7, Inode: 2, LastModified: mtime},
+		{Path: "/data/media/a.mkv", SizeBytes: 3000, AllocatedBytes: 4096, Device: 7, Inode: 10, Links: 1, LastModified: mtime},
+		{Path: "/data/media/b.mkv", SizeBytes: 2000, AllocatedBytes: 4096, Device: 7, Inode: 11, Links: 2, LastModified: mtime},
+		{Path: "/data/notes.txt", SizeBytes: 100, AllocatedBytes: 4096, Device: 7, Inode: 12, Links: 1, LastModified: mtime},
+		{Path: "/data/<script>.txt", SizeBytes: 50, AllocatedBytes: 4096, Device: 7, Inode: 13, Links: 1, LastModified: mtime},
+	}, 0)
+}
+
+func TestWriteNcduProducesImportableTree(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeNcdu(&buf, sampleTree(), time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	var doc []json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("export is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(doc) != 4 || string(doc[0]) != "1" || string(doc[1]) != "2" {
+		t.Fatalf("unexpected header: %s", buf.String())
+	}
+	var meta map[string]any
+	if err := json.Unmarshal(doc[2], &meta); err != nil || meta["progname"] != "talpa" || meta["timestamp"] != float64(1700000000) {
+		t.Fatalf("unexpected metadata %v, %v", meta, err)
+	}
+
+	var root []json.RawMessage
+	if err := json.Unmarshal(doc[3], &root); err != nil {
+		t.Fatal(err)
+	}
+	var rootInfo ncduInfo
+	if err := json.Unmarshal(root[0], &rootInfo); err != nil || rootInfo.Name != "/data" {
+		t.Fatalf("root info = %+v, %v", rootInfo, err)
+	}
+	if len(root) != 4 {
+		t.Fatalf("expected 3 root children, got %d", len(root)-1)
+	}
+	var media []json.RawMessage
+	if err := json.Unmarshal(root[2], &media); err != nil {
+		t.Fatalf("expected media directory array: %v", err)
+	}
+	var dirInfo, plain, linked ncduInfo
+	json.Unmarshal(media[0], &dirInfo)
+	json.Unmarshal(media[1], &plain)
+	json.Unmarshal(media[2], &linked)
+	if dirInfo.Name != "media" || dirInfo.Dev != 7 || dirInfo.Ino != 2 {
+		t.Fatalf("unexpected dir info %+v", dirInfo)
+	}
+	if plain.Name != "a.mkv" || plain.Asize != 3000 || plain.Dsize != 4096 || plain.Hlnkc || plain.Dev != 0 {
+		t.Fatalf("unexpected file info %+v", plain)
+	}
+	if linked.Name != "b.mkv" || !linked.Hlnkc || linked.Nlink != 2 || linked.Ino != 11 {
+		t.Fatalf("expected hard link info, got %+v", linked)
+	}
+}
+
+func TestWriteCSVListsResultItems(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != strings.Join(csvHeader, ",") {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	if got := strings.Join(rows[1], ","); got != "/home/u/.cache/go-build,,0,2097152,0,,dev_cache,low,true,planned,0,," {
+		t.Fatalf("unexpected row %q", got)
+	}
+}
+
+func TestWriteTreemapHTMLEmbedsFoldedTree(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTreemapHTML(&buf, sampleTree(), "", 2, 2, time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<h1>/data</h1>",
+		`"n":"media/"`,
+		`"n":"a.mkv","s":3000`,
+		`"n":"(1 more)","s":50`,
+		"apparent",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in treemap:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<script>.txt") || strings.Contains(out, "http") {
+		t.Fatalf("treemap must escape names and stay offline:\n%s", out)
+	}
+}
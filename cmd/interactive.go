@@ -27,7 +27,7 @@ func newMenuModel() menuModel {
 	return menuModel{
 		items: []menuItem{
 			{Title: "Clean (dry run)", Description: "Preview cache and temp cleanup", Args: []string{"clean", "--dry-run"}},
-			{Title: "Analyze disk", Description: "Inspect disk usage by path", Args: []string{"analyze"}},
+			{Title: "Analyze disk", Description: "Browse disk usage in the explorer", Args: []string{"analyze", "--tui"}},
 			{Title: "Purge projects (dry run)", Description: "Preview build artifact cleanup", Args: []string{"purge", "--dry-run"}},
 			{Title: "Status", Description: "Show current system metrics", Args: []string{"status"}},
 			{Title: "Optimize (dry run)", Description: "Preview safe optimization actions", Args: []string{"optimize", "--dry-run"}},
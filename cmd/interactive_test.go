@@ -21,7 +21,7 @@ func TestMenuModelUpdateNavigationAndSelection(t *testing.T) {
 	if len(m3.selected) == 0 {
 		t.Fatalf("expected selected command args")
 	}
-	if got := strings.Join(m3.selected, " "); got != "analyze" {
+	if got := strings.Join(m3.selected, " "); got != "analyze --tui" {
 		t.Fatalf("unexpected selection: %s", got)
 	}
 }
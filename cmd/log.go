@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"talpa/internal/app/common"
+	"talpa/internal/app/oplog"
+)
+
+var logCommands []string
+var logPlanID string
+var logResults []string
+var logRisks []string
+var logPathPrefix string
+var logSince string
+var logUntil string
+var logLimit int
+var logSummary bool
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Query and summarize the operation log",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := common.FromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		logOpts, err := buildLogOptions(time.Now())
+		if err != nil {
+			return err
+		}
+		svc := oplog.NewService()
+		result, err := svc.Run(cmd.Context(), app, logOpts)
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	},
+}
+
+var logVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify operation log hash chain integrity",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := common.FromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		svc := oplog.NewService()
+		result, err := svc.Verify(cmd.Context(), app)
+		if err != nil {
+			return err
+		}
+		if err := printResult(result); err != nil {
+			return err
+		}
+		if result.Summary.Errors > 0 {
+			return fmt.Errorf("operation log integrity check failed: %d issue(s)", result.Summary.Errors)
+		}
+		return nil
+	},
+}
+
+func init() {
+	logCmd.AddCommand(logVerifyCmd)
+	logCmd.Flags().StringSliceVar(&logCommands, "command", nil, "Filter by command (repeatable or comma-separated)")
+	logCmd.Flags().StringVar(&logPlanID, "plan", "", "Filter by plan ID")
+	logCmd.Flags().StringSliceVar(&logResults, "result", nil, "Filter by result, e.g. deleted,trashed,error")
+	logCmd.Flags().StringSliceVar(&logRisks, "risk", nil, "Filter by risk: low, medium, high")
+	logCmd.Flags().StringVar(&logPathPrefix, "path", "", "Filter by path prefix")
+	logCmd.Flags().StringVar(&logSince, "since", "", "Only entries at or after this time (RFC3339, YYYY-MM-DD, or age like 7d)")
+	logCmd.Flags().StringVar(&logUntil, "until", "", "Only entries at or before this time (RFC3339, YYYY-MM-DD for the whole day, or age like 1d)")
+	logCmd.Flags().IntVar(&logLimit, "limit", 0, "Show only the newest N matching entries (0 = unlimited)")
+	logCmd.Flags().BoolVar(&logSummary, "summary", false, "Summarize freed and trashed bytes per command and category instead of listing entries")
+}
+
+func buildLogOptions(now time.Time) (oplog.Options, error) {
+	if logLimit < 0 {
+		return oplog.Options{}, fmt.Errorf("--limit must be >= 0")
+	}
+	since, err := oplog.ParseTimeBound(logSince, now)
+	if err != nil {
+		return oplog.Options{}, err
+	}
+	until, err := oplog.ParseUntilBound(logUntil, now)
+	if err != nil {
+		return oplog.Options{}, err
+	}
+	return oplog.Options{
+		Commands:   logCommands,
+		PlanID:     logPlanID,
+		Results:    logResults,
+		Risks:      logRisks,
+		PathPrefix: logPathPrefix,
+		Since:      since,
+		Until:      until,
+		Limit:      logLimit,
+		Summary:    logSummary,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func profileBool(cmd *cobra.Command, flag string, value bool, fromProfile *bool) bool {
+	if cmd.Flags().Changed(flag) || fromProfile == nil {
+		return value
+	}
+	return *fromProfile
+}
+
+func profileInt(cmd *cobra.Command, flag string, value int, fromProfile int) int {
+	if cmd.Flags().Changed(flag) || fromProfile <= 0 {
+		return value
+	}
+	return fromProfile
+}
+
+func profilePaths(cmd *cobra.Command, flag string, value string, fromProfile []string) string {
+	if cmd.Flags().Changed(flag) || len(fromProfile) == 0 {
+		return value
+	}
+	return strings.Join(fromProfile, ",")
+}
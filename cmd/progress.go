@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"talpa/internal/infra/filesystem"
+)
+
+const progressPathWidth = 60
+
+type scanProgressEvent struct {
+	Event       string `json:"event"`
+	DirsVisited int64  `json:"dirs_visited"`
+	FilesSeen   int64  `json:"files_seen"`
+	BytesSeen   int64  `json:"bytes_seen"`
+	CurrentPath string `json:"current_path"`
+	Done        bool   `json:"done"`
+}
+
+func scanProgressReporter() func(filesystem.ScanProgress) {
+	if opts.JSON {
+		return newScanProgressReporter(os.Stderr, true)
+	}
+	st, err := os.Stderr.Stat()
+	if err != nil || !isCharDevice(st.Mode()) || isDumbTerm(os.Getenv("TERM")) {
+		return nil
+	}
+	return newScanProgressReporter(os.Stderr, false)
+}
+
+func newScanProgressReporter(w io.Writer, jsonLines bool) func(filesystem.ScanProgress) {
+	if jsonLines {
+		enc := json.NewEncoder(w)
+		return func(p filesystem.ScanProgress) {
+			_ = enc.Encode(scanProgressEvent{
+				Event:       "scan_progress",
+				DirsVisited: p.DirsVisited,
+				FilesSeen:   p.FilesSeen,
+				BytesSeen:   p.BytesSeen,
+				CurrentPath: p.CurrentPath,
+				Done:        p.Done,
+			})
+		}
+	}
+	return func(p filesystem.ScanProgress) {
+		if p.Done {
+			fmt.Fprint(w, "\r\033[K")
+			return
+		}
+		fmt.Fprintf(w, "\r\033[Kscanning %d dirs, %d files, %s  %s", p.DirsVisited, p.FilesSeen, humanBytes(p.BytesSeen), shortenPath(p.CurrentPath, progressPathWidth))
+	}
+}
+
+func shortenPath(path string, width int) string {
+	r := []rune(path)
+	if len(r) <= width {
+		return path
+	}
+	return "…" + string(r[len(r)-width+1:])
+}
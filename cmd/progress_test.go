@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"talpa/internal/infra/filesystem"
+)
+
+func TestScanProgressReporterWritesNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	report := newScanProgressReporter(&buf, true)
+	report(filesystem.ScanProgress{DirsVisited: 3, FilesSeen: 7, BytesSeen: 2048, CurrentPath: "/tmp/a"})
+	report(filesystem.ScanProgress{DirsVisited: 4, FilesSeen: 9, BytesSeen: 4096, CurrentPath: "/tmp/b", Done: true})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two NDJSON lines, got %q", buf.String())
+	}
+	var ev scanProgressEvent
+	if err := json.Unmarshal([]byte(lines[1]), &ev); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ev.Event != "scan_progress" || ev.DirsVisited != 4 || ev.BytesSeen != 4096 || !ev.Done {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestScanProgressReporterDrawsAndClearsLine(t *testing.T) {
+	var buf bytes.Buffer
+	report := newScanProgressReporter(&buf, false)
+	report(filesystem.ScanProgress{DirsVisited: 3, FilesSeen: 7, BytesSeen: 2048, CurrentPath: "/tmp/a"})
+	if !strings.Contains(buf.String(), "scanning 3 dirs, 7 files") || !strings.Contains(buf.String(), "/tmp/a") {
+		t.Fatalf("unexpected progress line: %q", buf.String())
+	}
+	buf.Reset()
+	report(filesystem.ScanProgress{Done: true})
+	if buf.String() != "\r\033[K" {
+		t.Fatalf("expected done to clear the line, got %q", buf.String())
+	}
+}
+
+func TestShortenPathKeepsTail(t *testing.T) {
+	if got := shortenPath("/short", 10); got != "/short" {
+		t.Fatalf("unexpected short path: %q", got)
+	}
+	got := shortenPath("/very/long/path/to/somewhere", 10)
+	if len([]rune(got)) != 10 || !strings.HasSuffix(got, "somewhere") {
+		t.Fatalf("unexpected shortened path: %q", got)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"talpa/internal/app/purge"
+)
+
+func writeProjects(b *strings.Builder, m purge.ProjectMetrics, bold func(string) string) {
+	title := "Projects"
+	var filters []string
+	if m.OlderThanDays > 0 {
+		filters = append(filters, fmt.Sprintf("idle %dd+", m.OlderThanDays))
+	}
+	if m.Top > 0 {
+		filters = append(filters, fmt.Sprintf("top %d", m.Top))
+	}
+	if len(filters) > 0 {
+		title += " (" + strings.Join(filters, ", ") + ")"
+	}
+	fmt.Fprintln(b, bold(title))
+	if len(m.Projects) == 0 {
+		fmt.Fprintln(b, "  no project artifacts found")
+		return
+	}
+
+	for i, p := range m.Projects {
+		if i > 0 {
+			fmt.Fprintln(b)
+		}
+		rank := ""
+		if p.Rank > 0 {
+			rank = fmt.Sprintf("%d.", p.Rank)
+		}
+		line := fmt.Sprintf("%4s %s  %s  idle %dd", rank, p.Root, humanBytes(p.ReclaimableBytes), p.IdleDays)
+		if p.LastCommit != nil {
+			line += "  last commit " + p.LastCommit.Local().Format(time.DateOnly)
+		} else if p.VCS != "" {
+			line += "  " + p.VCS
+		}
+		fmt.Fprintln(b, line)
+		for _, a := range p.Artifacts {
+			mark := " "
+			if a.Selected {
+				mark = "*"
+			}
+			rel, err := filepath.Rel(p.Root, a.Path)
+			if err != nil {
+				rel = a.Path
+			}
+			if a.Reason != "" {
+				rel += "  (" + a.Reason + ")"
+			}
+			fmt.Fprintf(b, "       %s %-6s  %10s  %s\n", mark, a.Risk, humanBytes(a.SizeBytes), rel)
+		}
+	}
+}
@@ -3,16 +3,21 @@ package cmd
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"talpa/internal/app/common"
 	"talpa/internal/app/purge"
+	"talpa/internal/infra/filesystem"
 )
 
 var purgePaths string
 var purgeDepth int
 var purgeRecentDays int
+var purgeOlderThanDays int
+var purgeTop int
+var purgeSizeMode string
 
 var purgeCmd = &cobra.Command{
 	Use:   "purge",
@@ -22,13 +27,25 @@ var purgeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		if err := validatePurgeFlags(purgeDepth, purgeRecentDays); err != nil {
+		pathList := purgePaths
+		recentDays := purgeRecentDays
+		if app.Profile != nil {
+			pathList = profilePaths(cmd, "paths", pathList, app.Profile.Defaults.PurgePaths)
+			recentDays = profileInt(cmd, "recent-days", recentDays, app.Profile.Defaults.RecentDays)
+		}
+		if err := validatePurgeFlags(purgeDepth, recentDays); err != nil {
+			return err
+		}
+		if err := validatePurgeSelection(purgeOlderThanDays, purgeTop); err != nil {
 			return err
 		}
+		if err := filesystem.ValidateSizeMode(purgeSizeMode); err != nil {
+			return fmt.Errorf("--size-mode must be one of: apparent, disk")
+		}
 
 		var paths []string
-		if strings.TrimSpace(purgePaths) != "" {
-			for _, p := range strings.Split(purgePaths, ",") {
+		if strings.TrimSpace(pathList) != "" {
+			for _, p := range strings.Split(pathList, ",") {
 				p = strings.TrimSpace(p)
 				if p != "" {
 					paths = append(paths, p)
@@ -38,8 +55,11 @@ var purgeCmd = &cobra.Command{
 
 		svc := purge.NewService()
 		result, err := svc.Run(cmd.Context(), app, paths, purge.Options{
-			MaxDepth:   purgeDepth,
-			RecentDays: purgeRecentDays,
+			MaxDepth:      purgeDepth,
+			RecentDays:    recentDays,
+			OlderThanDays: purgeOlderThanDays,
+			Top:           purgeTop,
+			SizeMode:      purgeSizeMode,
 		})
 		if err != nil {
 			return err
@@ -51,7 +71,10 @@ var purgeCmd = &cobra.Command{
 func init() {
 	purgeCmd.Flags().StringVar(&purgePaths, "paths", "", "Comma-separated paths to scan")
 	purgeCmd.Flags().IntVar(&purgeDepth, "depth", 4, "Maximum scan depth for artifact discovery")
-	purgeCmd.Flags().IntVar(&purgeRecentDays, "recent-days", 7, "Treat artifacts modified within N days as recent and skip by default")
+	purgeCmd.Flags().Var(newDurationFlag(&purgeRecentDays, 7, 24*time.Hour, "days"), "recent-days", "Treat artifacts modified within this age as recent and skip by default, e.g. 14 or 2w (bare numbers are days)")
+	purgeCmd.Flags().Var(newDurationFlag(&purgeOlderThanDays, 0, 24*time.Hour, "days"), "older-than", "Only select projects idle at least this long (last commit or change), e.g. 90d or 6mo (bare numbers are days)")
+	purgeCmd.Flags().IntVar(&purgeTop, "top", 0, "Only select the N projects ranked highest by size and staleness (0 = all)")
+	purgeCmd.Flags().StringVar(&purgeSizeMode, "size-mode", filesystem.SizeModeApparent, "Size accounting: apparent (file length) or disk (allocated blocks)")
 }
 
 func validatePurgeFlags(depth, recentDays int) error {
@@ -63,3 +86,13 @@ func validatePurgeFlags(depth, recentDays int) error {
 	}
 	return nil
 }
+
+func validatePurgeSelection(olderThanDays, top int) error {
+	if olderThanDays < 0 {
+		return fmt.Errorf("--older-than must be >= 0")
+	}
+	if top < 0 {
+		return fmt.Errorf("--top must be >= 0")
+	}
+	return nil
+}
@@ -24,3 +24,15 @@ func TestPurgeCmdAcceptsValidFlags(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 }
+
+func TestPurgeCmdRejectsNegativeSelection(t *testing.T) {
+	if err := validatePurgeSelection(-1, 0); err == nil || !strings.Contains(err.Error(), "--older-than") {
+		t.Fatalf("expected older-than validation error, got %v", err)
+	}
+	if err := validatePurgeSelection(90, -1); err == nil || !strings.Contains(err.Error(), "--top") {
+		t.Fatalf("expected top validation error, got %v", err)
+	}
+	if err := validatePurgeSelection(90, 20); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
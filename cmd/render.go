@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"talpa/internal/app/analyze"
+	"talpa/internal/app/purge"
+	"talpa/internal/domain/model"
+)
+
+var riskColors = map[model.RiskLevel]lipgloss.Color{
+	model.RiskLow:    lipgloss.Color("2"),
+	model.RiskMedium: lipgloss.Color("3"),
+	model.RiskHigh:   lipgloss.Color("1"),
+}
+
+type categoryTotal struct {
+	name          string
+	items         int
+	selected      int
+	selectedBytes int64
+}
+
+type jsonField struct {
+	key   string
+	value any
+}
+
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	st, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return isCharDevice(st.Mode()) && !isDumbTerm(os.Getenv("TERM"))
+}
+
+func writeResult(w io.Writer, v any, jsonMode, color bool) error {
+	if jsonMode {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	switch r := v.(type) {
+	case model.CommandResult:
+		return renderResult(w, r, color)
+	case fmt.Stringer:
+		_, err := fmt.Fprintln(w, r.String())
+		return err
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func renderResult(w io.Writer, r model.CommandResult, color bool) error {
+	paint := func(c lipgloss.Color, s string) string {
+		if !color {
+			return s
+		}
+		return lipgloss.NewStyle().Foreground(c).Render(s)
+	}
+	bold := func(s string) string {
+		if !color {
+			return s
+		}
+		return lipgloss.NewStyle().Bold(true).Render(s)
+	}
+
+	var b strings.Builder
+	header := []string{bold(r.Command)}
+	if r.PlanID != "" {
+		header = append(header, r.PlanID)
+	}
+	if r.DryRun {
+		header = append(header, paint(lipgloss.Color("6"), "dry run"))
+	}
+	header = append(header, humanDuration(time.Duration(r.DurationMS)*time.Millisecond))
+	fmt.Fprintln(&b, strings.Join(header, "  "))
+
+	_, grouped := r.Metrics.(purge.ProjectMetrics)
+	if len(r.Items) > 0 && !grouped {
+		resultWidth := len("RESULT")
+		for _, it := range r.Items {
+			if len(it.Result) > resultWidth {
+				resultWidth = len(it.Result)
+			}
+		}
+		fmt.Fprintln(&b)
+		fmt.Fprintf(&b, "  %-6s  %10s  %-*s  %s\n", "RISK", "SIZE", resultWidth, "RESULT", "PATH")
+		for _, it := range r.Items {
+			mark := " "
+			if it.Selected {
+				mark = "*"
+			}
+			risk := paint(riskColors[it.Risk], fmt.Sprintf("%-6s", it.Risk))
+			result := fmt.Sprintf("%-*s", resultWidth, it.Result)
+			if it.Result == "error" {
+				result = paint(riskColors[model.RiskHigh], result)
+			}
+			path := strings.Repeat("  ", max(it.Depth-1, 0)) + it.Path
+			if it.DuplicateOf != "" {
+				path += "  (copy of " + it.DuplicateOf + ")"
+			}
+			if it.Reason != "" {
+				path += "  (" + it.Reason + ")"
+			}
+			fmt.Fprintf(&b, "%s %s  %10s  %s  %s\n", mark, risk, humanBytes(it.SizeBytes), result, path)
+		}
+
+		totals := categoryTotals(r.Items)
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, bold("By category"))
+		nameWidth := 0
+		for _, t := range totals {
+			if len(t.name) > nameWidth {
+				nameWidth = len(t.name)
+			}
+		}
+		for _, t := range totals {
+			fmt.Fprintf(&b, "  %-*s  %4d items  %4d selected  %10s\n", nameWidth, t.name, t.items, t.selected, humanBytes(t.selectedBytes))
+		}
+	}
+
+	s := r.Summary
+	if len(r.Items) > 0 || s.ItemsTotal > 0 || s.EstimatedFreedBytes > 0 || s.Errors > 0 {
+		line := fmt.Sprintf("%s %d items, %d selected, %s", bold("Summary:"), s.ItemsTotal, s.ItemsSelected, humanBytes(s.EstimatedFreedBytes))
+		if s.SizeMode != "" {
+			line += " " + s.SizeMode
+		}
+		line += " to free"
+		errs := fmt.Sprintf("%d error(s)", s.Errors)
+		if s.Errors > 0 {
+			errs = paint(riskColors[model.RiskHigh], errs)
+		}
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, line+", "+errs)
+	}
+
+	if r.Partial {
+		fmt.Fprintln(&b, paint(riskColors[model.RiskMedium], fmt.Sprintf("Scan incomplete: %d directories not visited", len(r.Unvisited))))
+		for _, dir := range r.Unvisited {
+			fmt.Fprintln(&b, "  "+dir)
+		}
+	}
+
+	switch m := r.Metrics.(type) {
+	case analyze.ReportMetrics:
+		fmt.Fprintln(&b)
+		writeReport(&b, m, bold)
+	case analyze.DiffMetrics:
+		fmt.Fprintln(&b)
+		writeDiff(&b, m, bold)
+	case purge.ProjectMetrics:
+		fmt.Fprintln(&b)
+		writeProjects(&b, m, bold)
+	case nil:
+	default:
+		fields, err := orderedFields(r.Metrics)
+		if err != nil {
+			return err
+		}
+		if len(fields) > 0 {
+			fmt.Fprintln(&b)
+			writeFields(&b, fields, "", bold)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func categoryTotals(items []model.CandidateItem) []categoryTotal {
+	index := map[string]int{}
+	var out []categoryTotal
+	for _, it := range items {
+		name := it.Category
+		if name == "" {
+			name = "-"
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(out)
+			index[name] = i
+			out = append(out, categoryTotal{name: name})
+		}
+		out[i].items++
+		if it.Selected {
+			out[i].selected++
+			out[i].selectedBytes += it.SizeBytes
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].selectedBytes > out[j].selectedBytes })
+	return out
+}
+
+func orderedFields(v any) ([]jsonField, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	val, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	if fields, ok := val.([]jsonField); ok {
+		return fields, nil
+	}
+	return []jsonField{{key: "metrics", value: val}}, nil
+}
+
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			fields := []jsonField{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				val, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, jsonField{key: keyTok.(string), value: val})
+			}
+			_, err := dec.Token()
+			return fields, err
+		}
+		list := []any{}
+		for dec.More() {
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+func writeFields(b *strings.Builder, fields []jsonField, indent string, bold func(string) string) {
+	for _, f := range fields {
+		label := strings.ReplaceAll(f.key, "_", " ")
+		switch v := f.value.(type) {
+		case []jsonField:
+			fmt.Fprintln(b, indent+bold(label))
+			writeFields(b, v, indent+"  ", bold)
+		case []any:
+			if len(v) == 0 {
+				continue
+			}
+			if _, nested := v[0].([]jsonField); nested {
+				fmt.Fprintln(b, indent+bold(label))
+				for i, el := range v {
+					if i > 0 {
+						fmt.Fprintln(b)
+					}
+					if sub, ok := el.([]jsonField); ok {
+						writeFields(b, sub, indent+"  ", bold)
+					}
+				}
+				continue
+			}
+			parts := make([]string, 0, len(v))
+			for _, el := range v {
+				parts = append(parts, formatValue(f.key, el))
+			}
+			fmt.Fprintf(b, "%s%s: %s\n", indent, label, strings.Join(parts, ", "))
+		default:
+			if v == nil {
+				continue
+			}
+			fmt.Fprintf(b, "%s%s: %s\n", indent, label, formatValue(f.key, v))
+		}
+	}
+}
+
+func formatValue(key string, v any) string {
+	n, ok := v.(json.Number)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return n.String()
+	}
+	switch {
+	case strings.HasSuffix(key, "_bytes"):
+		return humanBytes(i)
+	case strings.HasSuffix(key, "_bps"):
+		return humanBytes(i) + "/s"
+	case strings.HasSuffix(key, "_ms"):
+		return humanDuration(time.Duration(i) * time.Millisecond)
+	}
+	return n.String()
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"talpa/internal/app/analyze"
+	"talpa/internal/app/purge"
+	"talpa/internal/domain/model"
+)
+
+func sampleResult() model.CommandResult {
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "clean",
+		PlanID:        "plan-clean-1",
+		Timestamp:     time.Date(2026, 2, 16, 10, 0, 0, 0, time.UTC),
+		DurationMS:    42,
+		DryRun:        true,
+		Summary:       model.Summary{ItemsTotal: 3, ItemsSelected: 2, EstimatedFreedBytes: 3 << 20, SizeMode: "apparent", Errors: 1},
+		Items: []model.CandidateItem{
+			{ID: "a", Path: "/home/u/.cache/go-build", SizeBytes: 2 << 20, Category: "dev_cache", Risk: model.RiskLow, Selected: true, Result: "planned"},
+			{ID: "b", Path: "/home/u/.cache/pip", SizeBytes: 1 << 20, Category: "dev_cache", Risk: model.RiskLow, Selected: true, Result: "error"},
+			{ID: "c", Path: "/home/u/.local/share/Trash", SizeBytes: 5 << 20, Category: "trash", Risk: model.RiskHigh, Result: "skipped"},
+		},
+		Metrics: struct {
+			Root      string `json:"root"`
+			SizeBytes int64  `json:"size_bytes"`
+			Disks     []struct {
+				Mount string `json:"mount"`
+			} `json:"disks"`
+		}{Root: "/home/u", SizeBytes: 1536, Disks: []struct {
+			Mount string `json:"mount"`
+		}{{Mount: "/"}}},
+		Partial:   true,
+		Unvisited: []string{"/home/u/slow"},
+	}
+}
+
+func TestWriteResultJSONMatchesIndentedEncoding(t *testing.T) {
+	res := sampleResult()
+	var got bytes.Buffer
+	if err := writeResult(&got, res, true, true); err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want)+"\n" {
+		t.Fatalf("json output changed:\n%s", got.String())
+	}
+}
+
+func TestRenderResultShowsTableSubtotalsAndMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResult(&buf, sampleResult(), false, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"clean  plan-clean-1  dry run  42ms",
+		"RISK",
+		"* low        2.0 MiB  planned  /home/u/.cache/go-build",
+		"  high       5.0 MiB  skipped  /home/u/.local/share/Trash",
+		"dev_cache     2 items     2 selected     3.0 MiB",
+		"trash         1 items     0 selected         0 B",
+		"Summary: 3 items, 2 selected, 3.0 MiB apparent to free, 1 error(s)",
+		"Scan incomplete: 1 directories not visited",
+		"root: /home/u",
+		"size bytes: 1.5 KiB",
+		"mount: /",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Fatalf("unexpected escape codes without color:\n%s", out)
+	}
+}
+
+func TestRenderResultOmitsEmptySummary(t *testing.T) {
+	var buf bytes.Buffer
+	res := model.CommandResult{Command: "status", Metrics: map[string]any{"cpu_usage": 12.5}}
+	if err := writeResult(&buf, res, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Summary") || !strings.Contains(buf.String(), "cpu usage: 12.5") {
+		t.Fatalf("unexpected status output:\n%s", buf.String())
+	}
+}
+
+func TestRenderResultDrawsReportCharts(t *testing.T) {
+	var buf bytes.Buffer
+	res := model.CommandResult{Command: "analyze", Metrics: analyze.ReportMetrics{
+		Root:      "/data",
+		SizeBytes: 4 << 20,
+		FileCount: 3,
+		ByType:    []analyze.HistogramBucket{{Key: "video", FileCount: 1, SizeBytes: 3 << 20, Percent: 75}, {Key: "other", FileCount: 2, SizeBytes: 1 << 20, Percent: 25}},
+		ByOwner:   []analyze.HistogramBucket{{Key: "1000", Name: "alice", FileCount: 3, SizeBytes: 4 << 20, Percent: 100}},
+	}}
+	if err := writeResult(&buf, res, false, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"/data  4.0 MiB in 3 files",
+		"By type",
+		"video  ##################           3.0 MiB   75.0%  1 files",
+		"alice (1000)  ########################     4.0 MiB  100.0%  3 files",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "By extension") {
+		t.Fatalf("expected empty sections to be skipped:\n%s", out)
+	}
+}
+
+func TestRenderResultListsDiffSections(t *testing.T) {
+	var buf bytes.Buffer
+	at := time.Date(2026, 2, 16, 10, 0, 0, 0, time.UTC)
+	res := model.CommandResult{Command: "analyze", Metrics: analyze.DiffMetrics{
+		Root: "/var", From: "monday", To: "now", FromTime: at, ToTime: at.Add(24 * time.Hour),
+		BeforeBytes: 1 << 30, AfterBytes: 3 << 30, DeltaBytes: 2 << 30,
+		Grew:        []analyze.PathDelta{{Path: "/var/lib/docker/volumes/db", Kind: "dir", BeforeBytes: 0, AfterBytes: 2 << 30, DeltaBytes: 2 << 30}},
+		Disappeared: []analyze.FileChange{{Path: "/var/tmp/x.iso", SizeBytes: 1 << 20}},
+	}}
+	if err := writeResult(&buf, res, false, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"1.0 GiB -> 3.0 GiB  +2.0 GiB",
+		"Grew",
+		"+2.0 GiB         0 B -> 2.0 GiB     /var/lib/docker/volumes/db/",
+		"Disappeared",
+		"-   1.0 MiB  /var/tmp/x.iso",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Shrank") || strings.Contains(out, "Appeared") {
+		t.Fatalf("expected empty sections to be skipped:\n%s", out)
+	}
+}
+
+func TestRenderResultGroupsPurgeProjects(t *testing.T) {
+	var buf bytes.Buffer
+	commit := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	items := []model.CandidateItem{{Path: "/p/api/node_modules", SizeBytes: 3 << 20, Risk: model.RiskLow, Selected: true, Result: "planned"}}
+	res := model.CommandResult{Command: "purge", Items: items, Summary: model.Summary{ItemsTotal: 2, ItemsSelected: 1, EstimatedFreedBytes: 3 << 20}, Metrics: purge.ProjectMetrics{
+		OlderThanDays: 90,
+		Top:           20,
+		Projects: []purge.Project{
+			{Rank: 1, Root: "/p/api", VCS: "git", LastCommit: &commit, IdleDays: 287, ReclaimableBytes: 3 << 20, Artifacts: []purge.ProjectArtifact{
+				{Path: "/p/api/node_modules", SizeBytes: 3 << 20, Risk: model.RiskLow, Selected: true},
+			}},
+			{Root: "/p/web", IdleDays: 3, Artifacts: []purge.ProjectArtifact{
+				{Path: "/p/web/dist", SizeBytes: 1 << 20, Risk: model.RiskLow, Reason: "project active 3d ago"},
+			}},
+		},
+	}}
+	if err := writeResult(&buf, res, false, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Projects (idle 90d+, top 20)",
+		"  1. /p/api  3.0 MiB  idle 287d  last commit 2026-01-02",
+		"       * low        3.0 MiB  node_modules",
+		"     /p/web  0 B  idle 3d",
+		"         low        1.0 MiB  dist  (project active 3d ago)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "RISK") {
+		t.Fatalf("expected the flat table to be replaced by the project view:\n%s", out)
+	}
+}
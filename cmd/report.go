@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"talpa/internal/app/analyze"
+)
+
+const reportBarWidth = 24
+
+func writeReport(b *strings.Builder, m analyze.ReportMetrics, bold func(string) string) {
+	fmt.Fprintf(b, "%s  %s in %d files", m.Root, humanBytes(m.SizeBytes), m.FileCount)
+	if m.SizeMode != "" {
+		fmt.Fprintf(b, " (%s)", m.SizeMode)
+	}
+	fmt.Fprintln(b)
+	for _, section := range []struct {
+		title   string
+		buckets []analyze.HistogramBucket
+	}{
+		{"By type", m.ByType},
+		{"By extension", m.ByExtension},
+		{"By age", m.ByAge},
+		{"By owner", m.ByOwner},
+	} {
+		if len(section.buckets) == 0 {
+			continue
+		}
+		fmt.Fprintln(b)
+		fmt.Fprintln(b, bold(section.title))
+		writeHistogram(b, section.buckets)
+	}
+}
+
+func writeHistogram(b *strings.Builder, buckets []analyze.HistogramBucket) {
+	labels := make([]string, len(buckets))
+	width := 0
+	for i, bucket := range buckets {
+		labels[i] = bucket.Key
+		if bucket.Name != "" {
+			labels[i] = bucket.Name + " (" + bucket.Key + ")"
+		}
+		if len(labels[i]) > width {
+			width = len(labels[i])
+		}
+	}
+	for i, bucket := range buckets {
+		fmt.Fprintf(b, "  %-*s  %s  %10s  %5.1f%%  %d files\n", width, labels[i], sizeBar(bucket.Percent/100, reportBarWidth), humanBytes(bucket.SizeBytes), bucket.Percent, bucket.FileCount)
+	}
+}
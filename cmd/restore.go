@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"talpa/internal/app/common"
+	"talpa/internal/app/restore"
+)
+
+var restorePlanID string
+var restorePathGlob string
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore trashed items recorded in the operation log",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := common.FromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		svc := restore.NewService()
+		result, err := svc.Run(cmd.Context(), app, restore.Options{
+			PlanID:   restorePlanID,
+			PathGlob: restorePathGlob,
+		})
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	},
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restorePlanID, "plan", "", "Restore items trashed by this plan ID")
+	restoreCmd.Flags().StringVar(&restorePathGlob, "path", "", "Restore items whose original path matches this glob or prefix")
+}
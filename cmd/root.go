@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,11 +10,18 @@ import (
 	"github.com/spf13/cobra"
 
 	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/rules"
 	"talpa/internal/infra/config"
 	"talpa/internal/infra/logging"
+	"talpa/internal/infra/planstore"
+	"talpa/internal/infra/snapshotstore"
 )
 
-var opts common.GlobalOptions
+var (
+	opts       common.GlobalOptions
+	configSets []string
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "talpa",
@@ -56,6 +62,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&opts.Confirm, "confirm", "", "Second confirmation token for high-risk actions (must be HIGH-RISK)")
 	rootCmd.PersistentFlags().BoolVar(&opts.JSON, "json", false, "Output as JSON")
 	rootCmd.PersistentFlags().BoolVar(&opts.NoOpLog, "no-oplog", false, "Disable operation log")
+	rootCmd.PersistentFlags().StringVar(&opts.Profile, "profile", "", "Rule/default profile: dev, desktop, minimal, ci, or one defined under profiles: in config.yaml (env: TALPA_PROFILE)")
+	rootCmd.PersistentFlags().StringArrayVar(&configSets, "set", nil, "Override a config key for this run, e.g. --set scanner.timeout=30s (repeatable)")
 
 	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(analyzeCmd)
@@ -67,48 +75,88 @@ func init() {
 	rootCmd.AddCommand(uninstallCmd)
 	rootCmd.AddCommand(installerCmd)
 	rootCmd.AddCommand(optimizeCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(rulesCmd)
+	rootCmd.AddCommand(configCmd)
 }
 
 func printResult(v any) error {
-	if opts.JSON {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(v)
+	return writeResult(os.Stdout, v, opts.JSON, !opts.JSON && useColor())
+}
+
+func buildAppContext(ctx context.Context) (*common.AppContext, error) {
+	store := config.NewStore()
+	cfg, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range configSets {
+		if err := cfg.ApplyFlag(assignment); err != nil {
+			return nil, err
+		}
+	}
+	if opts.NoOpLog {
+		cfg.OplogEnabled = false
+		cfg.SetFlag("oplog.enabled")
+	}
+	if strings.TrimSpace(opts.Profile) != "" {
+		cfg.Profile = strings.TrimSpace(opts.Profile)
+		cfg.SetFlag("profile")
 	}
 
-	if line, ok := v.(fmt.Stringer); ok {
-		fmt.Println(line.String())
-		return nil
+	oplog, err := logging.NewOperationLoggerWithOptions(ctx, !cfg.OplogEnabled, cfg.LoggingOptions())
+	if err != nil {
+		oplog = logging.NewNoopLogger()
 	}
 
-	b, err := json.MarshalIndent(v, "", "  ")
+	ruleSet, ruleIssues, err := store.LoadRules(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(ruleIssues) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: ignored %d invalid rule plugin issue(s); run `talpa rules validate` for details\n", len(ruleIssues))
 	}
-	fmt.Println(string(b))
-	return nil
-}
 
-func buildAppContext(ctx context.Context) (*common.AppContext, error) {
-	store := config.NewStore()
-	whitelist, err := store.LoadWhitelist(ctx)
+	profile, err := resolveProfile(cfg.Profiles, cfg.Profile)
 	if err != nil {
 		return nil, err
 	}
 
-	oplogDisabled := opts.NoOpLog || os.Getenv("TALPA_NO_OPLOG") == "1"
-	oplog, err := logging.NewOperationLogger(ctx, oplogDisabled)
+	plans, err := planstore.NewFileStore()
 	if err != nil {
-		oplog = logging.NewNoopLogger()
+		plans = nil
+	}
+	snapshots, err := snapshotstore.NewFileStore()
+	if err != nil {
+		snapshots = nil
 	}
 
 	return &common.AppContext{
 		Options:   opts,
-		Whitelist: whitelist,
+		Whitelist: cfg.Whitelist,
 		Logger:    oplog,
+		Plans:     plans,
+		Snapshots: snapshots,
+		Rules:     ruleSet,
+		Profile:   profile,
+		Config:    cfg,
 	}, nil
 }
 
+func resolveProfile(profiles []model.Profile, name string) (*model.Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
+	p, err := rules.FindProfile(profiles, name)
+	if err != nil {
+		return nil, err
+	}
+	opts.Profile = p.Name
+	return &p, nil
+}
+
 func isInteractiveTerminal() bool {
 	in, err := os.Stdin.Stat()
 	if err != nil {
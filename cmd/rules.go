@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"talpa/internal/app/common"
+	"talpa/internal/app/ruleset"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Inspect built-in and plugin clean/purge rules",
+	Args:  cobra.NoArgs,
+}
+
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the merged built-in and plugin rules",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := common.FromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		svc := ruleset.NewService()
+		result, err := svc.List(cmd.Context(), app)
+		if err != nil {
+			return err
+		}
+		return printResult(result)
+	},
+}
+
+var rulesValidateCmd = &cobra.Command{
+	Use:   "validate [file...]",
+	Short: "Validate rule plugin files (defaults to the rules.d directories)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := common.FromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		svc := ruleset.NewService()
+		result, err := svc.Validate(cmd.Context(), app, args)
+		if err != nil {
+			return err
+		}
+		if err := printResult(result); err != nil {
+			return err
+		}
+		if result.Summary.Errors > 0 {
+			return fmt.Errorf("rule validation failed: %d issue(s)", result.Summary.Errors)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rulesCmd.AddCommand(rulesListCmd)
+	rulesCmd.AddCommand(rulesValidateCmd)
+}
@@ -20,6 +20,9 @@ var statusCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if !cmd.Flags().Changed("interval") && app.Config.StatusInterval > 0 {
+			app.Options.StatusInterval = app.Config.StatusInterval
+		}
 		if app.Options.StatusInterval < 1 {
 			return fmt.Errorf("--interval must be >= 1")
 		}
@@ -51,6 +54,6 @@ var statusCmd = &cobra.Command{
 
 func init() {
 	statusCmd.Flags().IntVar(&opts.StatusTop, "top", 5, "Number of top processes by memory")
-	statusCmd.Flags().IntVar(&opts.StatusInterval, "interval", 1, "Refresh interval in seconds")
+	statusCmd.Flags().Var(newIntervalFlag(&opts.StatusInterval, 1), "interval", "Refresh interval, e.g. 5 or 1m (bare numbers are seconds)")
 	statusCmd.Flags().BoolVar(&statusWatch, "watch", false, "Continuously refresh status output")
 }
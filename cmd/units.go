@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"talpa/internal/domain/units"
+)
+
+type sizeFlag struct {
+	p *int64
+}
+
+func newSizeFlag(p *int64, def int64) *sizeFlag {
+	*p = def
+	return &sizeFlag{p: p}
+}
+
+func (f *sizeFlag) Set(s string) error {
+	n, err := units.ParseSize(s)
+	if err != nil {
+		return err
+	}
+	*f.p = n
+	return nil
+}
+
+func (f *sizeFlag) String() string { return strconv.FormatInt(*f.p, 10) }
+
+func (f *sizeFlag) Type() string { return "size" }
+
+type durationFlag struct {
+	p        *int
+	unit     time.Duration
+	unitName string
+	parse    func(string) (time.Duration, error)
+}
+
+func newDurationFlag(p *int, def int, unit time.Duration, unitName string) *durationFlag {
+	*p = def
+	return &durationFlag{p: p, unit: unit, unitName: unitName, parse: units.ParseAge}
+}
+
+func newIntervalFlag(p *int, def int) *durationFlag {
+	*p = def
+	return &durationFlag{p: p, unit: time.Second, unitName: "seconds", parse: units.ParseDuration}
+}
+
+func (f *durationFlag) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		*f.p = n
+		return nil
+	}
+	d, err := f.parse(s)
+	if err != nil {
+		return err
+	}
+	if d%f.unit != 0 {
+		return fmt.Errorf("%q is not a whole number of %s", s, f.unitName)
+	}
+	*f.p = int(d / f.unit)
+	return nil
+}
+
+func (f *durationFlag) String() string { return strconv.Itoa(*f.p) }
+
+func (f *durationFlag) Type() string { return "duration" }
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func humanDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
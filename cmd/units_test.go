@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSizeFlagParsesHumanUnits(t *testing.T) {
+	var n int64
+	f := newSizeFlag(&n, 0)
+	for in, want := range map[string]int64{"1024": 1024, "500M": 500 << 20, "2GiB": 2 << 30, "1.5KB": 1500} {
+		if err := f.Set(in); err != nil || n != want {
+			t.Fatalf("Set(%q) = %d, %v; want %d", in, n, err, want)
+		}
+	}
+	if err := f.Set("lots"); err == nil {
+		t.Fatalf("expected error for invalid size")
+	}
+}
+
+func TestDurationFlagParsesBareNumbersAndAges(t *testing.T) {
+	var days int
+	f := newDurationFlag(&days, 7, 24*time.Hour, "days")
+	if days != 7 || f.String() != "7" {
+		t.Fatalf("unexpected default %d", days)
+	}
+	for in, want := range map[string]int{"14": 14, "14d": 14, "2w": 14, "48h": 2} {
+		if err := f.Set(in); err != nil || days != want {
+			t.Fatalf("Set(%q) = %d, %v; want %d", in, days, err, want)
+		}
+	}
+	if err := f.Set("36h"); err == nil {
+		t.Fatalf("expected error for partial days")
+	}
+	if err := f.Set("1m"); err == nil {
+		t.Fatalf("expected error for an ambiguous minute/month age")
+	}
+
+	var secs int
+	g := newIntervalFlag(&secs, 1)
+	if err := g.Set("1m"); err != nil || secs != 60 {
+		t.Fatalf("Set(1m) = %d, %v", secs, err)
+	}
+	if err := g.Set("500ms"); err == nil {
+		t.Fatalf("expected error for sub-second interval")
+	}
+}
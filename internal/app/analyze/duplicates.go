@@ -0,0 +1,211 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/safety"
+	"talpa/internal/infra/filesystem"
+)
+
+type DuplicatesOptions struct {
+	MinSizeBytes int64
+	Limit        int
+	Action       string
+	TrashDir     string
+	SizeMode     string
+	Refresh      bool
+}
+
+type DuplicateSetMetrics struct {
+	Hash        string   `json:"hash"`
+	SizeBytes   int64    `json:"size_bytes"`
+	WastedBytes int64    `json:"wasted_bytes"`
+	Canonical   string   `json:"canonical"`
+	Copies      []string `json:"copies"`
+}
+
+type DuplicateMetrics struct {
+	Root        string                `json:"root"`
+	Sets        []DuplicateSetMetrics `json:"sets"`
+	WastedBytes int64                 `json:"wasted_bytes"`
+}
+
+var findDuplicates = filesystem.FindDuplicates
+var replaceWithHardlink = filesystem.ReplaceWithHardlink
+var replaceWithReflink = filesystem.ReplaceWithReflink
+var verifyDuplicate = filesystem.VerifyDuplicate
+
+func (Service) Duplicates(ctx context.Context, app *common.AppContext, root string, opts DuplicatesOptions) (model.CommandResult, error) {
+	start := time.Now()
+	switch opts.Action {
+	case "", "inspect":
+		opts.Action = "inspect"
+	case "trash", "delete", "hardlink", "reflink":
+	default:
+		return model.CommandResult{}, errors.New("duplicates action must be inspect, trash, delete, hardlink, or reflink")
+	}
+	rootAbs, err := resolveRoot(root)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	scanned, partial, err := scan(ctx, app, rootAbs, opts.Action, opts.Refresh)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	if err := requireCompleteScan(partial, opts.Action); err != nil {
+		return model.CommandResult{}, err
+	}
+	if err := requireActionConfirmation(app.Options, opts.Action); err != nil {
+		return model.CommandResult{}, err
+	}
+	sets, err := findDuplicates(scanned, filesystem.DuplicateOptions{
+		MinSizeBytes: opts.MinSizeBytes,
+		Concurrency:  app.Config.ScanConcurrency,
+		Context:      ctx,
+	})
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	if opts.Limit > 0 && len(sets) > opts.Limit {
+		sets = sets[:opts.Limit]
+	}
+
+	planID := common.NewPlanID("analyze")
+	acting := opts.Action != "inspect"
+	out := make([]model.CandidateItem, 0, len(sets)*2)
+	planItems := make([]model.PlanItem, 0, len(sets)*2)
+	metrics := DuplicateMetrics{Root: rootAbs, Sets: make([]DuplicateSetMetrics, 0, len(sets))}
+	var wasted, wastedApparent, wastedDisk int64
+	selected := 0
+	errCount := 0
+	for _, set := range sets {
+		canonical := canonicalCopy(set.Files)
+		setMetrics := DuplicateSetMetrics{Hash: set.Hash, SizeBytes: set.SizeBytes, Canonical: canonical.Path}
+		for _, f := range set.Files {
+			item := model.CandidateItem{
+				ID:           "analyze-" + strconv.Itoa(len(out)+1),
+				Path:         f.Path,
+				SizeBytes:    f.SizeBytes,
+				LastModified: f.LastModified,
+				Category:     "duplicate",
+				Risk:         model.RiskMedium,
+				Result:       "keep",
+				Kind:         "file",
+				FileCount:    1,
+			}
+			if opts.SizeMode == filesystem.SizeModeDisk {
+				item.SizeBytes = f.AllocatedBytes
+			}
+			if f.Path != canonical.Path {
+				item.Selected = true
+				item.DuplicateOf = canonical.Path
+				item.Result = "duplicate"
+				selected++
+				wasted += item.SizeBytes
+				wastedApparent += f.SizeBytes
+				wastedDisk += f.AllocatedBytes
+				setMetrics.WastedBytes += item.SizeBytes
+				setMetrics.Copies = append(setMetrics.Copies, f.Path)
+				if acting && app.Options.DryRun {
+					item.Result = "planned"
+				} else if acting {
+					result, failed := dedupCopy(ctx, app, planID, rootAbs, item, f, opts)
+					item.Result = result
+					if failed {
+						errCount++
+					}
+				}
+			}
+			out = append(out, item)
+			planItems = append(planItems, model.PlanItem{
+				CandidateItem: item,
+				Action:        opts.Action,
+				Device:        f.Device,
+				Inode:         f.Inode,
+				AllowedRoots:  []string{rootAbs},
+			})
+		}
+		metrics.WastedBytes += setMetrics.WastedBytes
+		metrics.Sets = append(metrics.Sets, setMetrics)
+	}
+
+	if app.Options.DryRun && acting {
+		plan := common.NewPlan(planID, "analyze", planItems)
+		plan.TrashDir = opts.TrashDir
+		plan.SizeMode = opts.SizeMode
+		if err := common.SavePlan(app, plan); err != nil {
+			return model.CommandResult{}, err
+		}
+	}
+
+	res := model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "analyze",
+		PlanID:        planID,
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		DryRun:        app.Options.DryRun,
+		Summary: model.Summary{
+			ItemsTotal:                  len(out),
+			ItemsSelected:               selected,
+			EstimatedFreedBytes:         wasted,
+			SizeMode:                    opts.SizeMode,
+			EstimatedFreedApparentBytes: wastedApparent,
+			EstimatedFreedDiskBytes:     wastedDisk,
+			Errors:                      errCount,
+		},
+		Items:   out,
+		Metrics: metrics,
+	}
+	markPartial(&res, partial)
+	return res, nil
+}
+
+func canonicalCopy(files []filesystem.ScanItem) filesystem.ScanItem {
+	best := files[0]
+	for _, f := range files[1:] {
+		if f.LastModified.Before(best.LastModified) || (f.LastModified.Equal(best.LastModified) && f.Path < best.Path) {
+			best = f
+		}
+	}
+	return best
+}
+
+func dedupCopy(ctx context.Context, app *common.AppContext, planID, rootAbs string, item model.CandidateItem, f filesystem.ScanItem, opts DuplicatesOptions) (string, bool) {
+	switch opts.Action {
+	case "trash", "delete":
+		if err := verifyDuplicate(item.DuplicateOf, item.Path); err != nil {
+			_ = logAnalyzeAction(ctx, app, planID, opts.Action, item.Path, item.SizeBytes, "error", model.TrashedEntry{}, err)
+			return "error", true
+		}
+		node := &filesystem.TreeNode{Path: f.Path, SizeBytes: f.SizeBytes, AllocatedBytes: f.AllocatedBytes, Device: f.Device, Inode: f.Inode}
+		return actOnNode(ctx, app, planID, rootAbs, node, ActOptions{Action: opts.Action, TrashDir: opts.TrashDir, SizeMode: opts.SizeMode})
+	}
+	result, err := replaceDuplicate(opts.Action, item.DuplicateOf, item.Path, []string{rootAbs}, app.Whitelist)
+	failed := err != nil
+	if logErr := logAnalyzeAction(ctx, app, planID, opts.Action, item.Path, item.SizeBytes, result, model.TrashedEntry{}, err); logErr != nil {
+		failed = true
+	}
+	return result, failed
+}
+
+func replaceDuplicate(action, canonical, path string, allowedRoots []string, whitelist []string) (string, error) {
+	if err := safety.ValidatePath(path, allowedRoots, whitelist); err != nil {
+		return "skipped", err
+	}
+	if action == "reflink" {
+		if err := replaceWithReflink(canonical, path); err != nil {
+			return "error", err
+		}
+		return "reflinked", nil
+	}
+	if err := replaceWithHardlink(canonical, path); err != nil {
+		return "error", err
+	}
+	return "hardlinked", nil
+}
@@ -0,0 +1,152 @@
+package analyze
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/infra/filesystem"
+	"talpa/internal/infra/logging"
+	"talpa/internal/infra/planstore"
+)
+
+func writeDuplicateFixture(t *testing.T) (root, keep, copyPath string) {
+	t.Helper()
+	root = t.TempDir()
+	keep = filepath.Join(root, "old", "weights.bin")
+	copyPath = filepath.Join(root, "new", "weights.bin")
+	for _, p := range []string{keep, copyPath} {
+		mustMkdir(t, filepath.Dir(p))
+		if err := os.WriteFile(p, []byte("model weights"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "new", "other.bin"), []byte("something else"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	older := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(keep, older, older); err != nil {
+		t.Fatal(err)
+	}
+	return root, keep, copyPath
+}
+
+func TestDuplicatesReportsWastedBytesAndKeepsOldestCopy(t *testing.T) {
+	root, keep, copyPath := writeDuplicateFixture(t)
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Duplicates(context.Background(), app, root, DuplicatesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 2 || res.Summary.ItemsSelected != 1 || res.Summary.EstimatedFreedBytes != 13 {
+		t.Fatalf("unexpected duplicate report: %+v", res)
+	}
+	for _, it := range res.Items {
+		switch it.Path {
+		case keep:
+			if it.Selected || it.Result != "keep" {
+				t.Fatalf("expected oldest copy to be kept, got %+v", it)
+			}
+		case copyPath:
+			if !it.Selected || it.Result != "duplicate" || it.DuplicateOf != keep {
+				t.Fatalf("expected newer copy to be flagged, got %+v", it)
+			}
+		default:
+			t.Fatalf("unexpected item %s", it.Path)
+		}
+	}
+	m, ok := res.Metrics.(DuplicateMetrics)
+	if !ok || len(m.Sets) != 1 || m.WastedBytes != 13 || m.Sets[0].Canonical != keep {
+		t.Fatalf("unexpected metrics: %+v", res.Metrics)
+	}
+}
+
+func TestDuplicatesHardlinkActionRequiresConfirmation(t *testing.T) {
+	root, keep, copyPath := writeDuplicateFixture(t)
+
+	svc := NewService()
+	app := &common.AppContext{Logger: logging.NewNoopLogger()}
+	if _, err := svc.Duplicates(context.Background(), app, root, DuplicatesOptions{Action: "hardlink"}); err == nil {
+		t.Fatal("expected hardlink without --yes to be refused")
+	}
+
+	app.Options.Yes = true
+	res, err := svc.Duplicates(context.Background(), app, root, DuplicatesOptions{Action: "hardlink"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary.Errors != 0 {
+		t.Fatalf("unexpected errors: %+v", res.Items)
+	}
+	kdev, kino, _ := filesystem.PathIdentity(keep)
+	cdev, cino, _ := filesystem.PathIdentity(copyPath)
+	if kdev != cdev || kino != cino {
+		t.Fatalf("expected %s to be hardlinked to %s", copyPath, keep)
+	}
+
+	again, err := svc.Duplicates(context.Background(), app, root, DuplicatesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Items) != 0 {
+		t.Fatalf("expected hardlinked copies to no longer count as duplicates, got %+v", again.Items)
+	}
+}
+
+func TestDuplicatesDeleteRefusesCopyWhenCanonicalChanged(t *testing.T) {
+	root, keep, copyPath := writeDuplicateFixture(t)
+
+	store := planstore.NewFileStoreAt(filepath.Join(t.TempDir(), "plans"))
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	planned, err := NewService().Duplicates(context.Background(), app, root, DuplicatesOptions{Action: "delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := store.Load(planned.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keep, []byte("retrained weights"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Options = common.GlobalOptions{Yes: true, Confirm: "HIGH-RISK"}
+	res, err := NewService().Apply(context.Background(), app, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary.Errors != 1 {
+		t.Fatalf("expected the stale copy to be refused, got %+v", res.Items)
+	}
+	if _, err := os.Stat(copyPath); err != nil {
+		t.Fatalf("expected the copy to survive: %v", err)
+	}
+
+	if err := os.WriteFile(keep, []byte("model weights"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := findDuplicates
+	t.Cleanup(func() { findDuplicates = saved })
+	findDuplicates = func(items []filesystem.ScanItem, opts filesystem.DuplicateOptions) ([]filesystem.DuplicateSet, error) {
+		sets, err := saved(items, opts)
+		if err == nil {
+			err = os.Remove(keep)
+		}
+		return sets, err
+	}
+	app.Options.DryRun = false
+	res, err = NewService().Duplicates(context.Background(), app, root, DuplicatesOptions{Action: "delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary.Errors != 1 {
+		t.Fatalf("expected the copy of a vanished canonical file to be refused, got %+v", res.Items)
+	}
+	if _, err := os.Stat(copyPath); err != nil {
+		t.Fatalf("expected the last remaining copy to survive: %v", err)
+	}
+}
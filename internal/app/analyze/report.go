@@ -0,0 +1,231 @@
+package analyze
+
+import (
+	"context"
+	"math"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/query"
+	"talpa/internal/infra/filesystem"
+)
+
+const otherBucket = "other"
+
+type ReportOptions struct {
+	Limit    int
+	Query    string
+	SizeMode string
+	Refresh  bool
+}
+
+type HistogramBucket struct {
+	Key       string  `json:"key"`
+	Name      string  `json:"name,omitempty"`
+	FileCount int64   `json:"file_count"`
+	SizeBytes int64   `json:"size_bytes"`
+	Percent   float64 `json:"percent"`
+}
+
+type ReportMetrics struct {
+	Root        string            `json:"root"`
+	SizeMode    string            `json:"size_mode,omitempty"`
+	SizeBytes   int64             `json:"size_bytes"`
+	FileCount   int64             `json:"file_count"`
+	ByType      []HistogramBucket `json:"by_type"`
+	ByExtension []HistogramBucket `json:"by_extension"`
+	ByAge       []HistogramBucket `json:"by_age"`
+	ByOwner     []HistogramBucket `json:"by_owner"`
+}
+
+var ageBuckets = []struct {
+	key string
+	max time.Duration
+}{
+	{"<1w", 7 * 24 * time.Hour},
+	{"<1m", 30 * 24 * time.Hour},
+	{"<6m", 182 * 24 * time.Hour},
+	{"<1y", 365 * 24 * time.Hour},
+	{"older", 0},
+}
+
+var typeFamilies = map[string][]string{
+	"video":    {"mp4", "mkv", "mov", "avi", "webm", "m4v", "wmv", "flv", "mpg", "mpeg"},
+	"audio":    {"mp3", "flac", "wav", "ogg", "m4a", "aac", "opus", "wma"},
+	"image":    {"jpg", "jpeg", "png", "gif", "webp", "heic", "bmp", "tif", "tiff", "svg", "raw", "cr2", "nef", "dng", "psd"},
+	"document": {"pdf", "doc", "docx", "odt", "xls", "xlsx", "ods", "ppt", "pptx", "odp", "txt", "md", "rtf", "epub", "csv"},
+	"archive":  {"zip", "tar", "gz", "tgz", "bz2", "xz", "zst", "7z", "rar", "lz4"},
+	"disk":     {"iso", "img", "qcow2", "vdi", "vmdk", "vhd", "vhdx", "dmg"},
+	"package":  {"deb", "rpm", "apk", "snap", "flatpak", "appimage", "whl", "jar", "nupkg"},
+	"code":     {"go", "c", "h", "cc", "cpp", "hpp", "rs", "py", "js", "ts", "tsx", "jsx", "java", "kt", "rb", "php", "cs", "swift", "sh", "json", "yaml", "yml", "toml", "xml", "html", "css"},
+	"binary":   {"o", "a", "so", "dll", "exe", "class", "pyc", "wasm", "rlib", "bin"},
+	"database": {"db", "sqlite", "sqlite3", "mdb", "ldb", "sst"},
+	"log":      {"log", "out", "trace"},
+}
+
+var extensionFamily = func() map[string]string {
+	out := map[string]string{}
+	for family, exts := range typeFamilies {
+		for _, ext := range exts {
+			out[ext] = family
+		}
+	}
+	return out
+}()
+
+var lookupOwner = func(uid string) string {
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
+
+type histogram map[string]*HistogramBucket
+
+func (h histogram) add(key string, size int64) {
+	b, ok := h[key]
+	if !ok {
+		b = &HistogramBucket{Key: key}
+		h[key] = b
+	}
+	b.FileCount++
+	b.SizeBytes += size
+}
+
+func (h histogram) buckets(total int64, limit int) []HistogramBucket {
+	out := make([]HistogramBucket, 0, len(h))
+	for _, b := range h {
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SizeBytes != out[j].SizeBytes {
+			return out[i].SizeBytes > out[j].SizeBytes
+		}
+		return out[i].Key < out[j].Key
+	})
+	if limit > 0 && len(out) > limit {
+		rest := HistogramBucket{Key: otherBucket}
+		for _, b := range out[limit:] {
+			rest.FileCount += b.FileCount
+			rest.SizeBytes += b.SizeBytes
+		}
+		out = append(out[:limit], rest)
+	}
+	for i := range out {
+		out[i].Percent = percentOf(out[i].SizeBytes, total)
+	}
+	return out
+}
+
+func (Service) Report(ctx context.Context, app *common.AppContext, root string, opts ReportOptions) (model.CommandResult, error) {
+	start := time.Now()
+	rootAbs, err := resolveRoot(root)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	filter, err := query.Parse(opts.Query, start)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	scanned, partial, err := scan(ctx, app, rootAbs, "inspect", opts.Refresh)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+
+	byType, byExt, byAge, byOwner := histogram{}, histogram{}, histogram{}, histogram{}
+	metrics := ReportMetrics{Root: rootAbs, SizeMode: opts.SizeMode}
+	type inode struct{ dev, ino uint64 }
+	seen := map[inode]bool{}
+	for _, it := range scanned {
+		if it.IsDir {
+			continue
+		}
+		size := it.SizeBytes
+		if opts.SizeMode == filesystem.SizeModeDisk {
+			size = it.AllocatedBytes
+		}
+		if !filter.Match(query.Entry{Path: it.Path, SizeBytes: size, ModTime: it.LastModified, UID: it.UID}) {
+			continue
+		}
+		if it.Links > 1 && (it.Device != 0 || it.Inode != 0) {
+			key := inode{it.Device, it.Inode}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		ext := fileExtension(it.Path)
+		metrics.FileCount++
+		metrics.SizeBytes += size
+		byType.add(fileFamily(ext), size)
+		if ext == "" {
+			ext = "(none)"
+		}
+		byExt.add(ext, size)
+		byAge.add(ageBucket(start.Sub(it.LastModified)), size)
+		byOwner.add(strconv.FormatUint(uint64(it.UID), 10), size)
+	}
+
+	metrics.ByType = byType.buckets(metrics.SizeBytes, 0)
+	metrics.ByExtension = byExt.buckets(metrics.SizeBytes, opts.Limit)
+	metrics.ByAge = make([]HistogramBucket, 0, len(ageBuckets))
+	for _, b := range ageBuckets {
+		bucket := HistogramBucket{Key: b.key}
+		if counted, ok := byAge[b.key]; ok {
+			bucket = *counted
+		}
+		bucket.Percent = percentOf(bucket.SizeBytes, metrics.SizeBytes)
+		metrics.ByAge = append(metrics.ByAge, bucket)
+	}
+	metrics.ByOwner = byOwner.buckets(metrics.SizeBytes, 0)
+	for i := range metrics.ByOwner {
+		metrics.ByOwner[i].Name = lookupOwner(metrics.ByOwner[i].Key)
+	}
+
+	res := model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "analyze",
+		PlanID:        common.NewPlanID("analyze"),
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		DryRun:        app.Options.DryRun,
+		Summary:       model.Summary{SizeMode: opts.SizeMode},
+		Metrics:       metrics,
+	}
+	markPartial(&res, partial)
+	return res, nil
+}
+
+func fileExtension(path string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+func fileFamily(ext string) string {
+	if family, ok := extensionFamily[ext]; ok {
+		return family
+	}
+	return otherBucket
+}
+
+func ageBucket(age time.Duration) string {
+	for _, b := range ageBuckets {
+		if b.max == 0 || age < b.max {
+			return b.key
+		}
+	}
+	return ageBuckets[len(ageBuckets)-1].key
+}
+
+func percentOf(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
@@ -0,0 +1,71 @@
+package analyze
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/infra/logging"
+)
+
+func TestReportBuildsTypeExtensionAgeAndOwnerHistograms(t *testing.T) {
+	root := t.TempDir()
+	mustMkdir(t, filepath.Join(root, "media"))
+	clip := filepath.Join(root, "media", "trip.MP4")
+	mustWrite(t, clip, 3000)
+	mustWrite(t, filepath.Join(root, "media", "song.mp3"), 500)
+	mustWrite(t, filepath.Join(root, "notes.txt"), 400)
+	mustWrite(t, filepath.Join(root, "README"), 100)
+	old := time.Now().AddDate(-2, 0, 0)
+	if err := os.Chtimes(clip, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	restore := lookupOwner
+	lookupOwner = func(uid string) string { return "user" + uid }
+	defer func() { lookupOwner = restore }()
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Report(context.Background(), app, root, ReportOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := res.Metrics.(ReportMetrics)
+	if !ok {
+		t.Fatalf("expected report metrics, got %T", res.Metrics)
+	}
+	if m.FileCount != 4 || m.SizeBytes != 4000 {
+		t.Fatalf("unexpected totals: %d files, %d bytes", m.FileCount, m.SizeBytes)
+	}
+	if m.ByType[0].Key != "video" || m.ByType[0].SizeBytes != 3000 || m.ByType[0].Percent != 75 {
+		t.Fatalf("unexpected type histogram: %+v", m.ByType)
+	}
+	if len(m.ByExtension) != 3 || m.ByExtension[0].Key != "mp4" || m.ByExtension[1].Key != "mp3" || m.ByExtension[2].Key != "other" || m.ByExtension[2].FileCount != 2 {
+		t.Fatalf("unexpected extension histogram: %+v", m.ByExtension)
+	}
+	if len(m.ByAge) != 5 || m.ByAge[0].Key != "<1w" || m.ByAge[0].FileCount != 3 || m.ByAge[4].Key != "older" || m.ByAge[4].SizeBytes != 3000 {
+		t.Fatalf("unexpected age histogram: %+v", m.ByAge)
+	}
+	if len(m.ByOwner) != 1 || m.ByOwner[0].FileCount != 4 || m.ByOwner[0].Name != "user"+m.ByOwner[0].Key {
+		t.Fatalf("unexpected owner histogram: %+v", m.ByOwner)
+	}
+
+	res, err = NewService().Report(context.Background(), app, root, ReportOptions{Query: "ext = txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := res.Metrics.(ReportMetrics); m.FileCount != 1 || m.ByType[0].Key != "document" {
+		t.Fatalf("expected query to narrow the report, got %+v", m)
+	}
+}
+
+func TestFileFamilyTreatsTSAsCode(t *testing.T) {
+	for ext, want := range map[string]string{"ts": "code", "tsx": "code", "mp4": "video", "unknownext": "other"} {
+		if got := fileFamily(ext); got != want {
+			t.Fatalf("%s: expected %s, got %s", ext, want, got)
+		}
+	}
+}
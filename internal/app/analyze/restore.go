@@ -0,0 +1,64 @@
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"talpa/internal/domain/safety"
+)
+
+var (
+	ErrRestoreConflict   = errors.New("RESTORE_CONFLICT: original path already exists")
+	ErrTrashEntryChanged = errors.New("RESTORE_INVALID: trash entry changed since it was trashed")
+)
+
+func RestoreFromTrash(trashedPath, originalPath string, whitelist []string, expectedDev uint64, expectedIno uint64) error {
+	if strings.TrimSpace(trashedPath) == "" || strings.TrimSpace(originalPath) == "" {
+		return errors.New("PATH_INVALID: empty path")
+	}
+	trashedAbs, err := filepath.Abs(filepath.Clean(trashedPath))
+	if err != nil {
+		return err
+	}
+	originalAbs, err := filepath.Abs(filepath.Clean(originalPath))
+	if err != nil {
+		return err
+	}
+	if err := safety.ValidatePath(originalAbs, nil, whitelist); err != nil {
+		return err
+	}
+	if filepath.Base(filepath.Dir(trashedAbs)) != trashFilesDir {
+		return fmt.Errorf("PATH_BLOCKED: not a trash entry %s", trashedAbs)
+	}
+	trashRoot := filepath.Dir(filepath.Dir(trashedAbs))
+	infoPath := filepath.Join(trashRoot, trashInfoDir, filepath.Base(trashedAbs)+trashInfoSuffix)
+	raw, err := os.ReadFile(infoPath)
+	if err != nil {
+		return fmt.Errorf("RESTORE_INVALID: %w", err)
+	}
+	info, err := parseTrashInfo(raw)
+	if err != nil {
+		return fmt.Errorf("RESTORE_INVALID: %w", err)
+	}
+	recorded := info.Path
+	if !filepath.IsAbs(recorded) {
+		recorded = filepath.Join(trashTopDir(trashRoot), recorded)
+	}
+	if filepath.Clean(recorded) != originalAbs {
+		return fmt.Errorf("RESTORE_INVALID: trashinfo path %s does not match %s", recorded, originalAbs)
+	}
+	return secureRestoreFromTrash(trashedAbs, originalAbs, infoPath, expectedDev, expectedIno)
+}
+
+func trashTopDir(trashRoot string) string {
+	if strings.HasPrefix(filepath.Base(trashRoot), ".Trash-") {
+		return filepath.Dir(trashRoot)
+	}
+	if filepath.Base(filepath.Dir(trashRoot)) == ".Trash" {
+		return filepath.Dir(filepath.Dir(trashRoot))
+	}
+	return string(filepath.Separator)
+}
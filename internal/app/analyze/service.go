@@ -3,6 +3,7 @@ package analyze
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,8 +13,10 @@ import (
 
 	"talpa/internal/app/common"
 	"talpa/internal/domain/model"
+	"talpa/internal/domain/query"
 	"talpa/internal/domain/safety"
 	"talpa/internal/infra/filesystem"
+	"talpa/internal/infra/snapshotstore"
 )
 
 type Service struct{}
@@ -27,178 +30,448 @@ type Options struct {
 	OnlyCandidates bool
 	Action         string
 	TrashDir       string
+	SizeMode       string
+	Refresh        bool
+	SaveSnapshot   string
+}
+
+type ActOptions struct {
+	Action   string
+	TrashDir string
+	SizeMode string
 }
 
 func NewService() Service { return Service{} }
 
 func (Service) Run(ctx context.Context, app *common.AppContext, root string, opts Options) (model.CommandResult, error) {
-	_ = ctx
 	start := time.Now()
-	if root == "" {
-		h, err := os.UserHomeDir()
-		if err != nil {
-			return model.CommandResult{}, err
-		}
-		root = h
+	rootAbs, err := resolveRoot(root)
+	if err != nil {
+		return model.CommandResult{}, err
 	}
-	rootAbs, err := filepath.Abs(root)
+	filter, err := query.Parse(opts.Query, start)
 	if err != nil {
 		return model.CommandResult{}, err
 	}
+	if opts.SaveSnapshot != "" {
+		if err := snapshotstore.ValidateName(opts.SaveSnapshot); err != nil {
+			return model.CommandResult{}, err
+		}
+	}
 
-	items, err := filesystem.Scan(root, filesystem.ScanOptions{
-		MaxDepth:       opts.Depth,
-		Excludes:       []string{"/proc", "/sys", "/dev", "/run"},
-		SkipMountpoint: opts.Action != "inspect",
-		SkipNetworkFS:  true,
-		Context:        ctx,
-	})
+	scanned, partial, err := scan(ctx, app, rootAbs, opts.Action, opts.Refresh)
 	if err != nil {
 		return model.CommandResult{}, err
 	}
+	if err := requireCompleteScan(partial, opts.Action); err != nil {
+		return model.CommandResult{}, err
+	}
 
-	if !app.Options.DryRun && opts.Action == "delete" {
-		if err := common.RequireHighRiskConfirmationOrDryRun(app.Options, "analyze delete"); err != nil {
-			return model.CommandResult{}, err
-		}
-	} else if !app.Options.DryRun && opts.Action == "trash" {
-		if err := common.RequireConfirmationOrDryRun(app.Options, "analyze trash"); err != nil {
-			return model.CommandResult{}, err
-		}
-		if err := ensureTrashActionSupported(); err != nil {
+	if err := requireActionConfirmation(app.Options, opts.Action); err != nil {
+		return model.CommandResult{}, err
+	}
+	if opts.SaveSnapshot != "" {
+		if err := saveSnapshot(app, opts.SaveSnapshot, rootAbs, scanned, partial); err != nil {
 			return model.CommandResult{}, err
 		}
 	}
 
-	items = filterItems(items, opts)
-	sortItems(items, opts.SortBy)
-	if opts.Limit > 0 && len(items) > opts.Limit {
-		items = items[:opts.Limit]
-	}
+	tree := filesystem.BuildTree(rootAbs, scanned, opts.Depth)
+	nodes := flattenTree(tree, opts, filter)
 
-	out := make([]model.CandidateItem, 0, len(items))
-	var estimate int64
-	candidates := 0
+	planID := common.NewPlanID("analyze")
+	out := make([]model.CandidateItem, 0, len(nodes))
+	planItems := make([]model.PlanItem, 0, len(nodes))
+	var candidateNodes []*filesystem.TreeNode
 	errCount := 0
-	for i, it := range items {
-		candidate := isCleanupCandidate(it.Path)
+	for i, n := range nodes {
+		it := n.node
+		candidate := n.candidate
 		if candidate {
-			estimate += it.SizeBytes
-			candidates++
+			candidateNodes = append(candidateNodes, it)
 		}
 		result := "inspect"
 		if candidate {
 			result = "candidate"
 		}
-		if opts.Action == "delete" {
-			if candidate {
-				if app.Options.DryRun {
-					result = "planned"
-				} else {
-					result = "deleted"
-					if err := safety.SafeDeleteWithIdentity(it.Path, []string{rootAbs}, app.Whitelist, false, it.Device, it.Inode); err != nil {
-						if os.IsNotExist(err) {
-							result = "skipped"
-						} else {
-							result = "error"
-							errCount++
-						}
-					}
-				}
-			} else {
+		if opts.Action == "delete" || opts.Action == "trash" {
+			if !candidate {
 				result = "skipped"
-			}
-		} else if opts.Action == "trash" {
-			if candidate {
-				if app.Options.DryRun {
-					result = "planned"
-				} else {
-					result = "trashed"
-					if err := moveToTrash(it.Path, opts.TrashDir, []string{rootAbs}, app.Whitelist, it.Device, it.Inode); err != nil {
-						if os.IsNotExist(err) {
-							result = "skipped"
-						} else {
-							result = "error"
-							errCount++
-						}
-					}
-				}
+			} else if app.Options.DryRun {
+				result = "planned"
+			} else if r, failed := actOnNode(ctx, app, planID, rootAbs, it, ActOptions{Action: opts.Action, TrashDir: opts.TrashDir, SizeMode: opts.SizeMode}); failed {
+				result = r
+				errCount++
 			} else {
-				result = "skipped"
+				result = r
 			}
 		}
-		out = append(out, model.CandidateItem{
+		kind := "file"
+		if it.IsDir {
+			kind = "dir"
+		}
+		item := model.CandidateItem{
 			ID:           "analyze-" + strconv.Itoa(i+1),
 			RuleID:       "",
 			Path:         it.Path,
-			SizeBytes:    it.SizeBytes,
+			SizeBytes:    it.Bytes(opts.SizeMode),
 			LastModified: it.LastModified,
 			Category:     "tree_node",
 			Risk:         model.RiskMedium,
 			Selected:     candidate,
 			RequiresRoot: false,
 			Result:       result,
+			Kind:         kind,
+			Depth:        n.depth,
+			FileCount:    it.FileCount,
+			SharedLinks:  it.SharedLinks,
+		}
+		out = append(out, item)
+		planItems = append(planItems, model.PlanItem{
+			CandidateItem: item,
+			Action:        opts.Action,
+			Device:        it.Device,
+			Inode:         it.Inode,
+			AllowedRoots:  []string{rootAbs},
 		})
 	}
 
+	if app.Options.DryRun && (opts.Action == "delete" || opts.Action == "trash") {
+		plan := common.NewPlan(planID, "analyze", planItems)
+		plan.TrashDir = opts.TrashDir
+		plan.SizeMode = opts.SizeMode
+		if err := common.SavePlan(app, plan); err != nil {
+			return model.CommandResult{}, err
+		}
+	}
+
+	estimate := filesystem.ReclaimableNodes(candidateNodes)
+	res := model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "analyze",
+		PlanID:        planID,
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		DryRun:        app.Options.DryRun,
+		Summary: model.Summary{
+			ItemsTotal:                  len(out),
+			ItemsSelected:               len(candidateNodes),
+			EstimatedFreedBytes:         estimate.Bytes(opts.SizeMode),
+			SizeMode:                    opts.SizeMode,
+			EstimatedFreedApparentBytes: estimate.ApparentBytes,
+			EstimatedFreedDiskBytes:     estimate.AllocatedBytes,
+			Errors:                      errCount,
+		},
+		Items: out,
+		Metrics: TreeMetrics{
+			Root:           tree.Path,
+			SizeBytes:      tree.Bytes(opts.SizeMode),
+			SizeMode:       opts.SizeMode,
+			ApparentBytes:  tree.SizeBytes,
+			AllocatedBytes: tree.AllocatedBytes,
+			FileCount:      tree.FileCount,
+			LastModified:   tree.LastModified,
+		},
+	}
+	markPartial(&res, partial)
+	return res, nil
+}
+
+func (Service) Tree(ctx context.Context, app *common.AppContext, root string, depth int, refresh bool) (*filesystem.TreeNode, error) {
+	rootAbs, err := resolveRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	scanned, partial, err := scan(ctx, app, rootAbs, "inspect", refresh)
+	if err != nil {
+		return nil, err
+	}
+	tree := filesystem.BuildTree(rootAbs, scanned, depth)
+	if partial != nil {
+		return tree, partial
+	}
+	return tree, nil
+}
+
+func (Service) ActOn(ctx context.Context, app *common.AppContext, root string, nodes []*filesystem.TreeNode, opts ActOptions) (model.CommandResult, error) {
+	start := time.Now()
+	if opts.Action != "delete" && opts.Action != "trash" {
+		return model.CommandResult{}, errors.New("action must be trash or delete")
+	}
+	rootAbs, err := resolveRoot(root)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	if err := requireActionConfirmation(app.Options, opts.Action); err != nil {
+		return model.CommandResult{}, err
+	}
+
+	sorted := append([]*filesystem.TreeNode(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	planID := common.NewPlanID("analyze")
+	out := make([]model.CandidateItem, 0, len(sorted))
+	var done []*filesystem.TreeNode
+	errCount := 0
+	for i, n := range sorted {
+		covered := false
+		for _, d := range done {
+			if hasPathPrefix(n.Path, d.Path) {
+				covered = true
+			}
+		}
+		result := "skipped"
+		if !covered && app.Options.DryRun {
+			result = "planned"
+		} else if !covered {
+			var failed bool
+			result, failed = actOnNode(ctx, app, planID, rootAbs, n, opts)
+			if failed {
+				errCount++
+			}
+		}
+		if result == "planned" || result == "deleted" || result == "trashed" {
+			done = append(done, n)
+		}
+		kind := "file"
+		if n.IsDir {
+			kind = "dir"
+		}
+		out = append(out, model.CandidateItem{
+			ID:           "analyze-" + strconv.Itoa(i+1),
+			Path:         n.Path,
+			SizeBytes:    n.Bytes(opts.SizeMode),
+			LastModified: n.LastModified,
+			Category:     "tree_node",
+			Risk:         model.RiskMedium,
+			Selected:     !covered,
+			Result:       result,
+			Kind:         kind,
+			FileCount:    n.FileCount,
+			SharedLinks:  n.SharedLinks,
+		})
+	}
+	freed := filesystem.ReclaimableNodes(done)
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "analyze",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DurationMS:    time.Since(start).Milliseconds(),
 		DryRun:        app.Options.DryRun,
 		Summary: model.Summary{
-			ItemsTotal:          len(out),
-			ItemsSelected:       candidates,
-			EstimatedFreedBytes: estimate,
-			Errors:              errCount,
+			ItemsTotal:                  len(out),
+			ItemsSelected:               len(done),
+			EstimatedFreedBytes:         freed.Bytes(opts.SizeMode),
+			SizeMode:                    opts.SizeMode,
+			EstimatedFreedApparentBytes: freed.ApparentBytes,
+			EstimatedFreedDiskBytes:     freed.AllocatedBytes,
+			Errors:                      errCount,
 		},
 		Items: out,
 	}, nil
 }
 
-func moveToTrash(path, trashDir string, allowedRoots []string, whitelist []string, expectedDev uint64, expectedIno uint64) error {
+func resolveRoot(root string) (string, error) {
+	if root == "" {
+		h, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		root = h
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(abs), nil
+}
+
+func scan(ctx context.Context, app *common.AppContext, rootAbs, action string, refresh bool) ([]filesystem.ScanItem, *filesystem.PartialScanError, error) {
+	var index *filesystem.ScanIndex
+	if app.Config.ScanIndex && app.Config.ScanIndexPath != "" {
+		if refresh {
+			index = filesystem.NewScanIndex(app.Config.ScanIndexPath, app.Config.ScanIndexMaxAge)
+		} else {
+			index = filesystem.OpenScanIndex(app.Config.ScanIndexPath, app.Config.ScanIndexMaxAge)
+		}
+	}
+	items, err := filesystem.Scan(rootAbs, filesystem.ScanOptions{
+		Excludes:       append([]string{"/proc", "/sys", "/dev", "/run"}, app.Config.Excludes...),
+		Concurrency:    app.Config.ScanConcurrency,
+		Timeout:        app.Config.ScanTimeout,
+		SkipMountpoint: action != "inspect",
+		SkipNetworkFS:  true,
+		IncludeDirs:    true,
+		Index:          index,
+		Progress:       app.ScanProgress,
+		Context:        ctx,
+	})
+	var partial *filesystem.PartialScanError
+	if errors.As(err, &partial) {
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	_ = index.Save()
+	return items, partial, nil
+}
+
+func requireCompleteScan(partial *filesystem.PartialScanError, action string) error {
+	if partial == nil || action == "" || action == "inspect" {
+		return nil
+	}
+	return fmt.Errorf("scan timed out with %d directories not visited; raise scanner.timeout or narrow the path before using --action %s", len(partial.Unvisited), action)
+}
+
+func markPartial(res *model.CommandResult, partial *filesystem.PartialScanError) {
+	if partial == nil {
+		return
+	}
+	res.Partial = true
+	res.Unvisited = partial.Unvisited
+}
+
+func requireActionConfirmation(opts common.GlobalOptions, action string) error {
+	if opts.DryRun {
+		return nil
+	}
+	switch action {
+	case "delete":
+		return common.RequireHighRiskConfirmationOrDryRun(opts, "analyze delete")
+	case "trash":
+		if err := common.RequireConfirmationOrDryRun(opts, "analyze trash"); err != nil {
+			return err
+		}
+		return ensureTrashActionSupported()
+	case "hardlink", "reflink":
+		return common.RequireConfirmationOrDryRun(opts, "analyze "+action)
+	}
+	return nil
+}
+
+func (Service) Apply(ctx context.Context, app *common.AppContext, plan model.Plan) (model.CommandResult, error) {
+	if err := common.RequirePlanCommand(plan, "analyze"); err != nil {
+		return model.CommandResult{}, err
+	}
+	actions := map[string]bool{}
+	for _, item := range plan.Items {
+		if !item.Selected {
+			continue
+		}
+		switch item.Action {
+		case "trash", "delete", "hardlink", "reflink":
+			actions[item.Action] = true
+		default:
+			return model.CommandResult{}, errors.New("PLAN_INVALID: unsupported analyze action " + item.Action)
+		}
+	}
+	for _, action := range []string{"delete", "trash", "hardlink", "reflink"} {
+		if !actions[action] {
+			continue
+		}
+		if err := requireActionConfirmation(app.Options, action); err != nil {
+			return model.CommandResult{}, err
+		}
+	}
+	return common.ExecutePlan(ctx, app, plan, common.UsageSizeOf(plan.SizeMode), func(item model.PlanItem) (string, model.TrashedEntry, error) {
+		if item.Action == "hardlink" || item.Action == "reflink" {
+			result, err := replaceDuplicate(item.Action, item.DuplicateOf, item.Path, item.AllowedRoots, app.Whitelist)
+			return result, model.TrashedEntry{}, err
+		}
+		if item.DuplicateOf != "" {
+			if err := verifyDuplicate(item.DuplicateOf, item.Path); err != nil {
+				return "error", model.TrashedEntry{}, err
+			}
+		}
+		if item.Action == "trash" {
+			trashed, err := moveToTrash(item.Path, plan.TrashDir, item.AllowedRoots, app.Whitelist, item.Device, item.Inode)
+			if err != nil {
+				return "error", model.TrashedEntry{}, err
+			}
+			return "trashed", trashed, nil
+		}
+		if err := safety.SafeDeleteWithIdentity(item.Path, item.AllowedRoots, app.Whitelist, false, item.Device, item.Inode); err != nil {
+			return "error", model.TrashedEntry{}, err
+		}
+		return "deleted", model.TrashedEntry{}, nil
+	}), nil
+}
+
+func logAnalyzeAction(ctx context.Context, app *common.AppContext, planID string, action string, path string, sizeBytes int64, result string, trashed model.TrashedEntry, opErr error) error {
+	entry := model.OperationLogEntry{
+		Timestamp:   time.Now().UTC(),
+		PlanID:      planID,
+		Command:     "analyze",
+		Action:      action,
+		Path:        path,
+		TrashPath:   trashed.Path,
+		TrashDevice: trashed.Device,
+		TrashInode:  trashed.Inode,
+		Category:    "tree_node",
+		SizeBytes:   sizeBytes,
+		Risk:        string(model.RiskMedium),
+		Result:      result,
+		DryRun:      false,
+	}
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	}
+	return app.Logger.Log(ctx, entry)
+}
+
+func moveToTrash(path, trashDir string, allowedRoots []string, whitelist []string, expectedDev uint64, expectedIno uint64) (model.TrashedEntry, error) {
 	if strings.TrimSpace(path) == "" {
-		return errors.New("empty path")
+		return model.TrashedEntry{}, errors.New("empty path")
 	}
 	srcAbs, err := filepath.Abs(filepath.Clean(path))
 	if err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	if err := safety.ValidatePath(srcAbs, allowedRoots, whitelist); err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	if strings.TrimSpace(trashDir) == "" {
-		home, err := os.UserHomeDir()
+		trashDir, err = defaultTrashDir()
 		if err != nil {
-			return err
+			return model.TrashedEntry{}, err
 		}
-		trashDir = filepath.Join(home, ".local", "share", "Trash", "files")
 	}
 	trashAbs, err := filepath.Abs(filepath.Clean(trashDir))
 	if err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	homeAbs, err := filepath.Abs(filepath.Clean(home))
 	if err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	if !hasPathPrefix(trashAbs, homeAbs) {
-		return errors.New("PATH_BLOCKED: trash dir must be inside user home")
+		return model.TrashedEntry{}, errors.New("PATH_BLOCKED: trash dir must be inside user home")
 	}
 	if err := safety.ValidatePath(trashAbs, []string{homeAbs}, nil); err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	if err := prepareTrashDir(trashAbs); err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	return secureMoveToTrash(srcAbs, trashAbs, allowedRoots, whitelist, expectedDev, expectedIno)
 }
 
+func defaultTrashDir() (string, error) {
+	if dataHome := strings.TrimSpace(os.Getenv("XDG_DATA_HOME")); dataHome != "" && filepath.IsAbs(dataHome) {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
 func isAllowedTrashSource(path string, roots []string, whitelist []string) bool {
 	resolvedPath := resolvePath(path)
 	if matchesWhitelist(path, whitelist) || matchesWhitelist(resolvedPath, whitelist) {
@@ -253,39 +526,6 @@ func matchesWhitelist(path string, whitelist []string) bool {
 	return false
 }
 
-func filterItems(items []filesystem.ScanItem, opts Options) []filesystem.ScanItem {
-	if opts.MinSizeBytes <= 0 && strings.TrimSpace(opts.Query) == "" && !opts.OnlyCandidates {
-		return items
-	}
-	q := strings.ToLower(strings.TrimSpace(opts.Query))
-	out := make([]filesystem.ScanItem, 0, len(items))
-	for _, it := range items {
-		if it.SizeBytes < opts.MinSizeBytes {
-			continue
-		}
-		pathLower := strings.ToLower(it.Path)
-		if q != "" && !strings.Contains(pathLower, q) {
-			continue
-		}
-		if opts.OnlyCandidates && !isCleanupCandidate(it.Path) {
-			continue
-		}
-		out = append(out, it)
-	}
-	return out
-}
-
-func sortItems(items []filesystem.ScanItem, sortBy string) {
-	switch sortBy {
-	case "path":
-		sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
-	case "mtime":
-		sort.Slice(items, func(i, j int) bool { return items[i].LastModified.After(items[j].LastModified) })
-	default:
-		sort.Slice(items, func(i, j int) bool { return items[i].SizeBytes > items[j].SizeBytes })
-	}
-}
-
 func isCleanupCandidate(path string) bool {
 	base := strings.ToLower(filepath.Base(path))
 	for _, n := range []string{"cache", "tmp", "temp", "log", "logs", "thumbnail", "thumbnails", "node_modules", "target", "dist", "build", "venv", ".venv", "__pycache__", ".tox", ".mypy_cache", "coverage", ".gradle", ".npm", ".yarn"} {
@@ -302,3 +542,28 @@ func isCleanupCandidate(path string) bool {
 	}
 	return false
 }
+
+func actOnNode(ctx context.Context, app *common.AppContext, planID, rootAbs string, node *filesystem.TreeNode, opts ActOptions) (string, bool) {
+	failed := false
+	var err error
+	var trashed model.TrashedEntry
+	result := "deleted"
+	if opts.Action == "trash" {
+		result = "trashed"
+		trashed, err = moveToTrash(node.Path, opts.TrashDir, []string{rootAbs}, app.Whitelist, node.Device, node.Inode)
+	} else {
+		err = safety.SafeDeleteWithIdentity(node.Path, []string{rootAbs}, app.Whitelist, false, node.Device, node.Inode)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			result = "skipped"
+		} else {
+			result = "error"
+			failed = true
+		}
+	}
+	if logErr := logAnalyzeAction(ctx, app, planID, opts.Action, node.Path, node.Bytes(opts.SizeMode), result, trashed, err); logErr != nil {
+		failed = true
+	}
+	return result, failed
+}
@@ -60,9 +60,17 @@ func TestRunDryRunGoldenJSON(t *testing.T) {
 func normalizeAnalyzeResult(res *model.CommandResult, home string) {
 	norm := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 	res.Timestamp = norm
+	res.PlanID = ""
 	res.DurationMS = 0
+	res.Summary.EstimatedFreedDiskBytes = 0
 	for i := range res.Items {
 		res.Items[i].Path = strings.ReplaceAll(res.Items[i].Path, home, "$HOME")
 		res.Items[i].LastModified = norm
 	}
+	if m, ok := res.Metrics.(TreeMetrics); ok {
+		m.Root = strings.ReplaceAll(m.Root, home, "$HOME")
+		m.LastModified = norm
+		m.AllocatedBytes = 0
+		res.Metrics = m
+	}
 }
@@ -10,6 +10,7 @@ import (
 
 	"talpa/internal/app/common"
 	"talpa/internal/infra/logging"
+	"talpa/internal/infra/planstore"
 )
 
 func TestRunFiltersAndOnlyCandidates(t *testing.T) {
@@ -31,11 +32,17 @@ func TestRunFiltersAndOnlyCandidates(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(res.Items) != 1 {
-		t.Fatalf("expected one candidate item, got %d", len(res.Items))
+	var selected []string
+	for _, it := range res.Items {
+		if it.Selected {
+			selected = append(selected, it.Path)
+		}
+		if it.Path == filepath.Join(root, "project", "docs") {
+			t.Fatalf("expected non-matching branch to be filtered out")
+		}
 	}
-	if !res.Items[0].Selected || res.Items[0].Result != "candidate" {
-		t.Fatalf("expected selected candidate item")
+	if len(selected) != 1 || selected[0] != filepath.Join(root, "project", "node_modules") {
+		t.Fatalf("expected node_modules directory as the only candidate, got %v", selected)
 	}
 }
 
@@ -171,7 +178,7 @@ func TestMoveToTrashRejectsSymlinkTrashDir(t *testing.T) {
 		t.Skipf("unable to create symlink: %v", err)
 	}
 
-	err := moveToTrash(src, symlinkTrash, []string{root}, nil, 0, 0)
+	_, err := moveToTrash(src, symlinkTrash, []string{root}, nil, 0, 0)
 	if err == nil {
 		t.Fatalf("expected symlink trash dir to be rejected")
 	}
@@ -191,3 +198,103 @@ func mustWrite(t *testing.T, path string, size int) {
 		t.Fatal(err)
 	}
 }
+
+func TestRunMarksPartialScanAndRefusesActions(t *testing.T) {
+	root := t.TempDir()
+	mustMkdir(t, filepath.Join(root, "project", "cache"))
+	target := filepath.Join(root, "project", "cache", "a.tmp")
+	mustWrite(t, target, 16)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(ctx, app, root, Options{Depth: 4, Limit: 10, SortBy: "size"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Partial || len(res.Unvisited) != 1 || res.Unvisited[0] != root {
+		t.Fatalf("expected partial result with root unvisited, got partial=%v unvisited=%v", res.Partial, res.Unvisited)
+	}
+
+	app.Options = common.GlobalOptions{DryRun: false, Yes: true, Confirm: "HIGH-RISK"}
+	if _, err := NewService().Run(ctx, app, root, Options{Depth: 4, Limit: 10, SortBy: "size", Action: "delete"}); err == nil {
+		t.Fatalf("expected delete to be refused on a partial scan")
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Fatalf("expected target to survive refused action: %v", err)
+	}
+}
+
+func TestRunQueryExpressionFiltersByExtensionSizeAndAge(t *testing.T) {
+	root := t.TempDir()
+	mustMkdir(t, filepath.Join(root, "videos"))
+	mustMkdir(t, filepath.Join(root, "docs"))
+	oldClip := filepath.Join(root, "videos", "old.mp4")
+	newClip := filepath.Join(root, "videos", "new.mp4")
+	smallClip := filepath.Join(root, "videos", "small.mkv")
+	mustWrite(t, oldClip, 4096)
+	mustWrite(t, newClip, 4096)
+	mustWrite(t, smallClip, 10)
+	mustWrite(t, filepath.Join(root, "docs", "big.txt"), 4096)
+	old := time.Now().AddDate(0, -7, 0)
+	for _, p := range []string{oldClip, smallClip} {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, root, Options{
+		Depth:  4,
+		SortBy: "path",
+		Query:  "ext in (mp4, mkv) and size > 1KiB and mtime > 6mo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, it := range res.Items {
+		got = append(got, it.Path)
+	}
+	want := []string{filepath.Join(root, "videos"), oldClip}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if _, err := NewService().Run(context.Background(), app, root, Options{Depth: 4, Query: "size > lots"}); err == nil {
+		t.Fatalf("expected invalid query error")
+	}
+}
+
+func TestRunQueryLimitsDeletePlanToMatchingPaths(t *testing.T) {
+	root := t.TempDir()
+	cache := filepath.Join(root, "cache")
+	clip := filepath.Join(cache, "clip.mp4")
+	keep := filepath.Join(cache, "keep.txt")
+	mustMkdir(t, cache)
+	mustWrite(t, clip, 4096)
+	mustWrite(t, keep, 4096)
+
+	store := planstore.NewFileStoreAt(filepath.Join(t.TempDir(), "plans"))
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	res, err := NewService().Run(context.Background(), app, root, Options{Depth: 4, Query: "ext = mp4", Action: "delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := store.Load(res.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var selected []string
+	for _, item := range plan.Items {
+		if item.Selected {
+			selected = append(selected, item.Path)
+		}
+	}
+	if len(selected) != 1 || selected[0] != clip {
+		t.Fatalf("expected the delete plan to select only %s, got %v", clip, selected)
+	}
+	if _, err := os.Stat(keep); err != nil {
+		t.Fatalf("expected non-matching file to be untouched: %v", err)
+	}
+}
@@ -4,11 +4,17 @@
 package analyze
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
+
+	"talpa/internal/app/common"
+	"talpa/internal/infra/logging"
+	"talpa/internal/infra/planstore"
 )
 
 func TestMoveToTrashFallsBackOnEXDEV(t *testing.T) {
@@ -19,13 +25,13 @@ func TestMoveToTrashFallsBackOnEXDEV(t *testing.T) {
 	mustWrite(t, filepath.Join(src, "a.tmp"), 16)
 	trash := filepath.Join(root, "trash")
 
-	original := renameAt
-	renameAt = func(oldfd int, oldpath string, newfd int, newpath string) error {
+	original := renameNoReplaceAt
+	renameNoReplaceAt = func(oldfd int, oldpath string, newfd int, newpath string) error {
 		return syscall.EXDEV
 	}
-	t.Cleanup(func() { renameAt = original })
+	t.Cleanup(func() { renameNoReplaceAt = original })
 
-	err := moveToTrash(src, trash, []string{root}, nil, 0, 0)
+	_, err := moveToTrash(src, trash, []string{root}, nil, 0, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,12 +54,163 @@ func TestMoveToTrashPropagatesRenameError(t *testing.T) {
 	mustMkdir(t, src)
 	trash := filepath.Join(root, "trash")
 
-	original := renameAt
-	renameAt = func(oldfd int, oldpath string, newfd int, newpath string) error { return errors.New("boom") }
-	t.Cleanup(func() { renameAt = original })
+	original := renameNoReplaceAt
+	renameNoReplaceAt = func(oldfd int, oldpath string, newfd int, newpath string) error { return errors.New("boom") }
+	t.Cleanup(func() { renameNoReplaceAt = original })
 
-	err := moveToTrash(src, trash, []string{root}, nil, 0, 0)
+	_, err := moveToTrash(src, trash, []string{root}, nil, 0, 0)
 	if err == nil {
 		t.Fatalf("expected rename error")
 	}
 }
+
+func TestMoveToTrashWritesTrashInfo(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	src := filepath.Join(root, "my cache")
+	mustMkdir(t, src)
+	mustWrite(t, filepath.Join(src, "a.tmp"), 16)
+	trash := filepath.Join(root, "trash")
+
+	if _, err := moveToTrash(src, trash, []string{root}, nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(trash, "files", "my cache", "a.tmp")); err != nil {
+		t.Fatalf("expected payload in trash files dir: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(trash, "info", "my cache.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "Path="+strings.ReplaceAll(src, " ", "%20")+"\n") {
+		t.Fatalf("expected url-escaped original path in trashinfo, got %q", raw)
+	}
+	info, err := parseTrashInfo(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Path != src {
+		t.Fatalf("expected parsed path %q, got %q", src, info.Path)
+	}
+	if info.DeletionDate.IsZero() {
+		t.Fatalf("expected deletion date to be recorded")
+	}
+}
+
+func TestMoveToTrashUsesUniqueNameOnCollision(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	trash := filepath.Join(root, "trash")
+	for i := 0; i < 2; i++ {
+		src := filepath.Join(root, "cache")
+		mustMkdir(t, src)
+		if _, err := moveToTrash(src, trash, []string{root}, nil, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"cache", "cache.2"} {
+		if _, err := os.Stat(filepath.Join(trash, "files", name)); err != nil {
+			t.Fatalf("expected trash entry %s: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(trash, "info", name+".trashinfo")); err != nil {
+			t.Fatalf("expected trashinfo for %s: %v", name, err)
+		}
+	}
+}
+
+func TestMoveToTrashRetriesWhenEntryAppearsAfterReservation(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	src := filepath.Join(root, "cache")
+	mustMkdir(t, src)
+	mustWrite(t, filepath.Join(src, "a.tmp"), 16)
+	trash := filepath.Join(root, "trash")
+	racer := filepath.Join(trash, "files", "cache")
+
+	original := renameNoReplaceAt
+	renameNoReplaceAt = func(oldfd int, oldpath string, newfd int, newpath string) error {
+		if newpath == "cache" {
+			mustMkdir(t, racer)
+			mustWrite(t, filepath.Join(racer, "other"), 4)
+		}
+		return original(oldfd, oldpath, newfd, newpath)
+	}
+	t.Cleanup(func() { renameNoReplaceAt = original })
+
+	trashed, err := moveToTrash(src, trash, []string{root}, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trashed.Path != filepath.Join(trash, "files", "cache.2") {
+		t.Fatalf("expected the move to retry under a new name, got %s", trashed.Path)
+	}
+	if _, err := os.Stat(filepath.Join(racer, "other")); err != nil {
+		t.Fatalf("expected the competing entry to be left intact: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(trash, "info"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "cache.2.trashinfo" {
+		t.Fatalf("expected only the retried reservation to remain, got %v", entries)
+	}
+}
+
+func TestMoveToTrashRemovesTrashInfoOnFailure(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	src := filepath.Join(root, "cache")
+	mustMkdir(t, src)
+	trash := filepath.Join(root, "trash")
+
+	original := renameNoReplaceAt
+	renameNoReplaceAt = func(oldfd int, oldpath string, newfd int, newpath string) error { return errors.New("boom") }
+	t.Cleanup(func() { renameNoReplaceAt = original })
+
+	if _, err := moveToTrash(src, trash, []string{root}, nil, 0, 0); err == nil {
+		t.Fatalf("expected rename error")
+	}
+	entries, err := os.ReadDir(filepath.Join(trash, "info"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no orphan trashinfo files, got %d", len(entries))
+	}
+}
+
+func TestApplyTrashPlanMovesPlannedItems(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	project := filepath.Join(root, "project")
+	target := filepath.Join(project, "cache", "a.tmp")
+	mustMkdir(t, filepath.Dir(target))
+	mustWrite(t, target, 16)
+	trash := filepath.Join(root, "trash")
+
+	store := planstore.NewFileStoreAt(filepath.Join(root, "plans"))
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	planned, err := NewService().Run(context.Background(), app, project, Options{Depth: 6, Limit: 50, SortBy: "size", Action: "trash", TrashDir: trash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := store.Load(planned.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.TrashDir != trash || len(plan.Items) != 2 || plan.Items[0].Path != filepath.Dir(target) || !plan.Items[0].Selected || plan.Items[0].Inode == 0 || plan.Items[1].Selected {
+		t.Fatalf("unexpected saved plan: %+v", plan)
+	}
+
+	app.Options = common.GlobalOptions{Yes: true}
+	res, err := NewService().Apply(context.Background(), app, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary.Errors != 0 || res.Items[0].Result != "trashed" {
+		t.Fatalf("unexpected apply result: %+v", res.Items)
+	}
+	if _, err := os.Stat(filepath.Join(trash, "files", "cache", "a.tmp")); err != nil {
+		t.Fatalf("expected trashed payload: %v", err)
+	}
+}
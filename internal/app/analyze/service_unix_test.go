@@ -22,11 +22,11 @@ func TestRunTrashActionEXDEVFallbackRejectsFIFO(t *testing.T) {
 		t.Skipf("mkfifo not available: %v", err)
 	}
 
-	original := renameAt
-	renameAt = func(oldfd int, oldpath string, newfd int, newpath string) error {
+	original := renameNoReplaceAt
+	renameNoReplaceAt = func(oldfd int, oldpath string, newfd int, newpath string) error {
 		return syscall.EXDEV
 	}
-	t.Cleanup(func() { renameAt = original })
+	t.Cleanup(func() { renameNoReplaceAt = original })
 
 	app := &common.AppContext{Options: common.GlobalOptions{DryRun: false, Yes: true}, Logger: logging.NewNoopLogger()}
 	res, err := NewService().Run(context.Background(), app, root, Options{Depth: 6, Limit: 20, SortBy: "size", Action: "trash"})
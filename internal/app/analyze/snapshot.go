@@ -0,0 +1,258 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/filesystem"
+)
+
+const diffAttributionShare = 0.9
+
+type DiffOptions struct {
+	Limit    int
+	SizeMode string
+	Refresh  bool
+}
+
+type PathDelta struct {
+	Path        string `json:"path"`
+	Kind        string `json:"kind"`
+	BeforeBytes int64  `json:"before_bytes"`
+	AfterBytes  int64  `json:"after_bytes"`
+	DeltaBytes  int64  `json:"delta_bytes"`
+}
+
+type FileChange struct {
+	Path         string    `json:"path"`
+	SizeBytes    int64     `json:"size_bytes"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+type DiffMetrics struct {
+	Root        string       `json:"root"`
+	From        string       `json:"from"`
+	To          string       `json:"to"`
+	FromTime    time.Time    `json:"from_time"`
+	ToTime      time.Time    `json:"to_time"`
+	SizeMode    string       `json:"size_mode,omitempty"`
+	BeforeBytes int64        `json:"before_bytes"`
+	AfterBytes  int64        `json:"after_bytes"`
+	DeltaBytes  int64        `json:"delta_bytes"`
+	Grew        []PathDelta  `json:"grew"`
+	Shrank      []PathDelta  `json:"shrank"`
+	Appeared    []FileChange `json:"appeared"`
+	Disappeared []FileChange `json:"disappeared"`
+}
+
+func snapshotFromTree(name string, tree *filesystem.TreeNode, now time.Time) model.Snapshot {
+	snap := model.Snapshot{SchemaVersion: "1.0", Name: name, Root: tree.Path, CreatedAt: now.UTC()}
+	var walk func(n *filesystem.TreeNode)
+	walk = func(n *filesystem.TreeNode) {
+		snap.Entries = append(snap.Entries, model.SnapshotEntry{
+			Path:           n.Path,
+			IsDir:          n.IsDir,
+			SizeBytes:      n.SizeBytes,
+			AllocatedBytes: n.AllocatedBytes,
+			FileCount:      n.FileCount,
+			LastModified:   n.LastModified,
+		})
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(tree)
+	return snap
+}
+
+func saveSnapshot(app *common.AppContext, name, rootAbs string, scanned []filesystem.ScanItem, partial *filesystem.PartialScanError) error {
+	if partial != nil {
+		return fmt.Errorf("scan timed out with %d directories not visited; snapshot %s was not saved", len(partial.Unvisited), name)
+	}
+	if app.Snapshots == nil {
+		return errors.New("snapshot store is unavailable")
+	}
+	return app.Snapshots.Save(snapshotFromTree(name, filesystem.BuildTree(rootAbs, scanned, 0), time.Now()))
+}
+
+func (Service) Diff(ctx context.Context, app *common.AppContext, from, to string, opts DiffOptions) (model.CommandResult, error) {
+	start := time.Now()
+	if app.Snapshots == nil {
+		return model.CommandResult{}, errors.New("snapshot store is unavailable")
+	}
+	before, err := app.Snapshots.Load(from)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	var after model.Snapshot
+	if to == "" {
+		scanned, partial, err := scan(ctx, app, before.Root, "inspect", opts.Refresh)
+		if err != nil {
+			return model.CommandResult{}, err
+		}
+		if partial != nil {
+			return model.CommandResult{}, fmt.Errorf("scan timed out with %d directories not visited; raise scanner.timeout to diff against now", len(partial.Unvisited))
+		}
+		after = snapshotFromTree("now", filesystem.BuildTree(before.Root, scanned, 0), time.Now())
+	} else {
+		after, err = app.Snapshots.Load(to)
+		if err != nil {
+			return model.CommandResult{}, err
+		}
+		if after.Root != before.Root {
+			return model.CommandResult{}, fmt.Errorf("snapshots cover different roots: %s is %s, %s is %s", from, before.Root, to, after.Root)
+		}
+	}
+
+	metrics := diffSnapshots(before, after, opts)
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "analyze",
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		DryRun:        app.Options.DryRun,
+		Summary:       model.Summary{SizeMode: opts.SizeMode},
+		Metrics:       metrics,
+	}, nil
+}
+
+func diffSnapshots(before, after model.Snapshot, opts DiffOptions) DiffMetrics {
+	size := func(e model.SnapshotEntry) int64 {
+		if opts.SizeMode == filesystem.SizeModeDisk {
+			return e.AllocatedBytes
+		}
+		return e.SizeBytes
+	}
+	old := make(map[string]model.SnapshotEntry, len(before.Entries))
+	for _, e := range before.Entries {
+		old[e.Path] = e
+	}
+	cur := make(map[string]model.SnapshotEntry, len(after.Entries))
+	for _, e := range after.Entries {
+		cur[e.Path] = e
+	}
+
+	m := DiffMetrics{
+		Root:        before.Root,
+		From:        before.Name,
+		To:          after.Name,
+		FromTime:    before.CreatedAt,
+		ToTime:      after.CreatedAt,
+		SizeMode:    opts.SizeMode,
+		Grew:        []PathDelta{},
+		Shrank:      []PathDelta{},
+		Appeared:    []FileChange{},
+		Disappeared: []FileChange{},
+	}
+	m.BeforeBytes = size(old[before.Root])
+	m.AfterBytes = size(cur[after.Root])
+	m.DeltaBytes = m.AfterBytes - m.BeforeBytes
+
+	deltas := map[string]PathDelta{}
+	children := map[string][]string{}
+	record := func(path string, isDir bool) {
+		if _, done := deltas[path]; done {
+			return
+		}
+		d := PathDelta{Path: path, Kind: "file", BeforeBytes: size(old[path]), AfterBytes: size(cur[path])}
+		if isDir {
+			d.Kind = "dir"
+		}
+		d.DeltaBytes = d.AfterBytes - d.BeforeBytes
+		deltas[path] = d
+		if path != m.Root {
+			parent := filepath.Dir(path)
+			children[parent] = append(children[parent], path)
+		}
+	}
+	for _, e := range before.Entries {
+		_, still := cur[e.Path]
+		record(e.Path, e.IsDir || cur[e.Path].IsDir)
+		if !still && !e.IsDir {
+			m.Disappeared = append(m.Disappeared, FileChange{Path: e.Path, SizeBytes: size(e), LastModified: e.LastModified})
+		}
+	}
+	for _, e := range after.Entries {
+		_, existed := old[e.Path]
+		record(e.Path, e.IsDir || old[e.Path].IsDir)
+		if !existed && !e.IsDir {
+			m.Appeared = append(m.Appeared, FileChange{Path: e.Path, SizeBytes: size(e), LastModified: e.LastModified})
+		}
+	}
+
+	explained := func(d PathDelta) bool {
+		for _, c := range children[d.Path] {
+			cd := deltas[c].DeltaBytes
+			if (cd > 0) == (d.DeltaBytes > 0) && float64(abs64(cd)) >= diffAttributionShare*float64(abs64(d.DeltaBytes)) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, d := range deltas {
+		if d.DeltaBytes == 0 || d.Path == m.Root {
+			continue
+		}
+		_, existed := old[d.Path]
+		_, still := cur[d.Path]
+		if d.Kind == "file" && (!existed || !still) {
+			continue
+		}
+		if d.Kind == "dir" && explained(d) {
+			continue
+		}
+		if d.DeltaBytes > 0 {
+			m.Grew = append(m.Grew, d)
+		} else {
+			m.Shrank = append(m.Shrank, d)
+		}
+	}
+
+	sort.Slice(m.Grew, func(i, j int) bool {
+		if m.Grew[i].DeltaBytes != m.Grew[j].DeltaBytes {
+			return m.Grew[i].DeltaBytes > m.Grew[j].DeltaBytes
+		}
+		return m.Grew[i].Path < m.Grew[j].Path
+	})
+	sort.Slice(m.Shrank, func(i, j int) bool {
+		if m.Shrank[i].DeltaBytes != m.Shrank[j].DeltaBytes {
+			return m.Shrank[i].DeltaBytes < m.Shrank[j].DeltaBytes
+		}
+		return m.Shrank[i].Path < m.Shrank[j].Path
+	})
+	for _, list := range [][]FileChange{m.Appeared, m.Disappeared} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].SizeBytes != list[j].SizeBytes {
+				return list[i].SizeBytes > list[j].SizeBytes
+			}
+			return list[i].Path < list[j].Path
+		})
+	}
+	if opts.Limit > 0 {
+		m.Grew = truncate(m.Grew, opts.Limit)
+		m.Shrank = truncate(m.Shrank, opts.Limit)
+		m.Appeared = truncate(m.Appeared, opts.Limit)
+		m.Disappeared = truncate(m.Disappeared, opts.Limit)
+	}
+	return m
+}
+
+func truncate[T any](list []T, n int) []T {
+	if len(list) > n {
+		return list[:n]
+	}
+	return list
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package analyze
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/logging"
+	"talpa/internal/infra/snapshotstore"
+)
+
+func TestSaveSnapshotThenDiffAgainstNow(t *testing.T) {
+	root := t.TempDir()
+	mustMkdir(t, filepath.Join(root, "logs"))
+	mustMkdir(t, filepath.Join(root, "cache"))
+	mustWrite(t, filepath.Join(root, "logs", "app.log"), 100)
+	mustWrite(t, filepath.Join(root, "logs", "old.log"), 10)
+	mustWrite(t, filepath.Join(root, "cache", "gone.bin"), 500)
+
+	app := &common.AppContext{
+		Options:   common.GlobalOptions{DryRun: true},
+		Logger:    logging.NewNoopLogger(),
+		Snapshots: snapshotstore.NewFileStoreAt(t.TempDir()),
+	}
+	if _, err := NewService().Run(context.Background(), app, root, Options{Depth: 1, SaveSnapshot: "base"}); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := app.Snapshots.Load("base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Entries) != 6 {
+		t.Fatalf("expected the full tree regardless of --depth, got %d entries", len(snap.Entries))
+	}
+
+	mustWrite(t, filepath.Join(root, "logs", "app.log"), 5000)
+	mustWrite(t, filepath.Join(root, "logs", "old.log"), 5)
+	if err := os.Remove(filepath.Join(root, "cache", "gone.bin")); err != nil {
+		t.Fatal(err)
+	}
+	mustMkdir(t, filepath.Join(root, "volumes", "v1"))
+	mustWrite(t, filepath.Join(root, "volumes", "v1", "a"), 2000)
+	mustWrite(t, filepath.Join(root, "volumes", "v1", "b"), 2000)
+
+	res, err := NewService().Diff(context.Background(), app, "base", "", DiffOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := res.Metrics.(DiffMetrics)
+	if !ok {
+		t.Fatalf("expected diff metrics, got %T", res.Metrics)
+	}
+	if m.From != "base" || m.To != "now" || m.BeforeBytes != 610 || m.AfterBytes != 9005 || m.DeltaBytes != 8395 {
+		t.Fatalf("unexpected totals: %+v", m)
+	}
+	var grew []string
+	for _, d := range m.Grew {
+		grew = append(grew, d.Path)
+	}
+	want := []string{filepath.Join(root, "logs", "app.log"), filepath.Join(root, "volumes", "v1")}
+	if len(grew) != 2 || grew[0] != want[0] || grew[1] != want[1] {
+		t.Fatalf("expected growth attributed to %v, got %v", want, grew)
+	}
+	if len(m.Shrank) != 1 || m.Shrank[0].Path != filepath.Join(root, "logs", "old.log") || m.Shrank[0].DeltaBytes != -5 {
+		t.Fatalf("unexpected shrank: %+v", m.Shrank)
+	}
+	if len(m.Appeared) != 2 || m.Appeared[0].Path != filepath.Join(root, "volumes", "v1", "a") {
+		t.Fatalf("unexpected appeared: %+v", m.Appeared)
+	}
+	if len(m.Disappeared) != 1 || m.Disappeared[0].Path != filepath.Join(root, "cache", "gone.bin") {
+		t.Fatalf("unexpected disappeared: %+v", m.Disappeared)
+	}
+}
+
+func TestDiffRejectsSnapshotsOfDifferentRoots(t *testing.T) {
+	store := snapshotstore.NewFileStoreAt(t.TempDir())
+	for name, root := range map[string]string{"a": "/srv/a", "b": "/srv/b"} {
+		if err := store.Save(model.Snapshot{Name: name, Root: root, Entries: []model.SnapshotEntry{{Path: root, IsDir: true}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := &common.AppContext{Logger: logging.NewNoopLogger(), Snapshots: store}
+	if _, err := NewService().Diff(context.Background(), app, "a", "b", DiffOptions{}); err == nil {
+		t.Fatalf("expected different roots error")
+	}
+	if _, err := NewService().Run(context.Background(), app, t.TempDir(), Options{Depth: 1, SaveSnapshot: "../x"}); err == nil {
+		t.Fatalf("expected invalid snapshot name error")
+	}
+}
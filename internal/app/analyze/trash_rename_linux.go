@@ -0,0 +1,18 @@
+//go:build linux
+// +build linux
+
+package analyze
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+func renameNoReplace(oldFD int, oldName string, newFD int, newName string) error {
+	err := unix.Renameat2(oldFD, oldName, newFD, newName, unix.RENAME_NOREPLACE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) {
+		return renameExclusiveAt(oldFD, oldName, newFD, newName)
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build unix && !linux
+// +build unix,!linux
+
+package analyze
+
+func renameNoReplace(oldFD int, oldName string, newFD int, newName string) error {
+	return renameExclusiveAt(oldFD, oldName, newFD, newName)
+}
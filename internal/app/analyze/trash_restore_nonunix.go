@@ -0,0 +1,50 @@
+//go:build !unix
+// +build !unix
+
+package analyze
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func secureRestoreFromTrash(trashedPath, originalPath, infoPath string, expectedDev uint64, expectedIno uint64) error {
+	_ = expectedDev
+	_ = expectedIno
+	info, err := os.Lstat(trashedPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(originalPath); err == nil {
+		return ErrRestoreConflict
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(originalPath), 0o755); err != nil {
+		return err
+	}
+	if err := renameNoReplace(trashedPath, originalPath, info.IsDir()); err != nil {
+		if os.IsExist(err) {
+			return ErrRestoreConflict
+		}
+		return err
+	}
+	if err := os.Remove(infoPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func renameNoReplace(oldPath, newPath string, isDir bool) error {
+	if isDir {
+		return os.Rename(oldPath, newPath)
+	}
+	if err := os.Link(oldPath, newPath); err != nil {
+		return err
+	}
+	if err := os.Remove(oldPath); err != nil {
+		_ = os.Remove(newPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+//go:build unix
+// +build unix
+
+package analyze
+
+import (
+	"errors"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+var renameNoReplaceAt = renameNoReplace
+
+func secureRestoreFromTrash(trashedPath, originalPath, infoPath string, expectedDev uint64, expectedIno uint64) error {
+	filesFD, err := openDirNoFollow(filepath.Dir(trashedPath))
+	if err != nil {
+		return err
+	}
+	defer unix.Close(filesFD)
+	name := filepath.Base(trashedPath)
+	st, err := lstatAt(filesFD, name)
+	if err != nil {
+		return err
+	}
+	id := toIdentity(st)
+	if (expectedDev != 0 || expectedIno != 0) && (id.dev != expectedDev || id.ino != expectedIno) {
+		return ErrTrashEntryChanged
+	}
+
+	dstParent := filepath.Dir(originalPath)
+	dstName := filepath.Base(originalPath)
+	if err := ensureDirPathNoFollow(dstParent, 0o755); err != nil {
+		return err
+	}
+	dstParentFD, err := openDirNoFollow(dstParent)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(dstParentFD)
+
+	if _, err := lstatAt(dstParentFD, dstName); err == nil {
+		return ErrRestoreConflict
+	} else if !errors.Is(err, unix.ENOENT) {
+		return err
+	}
+	if err := ensureIdentityAt(filesFD, name, id); err != nil {
+		return err
+	}
+	if err := renameNoReplaceAt(filesFD, name, dstParentFD, dstName); err != nil {
+		if errors.Is(err, unix.EEXIST) || errors.Is(err, unix.ENOTEMPTY) {
+			return ErrRestoreConflict
+		}
+		if !errors.Is(err, unix.EXDEV) {
+			return err
+		}
+		mountPoints, err := loadMountPoints()
+		if err != nil {
+			return err
+		}
+		if err := copyEntryAt(filesFD, name, dstParentFD, dstName, id.dev, trashedPath, trashedPath, mountPoints); err != nil {
+			if errors.Is(err, unix.EEXIST) {
+				return ErrRestoreConflict
+			}
+			_ = removeEntryAt(dstParentFD, dstName)
+			return err
+		}
+		if err := ensureIdentityAt(filesFD, name, id); err != nil {
+			_ = removeEntryAt(dstParentFD, dstName)
+			return err
+		}
+		if err := removeEntryAt(filesFD, name); err != nil {
+			_ = removeEntryAt(dstParentFD, dstName)
+			return err
+		}
+	}
+
+	infoFD, err := openDirNoFollow(filepath.Dir(infoPath))
+	if err != nil {
+		return nil
+	}
+	defer unix.Close(infoFD)
+	if err := unix.Unlinkat(infoFD, filepath.Base(infoPath), 0); err != nil && !errors.Is(err, unix.ENOENT) {
+		return err
+	}
+	return nil
+}
+
+func renameExclusiveAt(oldFD int, oldName string, newFD int, newName string) error {
+	st, err := lstatAt(oldFD, oldName)
+	if err != nil {
+		return err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		if err := unix.Linkat(oldFD, oldName, newFD, newName, 0); err != nil {
+			return err
+		}
+		if err := unix.Unlinkat(oldFD, oldName, 0); err != nil {
+			_ = unix.Unlinkat(newFD, newName, 0)
+			return err
+		}
+		return nil
+	}
+	if err := unix.Mkdirat(newFD, newName, 0o700); err != nil {
+		return err
+	}
+	if err := renameAt(oldFD, oldName, newFD, newName); err != nil {
+		_ = unix.Unlinkat(newFD, newName, unix.AT_REMOVEDIR)
+		return err
+	}
+	return nil
+}
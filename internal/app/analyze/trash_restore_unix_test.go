@@ -0,0 +1,112 @@
+//go:build unix
+// +build unix
+
+package analyze
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestRestoreFromTrashNeverReplacesPathCreatedDuringRestore(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	src := filepath.Join(root, "app.log")
+	mustWrite(t, src, 16)
+	trashed, err := moveToTrash(src, filepath.Join(root, "trash"), []string{root}, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := renameNoReplaceAt
+	renameNoReplaceAt = func(oldFD int, oldName string, newFD int, newName string) error {
+		if err := os.WriteFile(src, []byte("written meanwhile"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return original(oldFD, oldName, newFD, newName)
+	}
+	t.Cleanup(func() { renameNoReplaceAt = original })
+
+	if err := RestoreFromTrash(trashed.Path, src, nil, trashed.Device, trashed.Inode); !errors.Is(err, ErrRestoreConflict) {
+		t.Fatalf("expected a restore conflict, got %v", err)
+	}
+	if data, err := os.ReadFile(src); err != nil || string(data) != "written meanwhile" {
+		t.Fatalf("expected the new file to survive, got %q (%v)", data, err)
+	}
+	if _, err := os.Lstat(trashed.Path); err != nil {
+		t.Fatalf("expected the trashed entry to stay in the trash: %v", err)
+	}
+}
+
+func TestRestoreFromTrashRefusesEntrySwappedAfterTrashing(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	src := filepath.Join(root, "app.log")
+	mustWrite(t, src, 16)
+	trashed, err := moveToTrash(src, filepath.Join(root, "trash"), []string{root}, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st unix.Stat_t
+	if err := unix.Lstat(trashed.Path, &st); err != nil {
+		t.Fatal(err)
+	}
+	if trashed.Device != uint64(st.Dev) || trashed.Inode != uint64(st.Ino) {
+		t.Fatalf("expected the trashed entry's identity, got %+v", trashed)
+	}
+
+	mustWrite(t, filepath.Join(root, "other"), 8)
+	if err := os.Rename(filepath.Join(root, "other"), trashed.Path); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreFromTrash(trashed.Path, src, nil, trashed.Device, trashed.Inode); !errors.Is(err, ErrTrashEntryChanged) {
+		t.Fatalf("expected a swapped trash entry to be refused, got %v", err)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be restored, got %v", err)
+	}
+}
+
+func TestRenameExclusiveAtRefusesExistingDestination(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "file"), 4)
+	mustMkdir(t, filepath.Join(dir, "tree", "sub"))
+	mustWrite(t, filepath.Join(dir, "taken"), 8)
+	mustMkdir(t, filepath.Join(dir, "taken-dir", "keep"))
+	fd, err := openDirNoFollow(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fd)
+
+	if err := renameExclusiveAt(fd, "file", fd, "taken"); !errors.Is(err, unix.EEXIST) {
+		t.Fatalf("expected EEXIST for an existing file, got %v", err)
+	}
+	if err := renameExclusiveAt(fd, "tree", fd, "taken-dir"); !errors.Is(err, unix.EEXIST) {
+		t.Fatalf("expected EEXIST for an existing directory, got %v", err)
+	}
+	for _, p := range []string{"file", "tree/sub", "taken", "taken-dir/keep"} {
+		if _, err := os.Lstat(filepath.Join(dir, p)); err != nil {
+			t.Fatalf("expected %s to be untouched: %v", p, err)
+		}
+	}
+
+	if err := renameExclusiveAt(fd, "file", fd, "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := renameExclusiveAt(fd, "tree", fd, "moved-dir"); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"file", "tree"} {
+		if _, err := os.Lstat(filepath.Join(dir, p)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be moved away, got %v", p, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "moved-dir", "sub")); err != nil {
+		t.Fatalf("expected the directory contents to move: %v", err)
+	}
+}
@@ -7,35 +7,58 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 	"time"
+
+	"talpa/internal/domain/model"
 )
 
 var renameAt = func(_ int, _ string, _ int, _ string) error { return nil }
 
-func secureMoveToTrash(srcPath, trashAbs string, allowedRoots []string, whitelist []string, expectedDev uint64, expectedIno uint64) error {
+func secureMoveToTrash(srcPath, trashAbs string, allowedRoots []string, whitelist []string, expectedDev uint64, expectedIno uint64) (model.TrashedEntry, error) {
 	_ = allowedRoots
 	_ = whitelist
 	_ = expectedDev
 	_ = expectedIno
 	base := filepath.Base(srcPath)
 	if base == "." || base == string(filepath.Separator) {
-		return fmt.Errorf("invalid trash source path: %s", srcPath)
+		return model.TrashedEntry{}, fmt.Errorf("invalid trash source path: %s", srcPath)
 	}
-	for i := 0; i < 32; i++ {
-		suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
-		if i > 0 {
-			suffix = suffix + "-" + strconv.Itoa(i)
+	filesDir := filepath.Join(trashAbs, trashFilesDir)
+	infoDir := filepath.Join(trashAbs, trashInfoDir)
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return model.TrashedEntry{}, err
 		}
-		dst := filepath.Join(trashAbs, base+"-"+suffix)
-		err := os.Rename(srcPath, dst)
-		if err == nil {
-			return nil
+	}
+	info := formatTrashInfo(trashInfoPath(srcPath, ""), time.Now())
+	for i := 1; i <= maxTrashNameTries; i++ {
+		name := trashEntryName(base, i)
+		dst := filepath.Join(filesDir, name)
+		if _, err := os.Lstat(dst); err == nil {
+			continue
 		}
+		infoPath := filepath.Join(infoDir, name+trashInfoSuffix)
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
 		if os.IsExist(err) {
 			continue
 		}
-		return err
+		if err != nil {
+			return model.TrashedEntry{}, err
+		}
+		_, errWrite := f.Write(info)
+		errClose := f.Close()
+		if errWrite == nil {
+			errWrite = errClose
+		}
+		if errWrite != nil {
+			_ = os.Remove(infoPath)
+			return model.TrashedEntry{}, errWrite
+		}
+		if err := os.Rename(srcPath, dst); err != nil {
+			_ = os.Remove(infoPath)
+			return model.TrashedEntry{}, err
+		}
+		return model.TrashedEntry{Path: dst}, nil
 	}
-	return fmt.Errorf("failed to reserve unique trash destination for %s", srcPath)
+	return model.TrashedEntry{}, fmt.Errorf("failed to reserve unique trash destination for %s", srcPath)
 }
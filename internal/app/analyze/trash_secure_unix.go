@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"golang.org/x/sys/unix"
+
+	"talpa/internal/domain/model"
 )
 
 var renameAt = unix.Renameat
@@ -24,111 +26,239 @@ type entryIdentity struct {
 	ino uint64
 }
 
-func secureMoveToTrash(srcPath, trashAbs string, allowedRoots []string, whitelist []string, expectedDev uint64, expectedIno uint64) error {
+type trashTarget struct {
+	root   string
+	topdir string
+}
+
+func secureMoveToTrash(srcPath, trashAbs string, allowedRoots []string, whitelist []string, expectedDev uint64, expectedIno uint64) (model.TrashedEntry, error) {
 	if !isAllowedTrashSource(srcPath, allowedRoots, whitelist) {
-		return errors.New("PATH_BLOCKED: outside allowed roots")
+		return model.TrashedEntry{}, errors.New("PATH_BLOCKED: outside allowed roots")
 	}
 	if strings.TrimSpace(srcPath) == "" {
-		return errors.New("PATH_INVALID: empty source path")
+		return model.TrashedEntry{}, errors.New("PATH_INVALID: empty source path")
 	}
 	srcParent := filepath.Dir(srcPath)
 	srcName := filepath.Base(srcPath)
 
 	srcParentFD, err := openDirNoFollow(srcParent)
 	if err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	defer unix.Close(srcParentFD)
 
-	trashFD, err := openDirNoFollow(trashAbs)
+	target := selectTrashTarget(srcParentFD, srcPath, trashAbs)
+	trashFD, err := openDirNoFollow(target.root)
 	if err != nil {
-		return err
+		return model.TrashedEntry{}, err
 	}
 	defer unix.Close(trashFD)
-	containerName, err := reserveTrashContainer(trashFD, srcName)
+	filesFD, infoFD, err := openTrashSubdirs(trashFD)
 	if err != nil {
-		return err
-	}
-	containerFD, err := openDirAtNoFollow(trashFD, containerName)
-	if err != nil {
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
-		return err
+		return model.TrashedEntry{}, err
 	}
+	defer unix.Close(filesFD)
+	defer unix.Close(infoFD)
 
-	const payloadName = "item"
+	info := formatTrashInfo(trashInfoPath(srcPath, target.topdir), time.Now())
+	for attempt := 0; attempt < maxTrashNameTries; attempt++ {
+		name, err := reserveTrashEntry(infoFD, filesFD, srcName, info)
+		if err != nil {
+			return model.TrashedEntry{}, err
+		}
+		err = moveTrashEntry(srcParentFD, srcName, srcPath, filesFD, name, expectedDev, expectedIno)
+		if err == nil {
+			trashed := model.TrashedEntry{Path: filepath.Join(target.root, trashFilesDir, name)}
+			if st, err := lstatAt(filesFD, name); err == nil {
+				trashed.Device, trashed.Inode = uint64(st.Dev), uint64(st.Ino)
+			}
+			return trashed, nil
+		}
+		_ = unix.Unlinkat(infoFD, name+trashInfoSuffix, 0)
+		if !errors.Is(err, unix.EEXIST) && !errors.Is(err, unix.ENOTEMPTY) {
+			return model.TrashedEntry{}, err
+		}
+	}
+	return model.TrashedEntry{}, errors.New("failed to reserve unique trash entry")
+}
 
-	if err := ensureIdentityAt(srcParentFD, srcName, resolveExpectedIdentity(srcPath, expectedDev, expectedIno)); err != nil {
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
+func moveTrashEntry(srcParentFD int, srcName, srcPath string, filesFD int, name string, expectedDev, expectedIno uint64) error {
+	want := resolveExpectedIdentity(srcPath, expectedDev, expectedIno)
+	if err := ensureIdentityAt(srcParentFD, srcName, want); err != nil {
 		return err
 	}
-	if err := renameAt(srcParentFD, srcName, containerFD, payloadName); err == nil {
-		_ = unix.Close(containerFD)
-		return nil
-	} else if !errors.Is(err, unix.EXDEV) {
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
+	if err := renameNoReplaceAt(srcParentFD, srcName, filesFD, name); !errors.Is(err, unix.EXDEV) {
 		return err
 	}
 
 	srcSt, err := lstatAt(srcParentFD, srcName)
 	if err != nil {
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
 		return err
 	}
-	want := resolveExpectedIdentity(srcPath, expectedDev, expectedIno)
 	if err := ensureIdentityAt(srcParentFD, srcName, want); err != nil {
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
 		return err
 	}
 	srcID := toIdentity(srcSt)
 	mountPoints, err := loadMountPoints()
 	if err != nil {
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
 		return err
 	}
 	rootPath := filepath.Clean(srcPath)
 
-	if err := copyEntryAt(srcParentFD, srcName, containerFD, payloadName, uint64(srcSt.Dev), rootPath, rootPath, mountPoints); err != nil {
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
+	if err := copyEntryAt(srcParentFD, srcName, filesFD, name, uint64(srcSt.Dev), rootPath, rootPath, mountPoints); err != nil {
+		if !errors.Is(err, unix.EEXIST) {
+			_ = removeEntryAt(filesFD, name)
+		}
 		return err
 	}
 	if err := ensureIdentityAt(srcParentFD, srcName, srcID); err != nil {
-		_ = removeEntryAt(containerFD, payloadName)
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
+		_ = removeEntryAt(filesFD, name)
 		return err
 	}
 	if err := removeEntryAt(srcParentFD, srcName); err != nil {
-		_ = removeEntryAt(containerFD, payloadName)
-		_ = unix.Close(containerFD)
-		_ = unix.Unlinkat(trashFD, containerName, unix.AT_REMOVEDIR)
+		_ = removeEntryAt(filesFD, name)
 		return err
 	}
-	_ = unix.Close(containerFD)
 	return nil
 }
 
-func reserveTrashContainer(trashFD int, srcBase string) (string, error) {
-	base := srcBase + "-" + strconv.FormatInt(time.Now().UnixNano(), 10)
-	for i := 0; i < 32; i++ {
-		name := base
-		if i > 0 {
-			name = base + "-" + strconv.Itoa(i)
+func selectTrashTarget(srcParentFD int, srcPath, homeTrash string) trashTarget {
+	home := trashTarget{root: homeTrash}
+	var parentSt unix.Stat_t
+	if err := unix.Fstat(srcParentFD, &parentSt); err != nil {
+		return home
+	}
+	var trashSt unix.Stat_t
+	if err := unix.Lstat(homeTrash, &trashSt); err == nil && trashSt.Dev == parentSt.Dev {
+		return home
+	}
+	topdir := mountTopDir(srcPath)
+	if topdir == "" || topdir == string(filepath.Separator) {
+		return home
+	}
+	root, err := ensureTopdirTrash(topdir, uint64(parentSt.Dev))
+	if err != nil {
+		return home
+	}
+	return trashTarget{root: root, topdir: topdir}
+}
+
+func mountTopDir(path string) string {
+	mountPoints, err := loadMountPoints()
+	if err != nil {
+		return ""
+	}
+	for p := filepath.Dir(filepath.Clean(path)); ; p = filepath.Dir(p) {
+		if _, ok := mountPoints[p]; ok {
+			return p
+		}
+		if p == string(filepath.Separator) || p == "." {
+			return ""
 		}
-		if err := unix.Mkdirat(trashFD, name, 0o700); err == nil {
-			return name, nil
-		} else if errors.Is(err, unix.EEXIST) {
+	}
+}
+
+func ensureTopdirTrash(topdir string, dev uint64) (string, error) {
+	uid := strconv.Itoa(os.Getuid())
+	topFD, err := openDirNoFollow(topdir)
+	if err != nil {
+		return "", err
+	}
+	defer unix.Close(topFD)
+	var topSt unix.Stat_t
+	if err := unix.Fstat(topFD, &topSt); err != nil {
+		return "", err
+	}
+	if uint64(topSt.Dev) != dev {
+		return "", errors.New("PATH_BLOCKED: trash topdir on different filesystem")
+	}
+
+	if shared, err := lstatAt(topFD, ".Trash"); err == nil && shared.Mode&unix.S_IFMT == unix.S_IFDIR && shared.Mode&unix.S_ISVTX != 0 {
+		sharedFD, err := openDirAtNoFollow(topFD, ".Trash")
+		if err == nil {
+			err = ensureOwnedDirAt(sharedFD, uid)
+			_ = unix.Close(sharedFD)
+			if err == nil {
+				return filepath.Join(topdir, ".Trash", uid), nil
+			}
+		}
+	}
+
+	name := ".Trash-" + uid
+	if err := ensureOwnedDirAt(topFD, name); err != nil {
+		return "", err
+	}
+	return filepath.Join(topdir, name), nil
+}
+
+func ensureOwnedDirAt(parentFD int, name string) error {
+	if err := unix.Mkdirat(parentFD, name, 0o700); err != nil && !errors.Is(err, unix.EEXIST) {
+		return err
+	}
+	st, err := lstatAt(parentFD, name)
+	if err != nil {
+		return err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		return errors.New("PATH_BLOCKED: trash directory is not a directory")
+	}
+	if int(st.Uid) != os.Getuid() {
+		return errors.New("PATH_BLOCKED: trash directory owned by another user")
+	}
+	return nil
+}
+
+func openTrashSubdirs(trashFD int) (int, int, error) {
+	for _, name := range []string{trashFilesDir, trashInfoDir} {
+		if err := unix.Mkdirat(trashFD, name, 0o700); err != nil && !errors.Is(err, unix.EEXIST) {
+			return -1, -1, err
+		}
+	}
+	filesFD, err := openDirAtNoFollow(trashFD, trashFilesDir)
+	if err != nil {
+		return -1, -1, err
+	}
+	infoFD, err := openDirAtNoFollow(trashFD, trashInfoDir)
+	if err != nil {
+		_ = unix.Close(filesFD)
+		return -1, -1, err
+	}
+	return filesFD, infoFD, nil
+}
+
+func reserveTrashEntry(infoFD, filesFD int, srcBase string, info []byte) (string, error) {
+	for i := 1; i <= maxTrashNameTries; i++ {
+		name := trashEntryName(srcBase, i)
+		if _, err := lstatAt(filesFD, name); err == nil {
 			continue
-		} else {
+		} else if !errors.Is(err, unix.ENOENT) {
 			return "", err
 		}
+		fd, err := unix.Openat(infoFD, name+trashInfoSuffix, unix.O_WRONLY|unix.O_CREAT|unix.O_EXCL|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0o600)
+		if errors.Is(err, unix.EEXIST) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		f := os.NewFile(uintptr(fd), name+trashInfoSuffix)
+		if f == nil {
+			_ = unix.Close(fd)
+			_ = unix.Unlinkat(infoFD, name+trashInfoSuffix, 0)
+			return "", errors.New("failed to bind file descriptor")
+		}
+		_, errWrite := f.Write(info)
+		errClose := f.Close()
+		if errWrite != nil || errClose != nil {
+			_ = unix.Unlinkat(infoFD, name+trashInfoSuffix, 0)
+			if errWrite != nil {
+				return "", errWrite
+			}
+			return "", errClose
+		}
+		return name, nil
 	}
-	return "", errors.New("failed to reserve unique trash container")
+	return "", errors.New("failed to reserve unique trash entry")
 }
 
 func openDirNoFollow(path string) (int, error) {
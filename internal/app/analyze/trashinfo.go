@@ -0,0 +1,95 @@
+package analyze
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	trashFilesDir     = "files"
+	trashInfoDir      = "info"
+	trashInfoSuffix   = ".trashinfo"
+	trashInfoHeader   = "[Trash Info]"
+	trashDateLayout   = "2006-01-02T15:04:05"
+	maxTrashNameTries = 64
+)
+
+type trashInfo struct {
+	Path         string
+	DeletionDate time.Time
+}
+
+func trashEntryName(base string, attempt int) string {
+	if attempt <= 1 {
+		return base
+	}
+	return base + "." + strconv.Itoa(attempt)
+}
+
+func trashInfoPath(srcPath, topdir string) string {
+	if topdir == "" {
+		return filepath.Clean(srcPath)
+	}
+	rel, err := filepath.Rel(topdir, srcPath)
+	if err != nil {
+		return filepath.Clean(srcPath)
+	}
+	return rel
+}
+
+func formatTrashInfo(path string, deleted time.Time) []byte {
+	var b bytes.Buffer
+	b.WriteString(trashInfoHeader + "\n")
+	b.WriteString("Path=" + (&url.URL{Path: path}).EscapedPath() + "\n")
+	b.WriteString("DeletionDate=" + deleted.Local().Format(trashDateLayout) + "\n")
+	return b.Bytes()
+}
+
+func parseTrashInfo(raw []byte) (trashInfo, error) {
+	var info trashInfo
+	inGroup := false
+	s := bufio.NewScanner(bytes.NewReader(raw))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inGroup = line == trashInfoHeader
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Path":
+			p, err := url.PathUnescape(strings.TrimSpace(value))
+			if err != nil {
+				return trashInfo{}, err
+			}
+			info.Path = p
+		case "DeletionDate":
+			t, err := time.ParseInLocation(trashDateLayout, strings.TrimSpace(value), time.Local)
+			if err == nil {
+				info.DeletionDate = t
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return trashInfo{}, err
+	}
+	if info.Path == "" {
+		return trashInfo{}, errors.New("trashinfo: missing Path")
+	}
+	return info, nil
+}
@@ -0,0 +1,116 @@
+package analyze
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"talpa/internal/domain/query"
+	"talpa/internal/infra/filesystem"
+)
+
+type TreeMetrics struct {
+	Root           string    `json:"root"`
+	SizeBytes      int64     `json:"size_bytes"`
+	SizeMode       string    `json:"size_mode,omitempty"`
+	ApparentBytes  int64     `json:"apparent_bytes"`
+	AllocatedBytes int64     `json:"allocated_bytes"`
+	FileCount      int64     `json:"file_count"`
+	LastModified   time.Time `json:"last_modified"`
+}
+
+type treeEntry struct {
+	node      *filesystem.TreeNode
+	depth     int
+	candidate bool
+}
+
+func flattenTree(root *filesystem.TreeNode, opts Options, filter *query.Query) []treeEntry {
+	filtered := filter != nil || opts.OnlyCandidates
+	base := filepath.Dir(root.Path)
+	isCandidate := func(n *filesystem.TreeNode) bool {
+		return isCleanupCandidate(scopedPath(base, n.Path))
+	}
+	self := func(n *filesystem.TreeNode) bool {
+		return filter.Match(queryEntry(n, opts.SizeMode)) && (!opts.OnlyCandidates || isCandidate(n))
+	}
+	matches := map[*filesystem.TreeNode]bool{}
+	var match func(n *filesystem.TreeNode) bool
+	match = func(n *filesystem.TreeNode) bool {
+		if v, ok := matches[n]; ok {
+			return v
+		}
+		v := self(n)
+		for _, c := range n.Children {
+			if match(c) {
+				v = true
+			}
+		}
+		matches[n] = v
+		return v
+	}
+
+	var out []treeEntry
+	var walk func(n *filesystem.TreeNode, depth int, underCandidate bool)
+	walk = func(n *filesystem.TreeNode, depth int, underCandidate bool) {
+		children := make([]*filesystem.TreeNode, 0, len(n.Children))
+		for _, c := range n.Children {
+			if c.Bytes(opts.SizeMode) < opts.MinSizeBytes {
+				continue
+			}
+			if filtered && !match(c) {
+				continue
+			}
+			children = append(children, c)
+		}
+		sortNodes(children, opts.SortBy, opts.SizeMode)
+		if opts.Limit > 0 && len(children) > opts.Limit {
+			children = children[:opts.Limit]
+		}
+		for _, c := range children {
+			candidate := !underCandidate && isCandidate(c) && (!filtered || self(c))
+			out = append(out, treeEntry{node: c, depth: depth, candidate: candidate})
+			if opts.Depth <= 0 || depth < opts.Depth {
+				walk(c, depth+1, underCandidate || candidate)
+			}
+		}
+	}
+	walk(root, 1, false)
+	return out
+}
+
+func queryEntry(n *filesystem.TreeNode, sizeMode string) query.Entry {
+	return query.Entry{
+		Path:      n.Path,
+		IsDir:     n.IsDir,
+		SizeBytes: n.Bytes(sizeMode),
+		ModTime:   n.LastModified,
+		UID:       n.UID,
+	}
+}
+
+func sortNodes(nodes []*filesystem.TreeNode, sortBy, sizeMode string) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		switch sortBy {
+		case "path":
+		case "mtime":
+			if !a.LastModified.Equal(b.LastModified) {
+				return a.LastModified.After(b.LastModified)
+			}
+		default:
+			if a.Bytes(sizeMode) != b.Bytes(sizeMode) {
+				return a.Bytes(sizeMode) > b.Bytes(sizeMode)
+			}
+		}
+		return a.Path < b.Path
+	})
+}
+
+func scopedPath(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return string(filepath.Separator) + rel
+}
@@ -0,0 +1,119 @@
+package analyze
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"talpa/internal/app/common"
+	"talpa/internal/infra/filesystem"
+	"talpa/internal/infra/logging"
+)
+
+func TestRunAggregatesDirectoriesPerLevel(t *testing.T) {
+	root := t.TempDir()
+	mods := filepath.Join(root, "app", "node_modules")
+	for _, pkg := range []string{"a", "b", "c"} {
+		mustMkdir(t, filepath.Join(mods, pkg))
+		for _, f := range []string{"index.js", "util.js"} {
+			mustWrite(t, filepath.Join(mods, pkg, f), 100)
+		}
+	}
+	mustWrite(t, filepath.Join(root, "app", "big.iso"), 400)
+	mustWrite(t, filepath.Join(root, "small.txt"), 5)
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, root, Options{Depth: 2, Limit: 1, SortBy: "size", MinSizeBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Items) != 2 {
+		t.Fatalf("expected app and its largest child, got %+v", res.Items)
+	}
+	top, child := res.Items[0], res.Items[1]
+	if top.Path != filepath.Join(root, "app") || top.Kind != "dir" || top.Depth != 1 || top.SizeBytes != 1000 || top.FileCount != 7 {
+		t.Fatalf("unexpected top-level node: %+v", top)
+	}
+	if child.Path != mods || child.Depth != 2 || child.SizeBytes != 600 || child.FileCount != 6 || !child.Selected {
+		t.Fatalf("expected node_modules as one aggregated candidate, got %+v", child)
+	}
+	m, ok := res.Metrics.(TreeMetrics)
+	if !ok || m.SizeBytes != 1005 || m.FileCount != 8 {
+		t.Fatalf("unexpected root metrics: %+v", res.Metrics)
+	}
+	if res.Summary.EstimatedFreedBytes != 600 {
+		t.Fatalf("expected estimate to count node_modules once, got %d", res.Summary.EstimatedFreedBytes)
+	}
+}
+
+func TestActOnDeletesMarkedNodesWithHighRiskConfirmation(t *testing.T) {
+	root := t.TempDir()
+	mods := filepath.Join(root, "app", "node_modules")
+	mustMkdir(t, filepath.Join(mods, "pkg"))
+	mustWrite(t, filepath.Join(mods, "pkg", "index.js"), 100)
+	mustWrite(t, filepath.Join(root, "app", "main.go"), 10)
+
+	svc := NewService()
+	app := &common.AppContext{Options: common.GlobalOptions{Yes: true}, Logger: logging.NewNoopLogger()}
+	tree, err := svc.Tree(context.Background(), app, root, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var marked []*filesystem.TreeNode
+	var walk func(n *filesystem.TreeNode)
+	walk = func(n *filesystem.TreeNode) {
+		if n.Path == mods || n.Path == filepath.Join(mods, "pkg") {
+			marked = append(marked, n)
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(tree)
+	if len(marked) != 2 {
+		t.Fatalf("expected to find both nodes, got %d", len(marked))
+	}
+
+	if _, err := svc.ActOn(context.Background(), app, root, marked, ActOptions{Action: "delete"}); err == nil {
+		t.Fatalf("expected delete without HIGH-RISK to be refused")
+	}
+
+	app.Options.Confirm = "HIGH-RISK"
+	res, err := svc.ActOn(context.Background(), app, root, marked, ActOptions{Action: "delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items[0].Result != "deleted" || res.Items[1].Result != "skipped" || res.Summary.EstimatedFreedBytes != 100 {
+		t.Fatalf("expected parent deleted and nested node skipped, got %+v", res.Items)
+	}
+	if _, err := os.Stat(mods); !os.IsNotExist(err) {
+		t.Fatalf("expected node_modules to be removed, got %v", err)
+	}
+}
+
+func TestRunSelectsOnlyNodesMatchingTheFilter(t *testing.T) {
+	root := t.TempDir()
+	build := filepath.Join(root, "app", "build")
+	mustMkdir(t, build)
+	mustWrite(t, filepath.Join(build, "app.bin"), 300)
+	mustWrite(t, filepath.Join(build, "notes.txt"), 20)
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, root, Options{Query: "bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := map[string]bool{}
+	for _, item := range res.Items {
+		selected[item.Path] = item.Selected
+	}
+	bin := filepath.Join(build, "app.bin")
+	if len(selected) != 3 || selected[filepath.Join(root, "app")] || selected[build] || !selected[bin] {
+		t.Fatalf("expected only app.bin selected under its path-only ancestors, got %v", selected)
+	}
+	if res.Summary.ItemsSelected != 1 || res.Summary.EstimatedFreedBytes != 300 {
+		t.Fatalf("expected the estimate to cover app.bin only, got %+v", res.Summary)
+	}
+}
@@ -0,0 +1,58 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"talpa/internal/app/analyze"
+	"talpa/internal/app/clean"
+	"talpa/internal/app/common"
+	"talpa/internal/app/purge"
+	"talpa/internal/domain/model"
+)
+
+type Service struct{}
+
+type planApplier func(ctx context.Context, app *common.AppContext, plan model.Plan) (model.CommandResult, error)
+
+var appliers = map[string]planApplier{
+	"analyze": analyze.NewService().Apply,
+	"clean":   clean.NewService().Apply,
+	"purge":   purge.NewService().Apply,
+}
+
+func NewService() Service { return Service{} }
+
+func (Service) Run(ctx context.Context, app *common.AppContext, planID string) (model.CommandResult, error) {
+	planID = strings.TrimSpace(planID)
+	if planID == "" {
+		return model.CommandResult{}, errors.New("apply requires a plan ID")
+	}
+	if app.Plans == nil {
+		return model.CommandResult{}, errors.New("plan store unavailable")
+	}
+	plan, err := app.Plans.Load(planID)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	if plan.AppliedAt != nil {
+		return model.CommandResult{}, fmt.Errorf("PLAN_APPLIED: plan %s was already applied at %s", planID, plan.AppliedAt.Format(time.RFC3339))
+	}
+	run, ok := appliers[plan.Command]
+	if !ok {
+		return model.CommandResult{}, fmt.Errorf("PLAN_INVALID: plans from %q cannot be applied", plan.Command)
+	}
+	result, err := run(ctx, app, plan)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	if !app.Options.DryRun {
+		if err := app.Plans.MarkApplied(planID, time.Now()); err != nil {
+			return model.CommandResult{}, err
+		}
+	}
+	return result, nil
+}
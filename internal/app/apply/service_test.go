@@ -0,0 +1,152 @@
+package apply
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/app/purge"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/logging"
+	"talpa/internal/infra/planstore"
+)
+
+func newPurgeArtifact(t *testing.T, home, project string) string {
+	t.Helper()
+	dir := filepath.Join(home, "Projects", project, "node_modules")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.js"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "package.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(dir, old, old); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func planPurge(t *testing.T, home string, store planstore.Store) model.CommandResult {
+	t.Helper()
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	res, err := purge.NewService().Run(context.Background(), app, []string{filepath.Join(home, "Projects")}, purge.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PlanID == "" {
+		t.Fatal("expected dry-run to return a plan id")
+	}
+	return res
+}
+
+func TestRunAppliesSavedPlan(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	store := planstore.NewFileStoreAt(filepath.Join(home, "plans"))
+
+	artifact := newPurgeArtifact(t, home, "app")
+	planned := planPurge(t, home, store)
+	if _, err := os.Stat(artifact); err != nil {
+		t.Fatalf("dry-run must not delete: %v", err)
+	}
+
+	app := &common.AppContext{Options: common.GlobalOptions{Yes: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	res, err := NewService().Run(context.Background(), app, planned.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PlanID != planned.PlanID || len(res.Items) != 1 || res.Items[0].Result != "deleted" {
+		t.Fatalf("unexpected apply result: %+v", res)
+	}
+	if _, err := os.Stat(artifact); !os.IsNotExist(err) {
+		t.Fatalf("expected artifact to be deleted, stat err=%v", err)
+	}
+
+	if _, err := NewService().Run(context.Background(), app, planned.PlanID); err == nil || !strings.Contains(err.Error(), "PLAN_APPLIED") {
+		t.Fatalf("expected second apply to be refused, got %v", err)
+	}
+}
+
+func TestRunSkipsDriftedItems(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	store := planstore.NewFileStoreAt(filepath.Join(home, "plans"))
+
+	grown := newPurgeArtifact(t, home, "grown")
+	replaced := newPurgeArtifact(t, home, "replaced")
+	planned := planPurge(t, home, store)
+
+	if err := os.WriteFile(filepath.Join(grown, "b.js"), []byte("more"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replaced, filepath.Join(home, "replaced.old")); err != nil {
+		t.Fatal(err)
+	}
+	newPurgeArtifact(t, home, "replaced")
+
+	app := &common.AppContext{Options: common.GlobalOptions{Yes: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	res, err := NewService().Run(context.Background(), app, planned.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary.Errors != 2 {
+		t.Fatalf("expected two drift errors, got %+v", res.Summary)
+	}
+	for _, item := range res.Items {
+		if item.Result != "skipped" {
+			t.Fatalf("expected drifted item to be skipped, got %+v", item)
+		}
+	}
+	for _, p := range []string{grown, replaced} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("drifted path %s must be kept: %v", p, err)
+		}
+	}
+}
+
+func TestRunDryRunDoesNotConsumePlan(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	store := planstore.NewFileStoreAt(filepath.Join(home, "plans"))
+
+	artifact := newPurgeArtifact(t, home, "app")
+	planned := planPurge(t, home, store)
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	res, err := NewService().Run(context.Background(), app, planned.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Result != "planned" {
+		t.Fatalf("unexpected dry-run apply result: %+v", res.Items)
+	}
+	if _, err := os.Stat(artifact); err != nil {
+		t.Fatalf("dry-run apply must not delete: %v", err)
+	}
+	plan, err := store.Load(planned.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.AppliedAt != nil {
+		t.Fatal("dry-run apply must not mark the plan applied")
+	}
+}
+
+func TestRunRejectsUnknownPlan(t *testing.T) {
+	store := planstore.NewFileStoreAt(t.TempDir())
+	app := &common.AppContext{Options: common.GlobalOptions{Yes: true}, Logger: logging.NewNoopLogger(), Plans: store}
+	if _, err := NewService().Run(context.Background(), app, "plan-purge-missing"); err == nil {
+		t.Fatal("expected missing plan error")
+	}
+	if _, err := NewService().Run(context.Background(), app, "../../etc/passwd"); err == nil || !strings.Contains(err.Error(), "PLAN_INVALID") {
+		t.Fatalf("expected malformed plan id to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package clean
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"talpa/internal/app/optimize"
+	"talpa/internal/infra/filesystem"
+)
+
+const (
+	actionDelete = "delete"
+	actionPrune  = "prune"
+)
+
+type cacheAdapter struct {
+	RuleID   string
+	Name     string
+	Command  []string
+	Location []string
+}
+
+var (
+	resolveExec = optimize.ResolveTrustedExecutable
+	runExec     = optimize.RunTrustedExecutable
+	outputExec  = optimize.OutputTrustedExecutable
+)
+
+var storeVersionDir = regexp.MustCompile(`^v[0-9]+$`)
+
+func cacheAdapters() []cacheAdapter {
+	return []cacheAdapter{
+		{RuleID: "clean.dev.go.build", Name: "go", Command: []string{"go", "clean", "-cache"}, Location: []string{"env", "GOCACHE"}},
+		{RuleID: "clean.dev.go.mod", Name: "go", Command: []string{"go", "clean", "-modcache"}, Location: []string{"env", "GOMODCACHE"}},
+		{RuleID: "clean.dev.npm", Name: "npm", Command: []string{"npm", "cache", "clean", "--force"}, Location: []string{"config", "get", "cache"}},
+		{RuleID: "clean.dev.pnpm", Name: "pnpm", Command: []string{"pnpm", "store", "prune"}, Location: []string{"store", "path"}},
+		{RuleID: "clean.dev.yarn", Name: "yarn", Command: []string{"yarn", "cache", "clean"}, Location: []string{"cache", "dir"}},
+		{RuleID: "clean.dev.pip", Name: "pip", Command: []string{"pip", "cache", "purge"}, Location: []string{"cache", "dir"}},
+	}
+}
+
+func nativeAdapterFor(ctx context.Context, ruleID, path string) (cacheAdapter, bool) {
+	for _, a := range cacheAdapters() {
+		if a.RuleID != ruleID {
+			continue
+		}
+		resolved, err := resolveExec(a.Command[0])
+		if err != nil {
+			return cacheAdapter{}, false
+		}
+		out, err := outputExec(ctx, resolved, a.Location...)
+		if err != nil || !isToolCacheDir(strings.TrimSpace(string(out)), path) {
+			return cacheAdapter{}, false
+		}
+		a.Command = append([]string{resolved}, a.Command[1:]...)
+		return a, true
+	}
+	return cacheAdapter{}, false
+}
+
+func isToolCacheDir(location, path string) bool {
+	if location == "" || !filepath.IsAbs(location) {
+		return false
+	}
+	location, path = resolvedPath(location), resolvedPath(path)
+	if location == path {
+		return true
+	}
+	return storeVersionDir.MatchString(filepath.Base(location)) && filepath.Dir(location) == path
+}
+
+func resolvedPath(path string) string {
+	path = filepath.Clean(path)
+	if r, err := filepath.EvalSymlinks(path); err == nil {
+		return r
+	}
+	return path
+}
+
+func cleanAction(ctx context.Context, ruleID, path string, wholeTarget bool) string {
+	if !wholeTarget {
+		return actionDelete
+	}
+	if _, ok := nativeAdapterFor(ctx, ruleID, path); ok {
+		return actionPrune
+	}
+	return actionDelete
+}
+
+func pruneCleanTarget(ctx context.Context, ruleID, path string, allowedRoots, whitelist []string) (string, string, error) {
+	a, ok := nativeAdapterFor(ctx, ruleID, path)
+	if !ok {
+		if err := deleteCleanTarget(path, allowedRoots, whitelist, false); err != nil {
+			return "error", "", err
+		}
+		return "deleted", "", nil
+	}
+	runErr := runExec(ctx, a.Command[0], a.Command[1:]...)
+	if runErr == nil {
+		return "pruned", "", nil
+	}
+	note := fmt.Sprintf("%s failed, fell back to delete: %v", a.Name, runErr)
+	if err := deleteCleanTarget(path, allowedRoots, whitelist, false); err != nil {
+		return "error", note, err
+	}
+	return "deleted", note, nil
+}
+
+func prunedUsage(before, after filesystem.Usage) filesystem.Usage {
+	return filesystem.Usage{
+		ApparentBytes:  max(before.ApparentBytes-after.ApparentBytes, 0),
+		AllocatedBytes: max(before.AllocatedBytes-after.AllocatedBytes, 0),
+		FileCount:      max(before.FileCount-after.FileCount, 0),
+		LastModified:   before.LastModified,
+	}
+}
+
+func joinNote(parts ...string) string {
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, "; ")
+}
@@ -0,0 +1,247 @@
+package clean
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/logging"
+	"talpa/internal/infra/planstore"
+)
+
+func stubNativeExec(t *testing.T, resolveErr, runErr error) *[]string {
+	t.Helper()
+	oldResolve, oldRun, oldOutput := resolveExec, runExec, outputExec
+	t.Cleanup(func() { resolveExec, runExec, outputExec = oldResolve, oldRun, oldOutput })
+	var ran []string
+	resolveExec = func(name string) (string, error) {
+		if resolveErr != nil {
+			return "", resolveErr
+		}
+		return "/usr/bin/" + name, nil
+	}
+	runExec = func(_ context.Context, name string, args ...string) error {
+		ran = append(ran, name+" "+filepath.Join(args...))
+		return runErr
+	}
+	outputExec = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		if name != "/usr/bin/go" || strings.Join(args, " ") != "env GOCACHE" {
+			return nil, errors.New("unexpected location query")
+		}
+		return []byte(filepath.Join(os.Getenv("HOME"), ".cache", "go-build") + "\n"), nil
+	}
+	return &ran
+}
+
+func runCleanOnGoBuildCache(t *testing.T) (model.CommandResult, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cache := filepath.Join(home, ".cache", "go-build")
+	if err := os.MkdirAll(cache, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cache, "obj"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := &common.AppContext{
+		Options: common.GlobalOptions{Yes: true},
+		Logger:  logging.NewNoopLogger(),
+		Profile: &model.Profile{Name: "only-go", EnableRules: []string{"clean.dev.go.build"}},
+	}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range res.Items {
+		if it.RuleID == "clean.dev.go.build" {
+			return res, cache
+		}
+	}
+	t.Fatalf("expected go build cache item, got %+v", res.Items)
+	return res, cache
+}
+
+func itemFor(res model.CommandResult, ruleID string) model.CandidateItem {
+	for _, it := range res.Items {
+		if it.RuleID == ruleID {
+			return it
+		}
+	}
+	return model.CandidateItem{}
+}
+
+func TestRunPrefersNativePrune(t *testing.T) {
+	ran := stubNativeExec(t, nil, nil)
+	res, cache := runCleanOnGoBuildCache(t)
+
+	if got := itemFor(res, "clean.dev.go.build").Result; got != "pruned" {
+		t.Fatalf("expected pruned, got %q", got)
+	}
+	if len(*ran) != 1 || (*ran)[0] != "/usr/bin/go clean/-cache" {
+		t.Fatalf("unexpected native commands: %v", *ran)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "obj")); err != nil {
+		t.Fatalf("expected raw delete to be skipped when native prune succeeds: %v", err)
+	}
+}
+
+func TestRunFallsBackToDeleteWhenNativeFails(t *testing.T) {
+	stubNativeExec(t, nil, errors.New("exit status 1"))
+	res, cache := runCleanOnGoBuildCache(t)
+
+	if got := itemFor(res, "clean.dev.go.build").Result; got != "deleted" {
+		t.Fatalf("expected deleted, got %q", got)
+	}
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Fatalf("expected fallback delete, got %v", err)
+	}
+}
+
+func TestRunDeletesWhenNoTrustedExecutable(t *testing.T) {
+	ran := stubNativeExec(t, errors.New("not found"), nil)
+	res, cache := runCleanOnGoBuildCache(t)
+
+	if got := itemFor(res, "clean.dev.go.build").Result; got != "deleted" || len(*ran) != 0 {
+		t.Fatalf("expected raw delete without native run, got %q %v", got, *ran)
+	}
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Fatalf("expected raw delete, got %v", err)
+	}
+}
+
+func TestRunReportsBytesActuallyFreedByNativePrune(t *testing.T) {
+	stubNativeExec(t, nil, nil)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cache := filepath.Join(home, ".cache", "go-build")
+	if err := os.MkdirAll(cache, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"stale": 3000, "fresh": 1000} {
+		if err := os.WriteFile(filepath.Join(cache, name), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runExec = func(context.Context, string, ...string) error {
+		return os.Remove(filepath.Join(cache, "stale"))
+	}
+
+	store := planstore.NewFileStoreAt(filepath.Join(t.TempDir(), "plans"))
+	app := &common.AppContext{
+		Options: common.GlobalOptions{DryRun: true},
+		Logger:  logging.NewNoopLogger(),
+		Plans:   store,
+		Profile: &model.Profile{Name: "only-go", EnableRules: []string{"clean.dev.go.build"}},
+	}
+	planned, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := store.Load(planned.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.Options = common.GlobalOptions{Yes: true}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := itemFor(res, "clean.dev.go.build")
+	if it.Result != "pruned" || it.SizeBytes != 3000 || res.Summary.EstimatedFreedBytes != 3000 {
+		t.Fatalf("expected only the pruned 3000 bytes to be reported, got %+v (summary %+v)", it, res.Summary)
+	}
+
+	if err := os.WriteFile(filepath.Join(cache, "stale"), make([]byte, 3000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := NewService().Apply(context.Background(), app, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemFor(applied, "clean.dev.go.build").Result; got != "pruned" || applied.Summary.EstimatedFreedBytes != 3000 {
+		t.Fatalf("expected apply to report the pruned 3000 bytes, got %q (summary %+v)", got, applied.Summary)
+	}
+}
+
+func TestRunSkipsNativePruneForRulesOutsideTheToolCache(t *testing.T) {
+	ran := stubNativeExec(t, nil, nil)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	target := filepath.Join(home, "scratch", "build-cache")
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "obj"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	app := &common.AppContext{
+		Options: common.GlobalOptions{Yes: true},
+		Logger:  logging.NewNoopLogger(),
+		Profile: &model.Profile{Name: "only-go", EnableRules: []string{"clean.dev.go.build"}},
+		Rules:   []model.Rule{{ID: "clean.dev.go.build", Command: "clean", Category: "dev_cache", Pattern: target, Risk: model.RiskLow, Source: "override.yaml"}},
+	}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemFor(res, "clean.dev.go.build").Result; got != "deleted" || len(*ran) != 0 {
+		t.Fatalf("expected a plain delete without running go clean, got %q %v", got, *ran)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected the plugin target to be deleted, got %v", err)
+	}
+}
+
+func TestRunSkipsNativePruneWhenTheToolUsesAnotherCache(t *testing.T) {
+	ran := stubNativeExec(t, nil, nil)
+	outputExec = func(context.Context, string, ...string) ([]byte, error) {
+		return []byte("/srv/shared/go-build\n"), nil
+	}
+	res, cache := runCleanOnGoBuildCache(t)
+
+	if got := itemFor(res, "clean.dev.go.build").Result; got != "deleted" || len(*ran) != 0 {
+		t.Fatalf("expected a plain delete when GOCACHE points elsewhere, got %q %v", got, *ran)
+	}
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Fatalf("expected raw delete, got %v", err)
+	}
+}
+
+func TestRunSkipsNativePruneWhenTheCacheLocationIsUnknown(t *testing.T) {
+	ran := stubNativeExec(t, nil, nil)
+	outputExec = func(context.Context, string, ...string) ([]byte, error) {
+		return nil, errors.New("exit status 2")
+	}
+	res, _ := runCleanOnGoBuildCache(t)
+
+	if got := itemFor(res, "clean.dev.go.build").Result; got != "deleted" || len(*ran) != 0 {
+		t.Fatalf("expected a plain delete when the location query fails, got %q %v", got, *ran)
+	}
+}
+
+func TestIsToolCacheDirAcceptsVersionedStoreDirs(t *testing.T) {
+	root := t.TempDir()
+	cases := []struct {
+		location string
+		want     bool
+	}{
+		{root, true},
+		{filepath.Join(root, "v3"), true},
+		{filepath.Join(root, "store"), false},
+		{filepath.Dir(root), false},
+		{"relative/cache", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := isToolCacheDir(c.location, root); got != c.want {
+			t.Fatalf("isToolCacheDir(%q, %q) = %v, want %v", c.location, root, got, c.want)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"talpa/internal/domain/model"
 	"talpa/internal/domain/rules"
 	"talpa/internal/domain/safety"
+	"talpa/internal/infra/filesystem"
 )
 
 type Service struct{}
@@ -20,9 +21,11 @@ type Service struct{}
 var getEUID = os.Geteuid
 var cleanReadDir = os.ReadDir
 var cleanSafeDelete = safety.SafeDelete
+var cleanPathUsage = filesystem.PathUsage
 
 type Options struct {
-	System bool
+	System   bool
+	SizeMode string
 }
 
 func NewService() Service { return Service{} }
@@ -34,52 +37,84 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 		return model.CommandResult{}, err
 	}
 
-	ruleSet := rules.ExistingCleanRules(home, opts.System)
+	ruleSet := rules.ApplyProfile(app.Profile, rules.ExistingCleanRules(home, opts.System, app.Rules))
 
 	items := make([]model.CandidateItem, 0, len(ruleSet))
+	actions := make([]string, 0, len(ruleSet))
+	itemUsages := make([]filesystem.Usage, 0, len(ruleSet))
 	selected := 0
-	var estimate int64
 	errCount := 0
 	requiresHighRiskConfirm := false
 	isRoot := getEUID() == 0
 
-	for i, rule := range ruleSet {
+	for _, rule := range ruleSet {
 		p := rule.Pattern
-		size := dirSize(p)
+		usage := cleanPathUsage(p)
+		size := usage.Bytes(opts.SizeMode)
 		allowedRoots := cleanAllowedRoots(rule, home)
-		item := model.CandidateItem{
-			ID:           "clean-" + strconv.Itoa(i+1),
-			RuleID:       rule.ID,
-			Path:         p,
-			SizeBytes:    size,
-			LastModified: time.Now().UTC(),
-			Category:     rule.Category,
-			Risk:         rule.Risk,
-			Selected:     true,
-			RequiresRoot: rule.RequiresRoot,
-			Result:       "planned",
-		}
+		target := newCleanItem(rule, p, size, usage.LastModified)
+		candidates := []model.CandidateItem{target}
+		usages := []filesystem.Usage{usage}
 
 		if rule.RequiresRoot && !isRoot {
-			item.Selected = false
-			item.Result = "skipped"
+			skipItem(&candidates[0])
+		} else if !rules.MeetsThresholds(rule, size, usage.LastModified, start) {
+			skipItem(&candidates[0])
 		} else if err := safety.ValidatePath(p, allowedRoots, cleanWhitelistForPath(app.Whitelist, p)); err != nil {
-			item.Selected = false
-			item.Result = "skipped"
+			skipItem(&candidates[0])
 			errCount++
-		} else if rule.Risk == model.RiskHigh {
-			requiresHighRiskConfirm = true
-		} else {
-			selected++
-			estimate += size
+		} else if rules.HasEntryPolicy(rule) {
+			entries, entryUsage, err := policyEntries(rule, opts.SizeMode, start)
+			if err != nil {
+				skipItem(&candidates[0])
+				errCount++
+			} else if len(entries) == 0 {
+				skipItem(&candidates[0])
+			} else {
+				candidates = candidates[:0]
+				usages = usages[:0]
+				for _, e := range entries {
+					item := newCleanItem(rule, e.Path, e.SizeBytes, e.ModTime)
+					if err := safety.ValidatePath(e.Path, cleanAllowedRootsByPath(e.Path, home), cleanWhitelistForPath(app.Whitelist, e.Path)); err != nil {
+						skipItem(&item)
+						errCount++
+					}
+					candidates = append(candidates, item)
+					usages = append(usages, entryUsage[e.Path])
+				}
+			}
 		}
 
-		if item.Selected && rule.Risk == model.RiskHigh {
-			selected++
-			estimate += size
+		for i, item := range candidates {
+			item.ID = "clean-" + strconv.Itoa(len(items)+1)
+			if item.Selected {
+				selected++
+				if rule.Risk == model.RiskHigh {
+					requiresHighRiskConfirm = true
+				}
+			}
+			action := actionDelete
+			if item.Selected {
+				action = cleanAction(ctx, rule.ID, item.Path, item.Path == p)
+			}
+			item.SharedLinks = usages[i].SharedLinks
+			items = append(items, item)
+			actions = append(actions, action)
+			itemUsages = append(itemUsages, usages[i])
 		}
+	}
 
-		items = append(items, item)
+	planID := common.NewPlanID("clean")
+	if app.Options.DryRun {
+		planItems := make([]model.PlanItem, 0, len(items))
+		for i, item := range items {
+			planItems = append(planItems, common.NewPlanItem(item, actions[i], cleanAllowedRootsByPath(item.Path, home)))
+		}
+		plan := common.NewPlan(planID, "clean", planItems)
+		plan.SizeMode = opts.SizeMode
+		if err := common.SavePlan(app, plan); err != nil {
+			return model.CommandResult{}, err
+		}
 	}
 
 	if !app.Options.DryRun {
@@ -94,25 +129,38 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 			if !items[i].Selected {
 				continue
 			}
-			err := deleteCleanTarget(items[i].Path, cleanAllowedRootsByPath(items[i].Path, home), cleanWhitelistForPath(app.Whitelist, items[i].Path), false)
-			if err != nil {
+			allowedRoots := cleanAllowedRootsByPath(items[i].Path, home)
+			whitelist := cleanWhitelistForPath(app.Whitelist, items[i].Path)
+			var note string
+			var err error
+			if actions[i] == actionPrune {
+				items[i].Result, note, err = pruneCleanTarget(ctx, items[i].RuleID, items[i].Path, allowedRoots, whitelist)
+				if items[i].Result == "pruned" {
+					itemUsages[i] = prunedUsage(itemUsages[i], cleanPathUsage(items[i].Path))
+					items[i].SizeBytes = itemUsages[i].Bytes(opts.SizeMode)
+				}
+			} else if err = deleteCleanTarget(items[i].Path, allowedRoots, whitelist, false); err != nil {
 				items[i].Result = "error"
-				errCount++
 			} else {
 				items[i].Result = "deleted"
 			}
+			if err != nil {
+				errCount++
+				note = joinNote(note, err.Error())
+			}
 
 			if err := app.Logger.Log(ctx, model.OperationLogEntry{
 				Timestamp: time.Now().UTC(),
-				PlanID:    "plan-clean",
+				PlanID:    planID,
 				Command:   "clean",
-				Action:    "delete",
+				Action:    actions[i],
 				Path:      items[i].Path,
 				RuleID:    items[i].RuleID,
 				Category:  items[i].Category,
 				SizeBytes: items[i].SizeBytes,
 				Risk:      string(items[i].Risk),
 				Result:    items[i].Result,
+				Error:     note,
 				DryRun:    false,
 			}); err != nil {
 				errCount++
@@ -120,22 +168,62 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 		}
 	}
 
+	var selectedUsage []filesystem.Usage
+	for i, item := range items {
+		if item.Selected {
+			selectedUsage = append(selectedUsage, itemUsages[i])
+		}
+	}
+	estimate := filesystem.Reclaimable(selectedUsage)
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "clean",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DurationMS:    time.Since(start).Milliseconds(),
 		DryRun:        app.Options.DryRun,
 		Summary: model.Summary{
-			ItemsTotal:          len(items),
-			ItemsSelected:       selected,
-			EstimatedFreedBytes: estimate,
-			Errors:              errCount,
+			ItemsTotal:                  len(items),
+			ItemsSelected:               selected,
+			EstimatedFreedBytes:         estimate.Bytes(opts.SizeMode),
+			SizeMode:                    opts.SizeMode,
+			EstimatedFreedApparentBytes: estimate.ApparentBytes,
+			EstimatedFreedDiskBytes:     estimate.AllocatedBytes,
+			Errors:                      errCount,
 		},
 		Items: items,
 	}, nil
 }
 
+func (Service) Apply(ctx context.Context, app *common.AppContext, plan model.Plan) (model.CommandResult, error) {
+	if err := common.RequirePlanCommand(plan, "clean"); err != nil {
+		return model.CommandResult{}, err
+	}
+	highRisk := false
+	for _, item := range plan.Items {
+		if item.Selected && item.Risk == model.RiskHigh {
+			highRisk = true
+		}
+	}
+	if highRisk {
+		if err := common.RequireHighRiskConfirmationOrDryRun(app.Options, "clean apply"); err != nil {
+			return model.CommandResult{}, err
+		}
+	} else if err := common.RequireConfirmationOrDryRun(app.Options, "clean apply"); err != nil {
+		return model.CommandResult{}, err
+	}
+	return common.ExecutePlan(ctx, app, plan, common.UsageSizeOf(plan.SizeMode), func(item model.PlanItem) (string, model.TrashedEntry, error) {
+		if item.Action == actionPrune {
+			result, _, err := pruneCleanTarget(ctx, item.RuleID, item.Path, item.AllowedRoots, cleanWhitelistForPath(app.Whitelist, item.Path))
+			return result, model.TrashedEntry{}, err
+		}
+		if err := deleteCleanTarget(item.Path, item.AllowedRoots, cleanWhitelistForPath(app.Whitelist, item.Path), false); err != nil {
+			return "error", model.TrashedEntry{}, err
+		}
+		return "deleted", model.TrashedEntry{}, nil
+	}), nil
+}
+
 func cleanAllowedRoots(rule model.Rule, home string) []string {
 	if strings.HasPrefix(rule.ID, "clean.system.") {
 		return cleanAllowedRootsByPath(rule.Pattern, home)
@@ -191,16 +279,55 @@ func deleteCleanTarget(path string, allowedRoots []string, whitelist []string, d
 	return cleanSafeDelete(path, allowedRoots, whitelist, dryRun)
 }
 
-func dirSize(path string) int64 {
-	var size int64
-	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-		if err != nil || info == nil {
-			return nil
+func newCleanItem(rule model.Rule, path string, size int64, modTime time.Time) model.CandidateItem {
+	return model.CandidateItem{
+		RuleID:       rule.ID,
+		Path:         path,
+		SizeBytes:    size,
+		LastModified: modTime.UTC(),
+		Category:     rule.Category,
+		Risk:         rule.Risk,
+		Selected:     true,
+		RequiresRoot: rule.RequiresRoot,
+		Result:       "planned",
+	}
+}
+
+func skipItem(item *model.CandidateItem) {
+	item.Selected = false
+	item.Result = "skipped"
+}
+
+func policyEntries(rule model.Rule, sizeMode string, now time.Time) ([]rules.Entry, map[string]filesystem.Usage, error) {
+	depth := rule.EntryDepth
+	if depth == 0 {
+		depth = 1
+	}
+	var entries []rules.Entry
+	usage := map[string]filesystem.Usage{}
+	var walk func(dir string, level int) error
+	walk = func(dir string, level int) error {
+		dirEntries, err := cleanReadDir(dir)
+		if err != nil {
+			return err
 		}
-		if !info.IsDir() {
-			size += info.Size()
+		for _, de := range dirEntries {
+			path := filepath.Join(dir, de.Name())
+			if de.IsDir() && (depth == rules.EntryDepthFiles || level < depth) {
+				_ = walk(path, level+1)
+				continue
+			}
+			u := cleanPathUsage(path)
+			if u.LastModified.IsZero() {
+				continue
+			}
+			usage[path] = u
+			entries = append(entries, rules.Entry{Path: path, SizeBytes: u.Bytes(sizeMode), ModTime: u.LastModified})
 		}
 		return nil
-	})
-	return size
+	}
+	if err := walk(rule.Pattern, 1); err != nil {
+		return nil, nil, err
+	}
+	return rules.SelectEntries(rule, entries, now), usage, nil
 }
@@ -58,7 +58,9 @@ func TestRunDryRunGoldenJSON(t *testing.T) {
 func normalizeResult(res *model.CommandResult, home string) {
 	norm := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 	res.Timestamp = norm
+	res.PlanID = ""
 	res.DurationMS = 0
+	res.Summary.EstimatedFreedDiskBytes = 0
 	for i := range res.Items {
 		res.Items[i].Path = strings.ReplaceAll(res.Items[i].Path, home, "$HOME")
 		res.Items[i].LastModified = norm
@@ -5,8 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/rules"
 	"talpa/internal/infra/logging"
 )
 
@@ -122,3 +125,205 @@ func (f fakeDirEntry) Name() string               { return f.name }
 func (f fakeDirEntry) IsDir() bool                { return false }
 func (f fakeDirEntry) Type() os.FileMode          { return 0 }
 func (f fakeDirEntry) Info() (os.FileInfo, error) { return nil, os.ErrNotExist }
+
+func TestRunIncludesPluginRulesAndHonorsThresholds(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for _, dir := range []string{"big", "small"} {
+		if err := os.MkdirAll(filepath.Join(home, ".acme", dir, "cache"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(home, ".acme", "big", "cache", "blob"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &common.AppContext{
+		Options: common.GlobalOptions{DryRun: true},
+		Logger:  logging.NewNoopLogger(),
+		Rules: []model.Rule{
+			{ID: "clean.acme", Command: "clean", Category: "acme_cache", Pattern: filepath.Join(home, ".acme", "*", "cache"), Risk: model.RiskLow, MinSizeBytes: 1024, Source: "acme.yaml"},
+		},
+	}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := map[string]bool{}
+	for _, it := range res.Items {
+		if it.RuleID == "clean.acme" {
+			selected[it.Path] = it.Selected
+		}
+	}
+	if len(selected) != 2 {
+		t.Fatalf("expected glob rule to expand to two targets, got %+v", selected)
+	}
+	if !selected[filepath.Join(home, ".acme", "big", "cache")] || selected[filepath.Join(home, ".acme", "small", "cache")] {
+		t.Fatalf("expected only the target above min_size to be selected, got %+v", selected)
+	}
+}
+
+func TestRunAppliesProfileRuleSelection(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, dir := range []string{filepath.Join(".cache", "thumbnails"), ".npm"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := &common.AppContext{
+		Options: common.GlobalOptions{DryRun: true},
+		Logger:  logging.NewNoopLogger(),
+		Profile: &model.Profile{Name: "desktop", EnableCategories: []string{"thumbnails"}},
+	}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].RuleID != "clean.thumbnails" {
+		t.Fatalf("expected only thumbnails rule under profile, got %+v", res.Items)
+	}
+}
+
+func TestRunEntryPolicyDeletesOnlyMatchingEntries(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	npm := filepath.Join(home, ".npm")
+	old := time.Now().Add(-45 * 24 * time.Hour)
+	for _, name := range []string{"fresh", "stale-a", "stale-b"} {
+		dir := filepath.Join(npm, name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(dir, "blob")
+		if err := os.WriteFile(file, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "fresh" {
+			for _, p := range []string{file, dir} {
+				if err := os.Chtimes(p, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	app := &common.AppContext{
+		Options: common.GlobalOptions{Yes: true},
+		Logger:  logging.NewNoopLogger(),
+		Rules: []model.Rule{
+			{ID: "clean.dev.npm", Command: "clean", Category: "dev_cache", Pattern: npm, Risk: model.RiskLow, OlderThan: 30 * 24 * time.Hour},
+		},
+	}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var npmItems []model.CandidateItem
+	for _, it := range res.Items {
+		if it.RuleID == "clean.dev.npm" {
+			npmItems = append(npmItems, it)
+		}
+	}
+	if len(npmItems) != 2 {
+		t.Fatalf("expected two stale entries, got %+v", npmItems)
+	}
+	for _, it := range npmItems {
+		if filepath.Dir(it.Path) != npm || it.Result != "deleted" || it.SizeBytes != 4 {
+			t.Fatalf("unexpected item: %+v", it)
+		}
+		if d := it.LastModified.Sub(old.UTC()); d > time.Second || d < -time.Second {
+			t.Fatalf("expected real mtime %s, got %s", old.UTC(), it.LastModified)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(npm, "fresh", "blob")); err != nil {
+		t.Fatalf("expected fresh entry to remain: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(npm, "stale-a")); !os.IsNotExist(err) {
+		t.Fatalf("expected stale entry to be deleted, got %v", err)
+	}
+}
+
+func TestRunEntryPolicyAgesIndividualFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cache := filepath.Join(home, ".npm", "_cacache")
+	old := time.Now().Add(-45 * 24 * time.Hour)
+	bucket := filepath.Join(cache, "content-v2", "sha512", "ab")
+	if err := os.MkdirAll(bucket, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(bucket, "stale")
+	fresh := filepath.Join(bucket, "fresh")
+	for _, p := range []string{stale, fresh} {
+		if err := os.WriteFile(p, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &common.AppContext{
+		Options: common.GlobalOptions{Yes: true},
+		Logger:  logging.NewNoopLogger(),
+		Rules: []model.Rule{
+			{ID: "clean.dev.npm", Command: "clean", Category: "dev_cache", Pattern: cache, Risk: model.RiskLow, OlderThan: 30 * 24 * time.Hour, EntryDepth: rules.EntryDepthFiles},
+		},
+	}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var npmItems []model.CandidateItem
+	for _, it := range res.Items {
+		if it.RuleID == "clean.dev.npm" {
+			npmItems = append(npmItems, it)
+		}
+	}
+	if len(npmItems) != 1 || npmItems[0].Path != stale || npmItems[0].Result != "deleted" {
+		t.Fatalf("expected only the stale file to be deleted, got %+v", npmItems)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatalf("expected the fresh file to remain: %v", err)
+	}
+}
+
+func TestRunMinAgeUsesNewestModificationInsideTarget(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	target := filepath.Join(home, ".cache", "acme")
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "active"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-45 * 24 * time.Hour)
+	if err := os.Chtimes(target, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &common.AppContext{
+		Options: common.GlobalOptions{DryRun: true},
+		Logger:  logging.NewNoopLogger(),
+		Rules: []model.Rule{
+			{ID: "clean.acme", Command: "clean", Category: "dev_cache", Pattern: target, Risk: model.RiskLow, MinAge: 7 * 24 * time.Hour},
+		},
+	}
+	res, err := NewService().Run(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range res.Items {
+		if it.RuleID == "clean.acme" && it.Selected {
+			t.Fatalf("expected a target with a fresh file inside to stay unselected, got %+v", it)
+		}
+	}
+}
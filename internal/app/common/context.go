@@ -1,6 +1,13 @@
 package common
 
-import "talpa/internal/infra/logging"
+import (
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/config"
+	"talpa/internal/infra/filesystem"
+	"talpa/internal/infra/logging"
+	"talpa/internal/infra/planstore"
+	"talpa/internal/infra/snapshotstore"
+)
 
 type contextKey string
 
@@ -13,12 +20,19 @@ type GlobalOptions struct {
 	Confirm        string
 	JSON           bool
 	NoOpLog        bool
+	Profile        string
 	StatusTop      int
 	StatusInterval int
 }
 
 type AppContext struct {
-	Options   GlobalOptions
-	Whitelist []string
-	Logger    logging.Logger
+	Options      GlobalOptions
+	Whitelist    []string
+	Logger       logging.Logger
+	Plans        planstore.Store
+	Snapshots    snapshotstore.Store
+	Rules        []model.Rule
+	Profile      *model.Profile
+	Config       config.Config
+	ScanProgress func(filesystem.ScanProgress)
 }
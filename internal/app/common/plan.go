@@ -0,0 +1,202 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/filesystem"
+)
+
+type PlanStep func(item model.PlanItem) (result string, trashed model.TrashedEntry, err error)
+
+var planIDNow = time.Now
+
+func NewPlanID(command string) string {
+	suffix := strconv.FormatInt(planIDNow().UnixNano()%1e8, 16)
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err == nil {
+		suffix = hex.EncodeToString(buf)
+	}
+	return "plan-" + command + "-" + planIDNow().UTC().Format("20060102T150405Z") + "-" + suffix
+}
+
+func NewPlan(planID, command string, items []model.PlanItem) model.Plan {
+	return model.Plan{
+		SchemaVersion: "1.0",
+		PlanID:        planID,
+		Command:       command,
+		CreatedAt:     time.Now().UTC(),
+		Items:         items,
+	}
+}
+
+func UsageSizeOf(mode string) func(string) int64 {
+	return func(path string) int64 {
+		return filesystem.PathUsage(path).Bytes(mode)
+	}
+}
+
+func NewPlanItem(item model.CandidateItem, action string, allowedRoots []string) model.PlanItem {
+	out := model.PlanItem{CandidateItem: item, Action: action, AllowedRoots: allowedRoots}
+	if dev, ino, err := filesystem.PathIdentity(item.Path); err == nil {
+		out.Device = dev
+		out.Inode = ino
+	}
+	return out
+}
+
+func SavePlan(app *AppContext, plan model.Plan) error {
+	if app.Plans == nil {
+		return nil
+	}
+	return app.Plans.Save(plan)
+}
+
+func VerifyPlanItem(item model.PlanItem, sizeOf func(string) int64) error {
+	dev, ino, err := filesystem.PathIdentity(item.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("PLAN_DRIFT: %s no longer exists", item.Path)
+		}
+		return err
+	}
+	if (item.Device != 0 || item.Inode != 0) && (dev != item.Device || ino != item.Inode) {
+		return fmt.Errorf("PLAN_DRIFT: %s was replaced since the plan was created", item.Path)
+	}
+	if sizeOf != nil {
+		if size := sizeOf(item.Path); size != item.SizeBytes {
+			return fmt.Errorf("PLAN_DRIFT: %s size changed from %d to %d bytes", item.Path, item.SizeBytes, size)
+		}
+	}
+	return nil
+}
+
+func ExecutePlan(ctx context.Context, app *AppContext, plan model.Plan, sizeOf func(string) int64, step PlanStep) model.CommandResult {
+	start := time.Now()
+	items := make([]model.CandidateItem, 0, len(plan.Items))
+	selected := 0
+	errCount := 0
+	var freed int64
+	removed := make([]string, 0, len(plan.Items))
+	for _, planned := range plan.Items {
+		item := planned.CandidateItem
+		if !item.Selected {
+			items = append(items, item)
+			continue
+		}
+		selected++
+		if err := ctx.Err(); err != nil {
+			item.Result = "skipped"
+			errCount++
+			items = append(items, item)
+			continue
+		}
+		entry := model.OperationLogEntry{
+			Timestamp: time.Now().UTC(),
+			PlanID:    plan.PlanID,
+			Command:   plan.Command,
+			Action:    planned.Action,
+			Path:      item.Path,
+			RuleID:    item.RuleID,
+			Category:  item.Category,
+			SizeBytes: item.SizeBytes,
+			Risk:      string(item.Risk),
+			DryRun:    app.Options.DryRun,
+		}
+		if underRemovedPath(item.Path, removed) {
+			item.Result = "skipped"
+			entry.Result = item.Result
+			if !app.Options.DryRun {
+				if err := app.Logger.Log(ctx, entry); err != nil {
+					errCount++
+				}
+			}
+			items = append(items, item)
+			continue
+		}
+		if err := VerifyPlanItem(planned, sizeOf); err != nil {
+			item.Result = "skipped"
+			errCount++
+			entry.Result = item.Result
+			entry.Error = err.Error()
+			if !app.Options.DryRun {
+				if err := app.Logger.Log(ctx, entry); err != nil {
+					errCount++
+				}
+			}
+			items = append(items, item)
+			continue
+		}
+		if app.Options.DryRun {
+			item.Result = "planned"
+			freed += item.SizeBytes
+			removed = append(removed, item.Path)
+			items = append(items, item)
+			continue
+		}
+		result, trashed, err := step(planned)
+		item.Result = result
+		if result == "error" {
+			errCount++
+		}
+		if result == "deleted" || result == "trashed" {
+			freed += item.SizeBytes
+			removed = append(removed, item.Path)
+		} else if result == "hardlinked" || result == "reflinked" {
+			freed += item.SizeBytes
+		} else if result == "pruned" {
+			freed += max(item.SizeBytes-sizeOf(item.Path), 0)
+		}
+		entry.Result = result
+		entry.TrashPath = trashed.Path
+		entry.TrashDevice = trashed.Device
+		entry.TrashInode = trashed.Inode
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if err := app.Logger.Log(ctx, entry); err != nil {
+			errCount++
+		}
+		items = append(items, item)
+	}
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       plan.Command,
+		PlanID:        plan.PlanID,
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		DryRun:        app.Options.DryRun,
+		Summary: model.Summary{
+			ItemsTotal:          len(items),
+			ItemsSelected:       selected,
+			EstimatedFreedBytes: freed,
+			Errors:              errCount,
+		},
+		Items: items,
+	}
+}
+
+func underRemovedPath(path string, removed []string) bool {
+	for _, r := range removed {
+		if strings.HasPrefix(path, r+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func RequirePlanCommand(plan model.Plan, command string) error {
+	if plan.Command != command {
+		return errors.New("PLAN_INVALID: plan " + plan.PlanID + " was created by " + plan.Command + ", not " + command)
+	}
+	return nil
+}
@@ -75,6 +75,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 		}
 	}
 
+	planID := common.NewPlanID("installer")
 	errCount := 0
 	if opts.Apply {
 		if err := common.RequireHighRiskConfirmationOrDryRun(app.Options, "installer cleanup"); err != nil {
@@ -83,14 +84,14 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 		if !app.Options.DryRun {
 			for i := range items {
 				if !items[i].Selected {
-					if err := common.LogApplySkip(ctx, app.Logger, "plan-installer", "installer", items[i]); err != nil {
+					if err := common.LogApplySkip(ctx, app.Logger, planID, "installer", items[i]); err != nil {
 						errCount++
 					}
 					continue
 				}
 				entry := model.OperationLogEntry{
 					Timestamp: time.Now().UTC(),
-					PlanID:    "plan-installer",
+					PlanID:    planID,
 					Command:   "installer",
 					Action:    "delete",
 					Path:      items[i].Path,
@@ -161,6 +162,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "installer",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DryRun:        app.Options.DryRun,
 		Summary: model.Summary{
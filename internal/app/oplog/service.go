@@ -0,0 +1,299 @@
+package oplog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/units"
+	"talpa/internal/infra/logging"
+)
+
+type Service struct{}
+
+type Options struct {
+	Commands   []string
+	PlanID     string
+	Results    []string
+	Risks      []string
+	PathPrefix string
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Summary    bool
+}
+
+type Metrics struct {
+	LogPath string                    `json:"log_path"`
+	Entries []model.OperationLogEntry `json:"entries,omitempty"`
+	Report  *Report                   `json:"report,omitempty"`
+}
+
+type Report struct {
+	Since        *time.Time `json:"since,omitempty"`
+	Until        *time.Time `json:"until,omitempty"`
+	Operations   int        `json:"operations"`
+	FreedBytes   int64      `json:"freed_bytes"`
+	TrashedBytes int64      `json:"trashed_bytes"`
+	ByCommand    []Totals   `json:"by_command"`
+	ByCategory   []Totals   `json:"by_category"`
+}
+
+type Totals struct {
+	Key          string `json:"key"`
+	Operations   int    `json:"operations"`
+	Freed        int    `json:"freed"`
+	FreedBytes   int64  `json:"freed_bytes"`
+	Trashed      int    `json:"trashed"`
+	TrashedBytes int64  `json:"trashed_bytes"`
+	Errors       int    `json:"errors"`
+}
+
+var (
+	operationLogPath = logging.OperationLogPath
+	readOperationLog = logging.ReadAllOperationLogs
+	verifyChain      = logging.VerifyChain
+)
+
+func NewService() Service { return Service{} }
+
+func resolveLogPath(app *common.AppContext) (string, error) {
+	if app.Config.OplogPath != "" {
+		return app.Config.OplogPath, nil
+	}
+	return operationLogPath()
+}
+
+func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (model.CommandResult, error) {
+	start := time.Now()
+	if !opts.Since.IsZero() && !opts.Until.IsZero() && opts.Until.Before(opts.Since) {
+		return model.CommandResult{}, errors.New("--until must not be before --since")
+	}
+	logPath, err := resolveLogPath(app)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	entries, err := readOperationLog(ctx, logPath)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+
+	matched := FilterEntries(entries, opts)
+	report := Summarize(matched, opts.Since, opts.Until)
+	metrics := Metrics{LogPath: logPath}
+	if opts.Summary {
+		metrics.Report = &report
+	} else {
+		if opts.Limit > 0 && len(matched) > opts.Limit {
+			matched = matched[len(matched)-opts.Limit:]
+		}
+		metrics.Entries = matched
+	}
+
+	errCount := 0
+	for _, t := range report.ByCommand {
+		errCount += t.Errors
+	}
+
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "log",
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		Summary: model.Summary{
+			ItemsTotal:          report.Operations,
+			EstimatedFreedBytes: report.FreedBytes,
+			Errors:              errCount,
+		},
+		Metrics: metrics,
+	}, nil
+}
+
+func (Service) Verify(ctx context.Context, app *common.AppContext) (model.CommandResult, error) {
+	start := time.Now()
+	logPath, err := resolveLogPath(app)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	report, err := verifyChain(ctx, logPath)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "log verify",
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		Summary: model.Summary{
+			ItemsTotal: report.Entries,
+			Errors:     len(report.Issues),
+		},
+		Metrics: report,
+	}, nil
+}
+
+func FilterEntries(entries []model.OperationLogEntry, opts Options) []model.OperationLogEntry {
+	commands := toSet(opts.Commands)
+	results := toSet(opts.Results)
+	risks := toSet(opts.Risks)
+	prefix := strings.TrimSpace(opts.PathPrefix)
+	if prefix != "" {
+		prefix = filepath.Clean(prefix)
+	}
+	out := make([]model.OperationLogEntry, 0, len(entries))
+	for _, e := range entries {
+		if len(commands) > 0 && !inSet(commands, e.Command) {
+			continue
+		}
+		if opts.PlanID != "" && e.PlanID != opts.PlanID {
+			continue
+		}
+		if len(results) > 0 && !inSet(results, e.Result) {
+			continue
+		}
+		if len(risks) > 0 && !inSet(risks, e.Risk) {
+			continue
+		}
+		if prefix != "" && !hasPathPrefix(e.Path, prefix) {
+			continue
+		}
+		if !opts.Since.IsZero() && e.Timestamp.Before(opts.Since) {
+			continue
+		}
+		if !opts.Until.IsZero() && e.Timestamp.After(opts.Until) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func Summarize(entries []model.OperationLogEntry, since, until time.Time) Report {
+	report := Report{ByCommand: []Totals{}, ByCategory: []Totals{}}
+	if !since.IsZero() {
+		s := since.UTC()
+		report.Since = &s
+	}
+	if !until.IsZero() {
+		u := until.UTC()
+		report.Until = &u
+	}
+	byCommand := map[string]*Totals{}
+	byCategory := map[string]*Totals{}
+	for _, e := range entries {
+		report.Operations++
+		freed := isFreedResult(e.Result)
+		trashed := e.Result == "trashed"
+		if freed {
+			report.FreedBytes += e.SizeBytes
+		}
+		if trashed {
+			report.TrashedBytes += e.SizeBytes
+		}
+		for _, agg := range []struct {
+			m   map[string]*Totals
+			key string
+		}{{byCommand, e.Command}, {byCategory, e.Category}} {
+			key := agg.key
+			if key == "" {
+				key = "unknown"
+			}
+			t, ok := agg.m[key]
+			if !ok {
+				t = &Totals{Key: key}
+				agg.m[key] = t
+			}
+			t.Operations++
+			if freed {
+				t.Freed++
+				t.FreedBytes += e.SizeBytes
+			}
+			if trashed {
+				t.Trashed++
+				t.TrashedBytes += e.SizeBytes
+			}
+			if e.Result == "error" {
+				t.Errors++
+			}
+		}
+	}
+	report.ByCommand = sortedTotals(byCommand)
+	report.ByCategory = sortedTotals(byCategory)
+	return report
+}
+
+func ParseUntilBound(value string, now time.Time) (time.Time, error) {
+	if day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(value), time.Local); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond).UTC(), nil
+	}
+	return ParseTimeBound(value, now)
+}
+
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	d, err := units.ParseAge(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339, YYYY-MM-DD, or a relative age like 7d, 2w, 6mo, 36h", value)
+	}
+	return now.Add(-d).UTC(), nil
+}
+
+func isFreedResult(result string) bool {
+	switch result {
+	case "deleted", "pruned", "hardlinked", "reflinked":
+		return true
+	}
+	return false
+}
+
+func sortedTotals(m map[string]*Totals) []Totals {
+	out := make([]Totals, 0, len(m))
+	for _, t := range m {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].FreedBytes != out[j].FreedBytes {
+			return out[i].FreedBytes > out[j].FreedBytes
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func toSet(values []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" {
+			out[v] = struct{}{}
+		}
+	}
+	return out
+}
+
+func inSet(set map[string]struct{}, v string) bool {
+	_, ok := set[strings.ToLower(strings.TrimSpace(v))]
+	return ok
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	path = filepath.Clean(path)
+	if path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(prefix, string(filepath.Separator))+string(filepath.Separator))
+}
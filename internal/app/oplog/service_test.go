@@ -0,0 +1,147 @@
+package oplog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/logging"
+)
+
+func sampleEntries() []model.OperationLogEntry {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []model.OperationLogEntry{
+		{Timestamp: base, PlanID: "plan-clean", Command: "clean", Action: "delete", Path: "/home/u/.cache/a", Category: "xdg_cache", SizeBytes: 100, Risk: "low", Result: "deleted"},
+		{Timestamp: base.Add(24 * time.Hour), PlanID: "plan-purge", Command: "purge", Action: "delete", Path: "/home/u/Projects/x/node_modules", Category: "project_artifact", SizeBytes: 500, Risk: "low", Result: "deleted"},
+		{Timestamp: base.Add(48 * time.Hour), PlanID: "plan-purge", Command: "purge", Action: "delete", Path: "/home/u/Projects/y/target", Category: "project_artifact", SizeBytes: 300, Risk: "low", Result: "error"},
+		{Timestamp: base.Add(72 * time.Hour), PlanID: "plan-analyze", Command: "analyze", Action: "trash", Path: "/home/u/Downloads/big.iso", Category: "tree_node", SizeBytes: 1000, Risk: "medium", Result: "trashed"},
+	}
+}
+
+func TestFilterEntries(t *testing.T) {
+	entries := sampleEntries()
+	base := entries[0].Timestamp
+
+	got := FilterEntries(entries, Options{Commands: []string{"PURGE"}})
+	if len(got) != 2 {
+		t.Fatalf("expected two purge entries, got %d", len(got))
+	}
+	got = FilterEntries(entries, Options{Results: []string{"deleted", "trashed"}, Risks: []string{"low"}})
+	if len(got) != 2 {
+		t.Fatalf("expected two low-risk freed entries, got %d", len(got))
+	}
+	got = FilterEntries(entries, Options{PathPrefix: "/home/u/Projects/"})
+	if len(got) != 2 {
+		t.Fatalf("expected two entries under Projects, got %d", len(got))
+	}
+	got = FilterEntries(entries, Options{PathPrefix: "/home/u/Proj"})
+	if len(got) != 0 {
+		t.Fatalf("expected prefix to match whole path segments, got %d", len(got))
+	}
+	got = FilterEntries(entries, Options{Since: base.Add(12 * time.Hour), Until: base.Add(60 * time.Hour)})
+	if len(got) != 2 || got[0].PlanID != "plan-purge" {
+		t.Fatalf("unexpected time-range result: %+v", got)
+	}
+}
+
+func TestSummarizeTotalsFreedBytes(t *testing.T) {
+	report := Summarize(sampleEntries(), time.Time{}, time.Time{})
+	if report.Operations != 4 {
+		t.Fatalf("expected four operations, got %d", report.Operations)
+	}
+	if report.FreedBytes != 600 || report.TrashedBytes != 1000 {
+		t.Fatalf("expected 600 freed and 1000 trashed bytes, got %d and %d", report.FreedBytes, report.TrashedBytes)
+	}
+	if report.ByCommand[0].Key != "purge" || report.ByCommand[0].FreedBytes != 500 {
+		t.Fatalf("expected purge to lead freed bytes, got %+v", report.ByCommand[0])
+	}
+	for _, c := range report.ByCommand {
+		if c.Key == "analyze" && (c.Freed != 0 || c.Trashed != 1 || c.TrashedBytes != 1000) {
+			t.Fatalf("expected trashed bytes to be reported apart from freed, got %+v", c)
+		}
+	}
+	for _, c := range report.ByCategory {
+		if c.Key == "project_artifact" && (c.FreedBytes != 500 || c.Errors != 1 || c.Operations != 2) {
+			t.Fatalf("unexpected project_artifact totals: %+v", c)
+		}
+	}
+}
+
+func TestRunSummaryMode(t *testing.T) {
+	originalPath := operationLogPath
+	originalRead := readOperationLog
+	operationLogPath = func() (string, error) { return "/tmp/operations.log", nil }
+	readOperationLog = func(context.Context, string) ([]model.OperationLogEntry, error) { return sampleEntries(), nil }
+	t.Cleanup(func() {
+		operationLogPath = originalPath
+		readOperationLog = originalRead
+	})
+
+	app := &common.AppContext{Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, Options{Commands: []string{"purge"}, Summary: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := res.Metrics.(Metrics)
+	if !ok || m.Report == nil {
+		t.Fatalf("expected summary report metrics, got %#v", res.Metrics)
+	}
+	if len(m.Entries) != 0 {
+		t.Fatalf("expected no entries in summary mode")
+	}
+	if res.Summary.EstimatedFreedBytes != 500 || res.Summary.Errors != 1 {
+		t.Fatalf("unexpected summary: %+v", res.Summary)
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	got, err := ParseTimeBound("7d", now)
+	if err != nil || !got.Equal(now.Add(-7*24*time.Hour)) {
+		t.Fatalf("unexpected 7d bound: %v %v", got, err)
+	}
+	got, err = ParseTimeBound("2026-03-01T00:00:00Z", now)
+	if err != nil || !got.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected RFC3339 bound: %v %v", got, err)
+	}
+	got, err = ParseTimeBound("6mo", now)
+	if err != nil || !got.Equal(now.Add(-180*24*time.Hour)) {
+		t.Fatalf("unexpected 6mo bound: %v %v", got, err)
+	}
+	for _, v := range []string{"last tuesday", "1m", "99999999y"} {
+		if _, err := ParseTimeBound(v, now); err == nil {
+			t.Fatalf("expected invalid time error for %q", v)
+		}
+	}
+}
+
+func TestSummarizeCountsPrunedAndDedupedAsFreed(t *testing.T) {
+	entries := []model.OperationLogEntry{
+		{Command: "clean", Category: "dev_cache", SizeBytes: 10, Result: "pruned"},
+		{Command: "analyze", Category: "duplicate", SizeBytes: 20, Result: "hardlinked"},
+		{Command: "analyze", Category: "duplicate", SizeBytes: 40, Result: "reflinked"},
+		{Command: "clean", Category: "dev_cache", SizeBytes: 80, Result: "skipped"},
+	}
+	if got := Summarize(entries, time.Time{}, time.Time{}).FreedBytes; got != 70 {
+		t.Fatalf("expected 70 freed bytes, got %d", got)
+	}
+}
+
+func TestParseUntilBoundIncludesTheWholeDay(t *testing.T) {
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	until, err := ParseUntilBound("2026-03-08", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evening := time.Date(2026, 3, 8, 23, 30, 0, 0, time.Local)
+	next := time.Date(2026, 3, 9, 0, 0, 0, 0, time.Local)
+	if evening.After(until) || !next.After(until) {
+		t.Fatalf("expected %s to cover the whole local day, got %s", "2026-03-08", until)
+	}
+	got, err := ParseUntilBound("2026-03-08T12:00:00Z", now)
+	if err != nil || !got.Equal(time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected an exact time to stay exact, got %s %v", got, err)
+	}
+}
@@ -68,6 +68,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 		}
 	}
 
+	planID := common.NewPlanID("optimize")
 	errCount := 0
 
 	if opts.Apply {
@@ -84,7 +85,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 
 				entry := model.OperationLogEntry{
 					Timestamp: time.Now().UTC(),
-					PlanID:    "plan-optimize",
+					PlanID:    planID,
 					Command:   "optimize",
 					Action:    "exec",
 					Path:      items[i].Path,
@@ -149,6 +150,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "optimize",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DryRun:        app.Options.DryRun,
 		Summary: model.Summary{
@@ -182,25 +184,49 @@ func adapterForRuleID(ruleID string, adapters []optimizeAdapter) (optimizeAdapte
 	return optimizeAdapter{}, false
 }
 
+func ResolveTrustedExecutable(name string) (string, error) {
+	return resolveTrustedExecutable(name)
+}
+
+func RunTrustedExecutable(ctx context.Context, name string, args ...string) error {
+	return runCommand(ctx, name, args...)
+}
+
+func OutputTrustedExecutable(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd, cancel, err := trustedCommand(ctx, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	return cmd.Output()
+}
+
 func runCommand(ctx context.Context, name string, args ...string) error {
-	abs, err := absPath(name)
+	cmd, cancel, err := trustedCommand(ctx, name, args...)
 	if err != nil {
 		return err
 	}
+	defer cancel()
+	return cmd.Run()
+}
+
+func trustedCommand(ctx context.Context, name string, args ...string) (*exec.Cmd, context.CancelFunc, error) {
+	abs, err := absPath(name)
+	if err != nil {
+		return nil, nil, err
+	}
 	resolved, err := evalSymlinks(abs)
 	if err != nil {
-		return fmt.Errorf("cannot resolve executable symlink: %w", err)
+		return nil, nil, fmt.Errorf("cannot resolve executable symlink: %w", err)
 	}
 	if !isTrustedExecutablePath(resolved) {
-		return fmt.Errorf("untrusted executable path: %s", resolved)
+		return nil, nil, fmt.Errorf("untrusted executable path: %s", resolved)
 	}
 	if err := validateTrustedExecutableFile(resolved); err != nil {
-		return err
+		return nil, nil, err
 	}
 	cmdCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
-	defer cancel()
-	cmd := exec.CommandContext(cmdCtx, resolved, args...)
-	return cmd.Run()
+	return exec.CommandContext(cmdCtx, resolved, args...), cancel, nil
 }
 
 func resolveTrustedExecutable(name string) (string, error) {
@@ -68,6 +68,7 @@ func TestRunDryRunGoldenJSON(t *testing.T) {
 func normalizeOptimizeResult(res *model.CommandResult) {
 	norm := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 	res.Timestamp = norm
+	res.PlanID = ""
 	for i := range res.Items {
 		res.Items[i].LastModified = norm
 	}
@@ -0,0 +1,102 @@
+package purge
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"talpa/internal/app/optimize"
+)
+
+type gitState int
+
+const (
+	gitUnknown gitState = iota
+	gitIgnored
+	gitUntracked
+	gitTracked
+	gitDirty
+)
+
+var (
+	resolveGit = optimize.ResolveTrustedExecutable
+	gitOutput  = optimize.OutputTrustedExecutable
+)
+
+func findGit() string {
+	git, err := resolveGit("git")
+	if err != nil {
+		return ""
+	}
+	return git
+}
+
+func inspectGit(ctx context.Context, git, path string) gitState {
+	if git == "" {
+		return gitUnknown
+	}
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	run := func(args ...string) ([]byte, error) { return runGit(ctx, git, dir, args...) }
+
+	tracked, err := run("ls-files", "-z", "--", name)
+	if err != nil {
+		return gitUnknown
+	}
+	if len(tracked) > 0 {
+		changed, err := run("status", "--porcelain", "-z", "--untracked-files=no", "--", name)
+		if err != nil || len(changed) > 0 {
+			return gitDirty
+		}
+		return gitTracked
+	}
+
+	status, err := run("status", "--porcelain", "-z", "--ignored", "--untracked-files=normal", "--", name)
+	if err != nil {
+		return gitUnknown
+	}
+	state := gitUnknown
+	for _, entry := range bytes.Split(status, []byte{0}) {
+		switch {
+		case bytes.HasPrefix(entry, []byte("?? ")):
+			return gitUntracked
+		case bytes.HasPrefix(entry, []byte("!! ")):
+			state = gitIgnored
+		}
+	}
+	return state
+}
+
+func lastCommit(ctx context.Context, git, dir string) time.Time {
+	if git == "" {
+		return time.Time{}
+	}
+	out, err := runGit(ctx, git, dir, "log", "-1", "--format=%ct", "--", ".")
+	if err != nil {
+		return time.Time{}
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0).UTC()
+}
+
+func runGit(ctx context.Context, git, dir string, args ...string) ([]byte, error) {
+	base := []string{"--no-optional-locks", "--literal-pathspecs", "-c", "core.fsmonitor=false", "-C", dir}
+	return gitOutput(ctx, git, append(base, args...)...)
+}
+
+func (s gitState) reason() string {
+	switch s {
+	case gitUntracked:
+		return "not ignored by git"
+	case gitTracked:
+		return "tracked by git"
+	case gitDirty:
+		return "uncommitted changes in git"
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/logging"
+)
+
+func TestInspectGitClassifiesStatusOutput(t *testing.T) {
+	savedOutput := gitOutput
+	t.Cleanup(func() { gitOutput = savedOutput })
+
+	cases := []struct {
+		name      string
+		lsFiles   string
+		changed   string
+		status    string
+		err       error
+		wantState gitState
+	}{
+		{name: "no repo", err: errors.New("exit status 128"), wantState: gitUnknown},
+		{name: "ignored", status: "!! node_modules/\x00", wantState: gitIgnored},
+		{name: "untracked", status: "!! dist/cache/\x00?? dist/app.js\x00", wantState: gitUntracked},
+		{name: "empty", wantState: gitUnknown},
+		{name: "tracked", lsFiles: "target/data.csv\x00", wantState: gitTracked},
+		{name: "dirty", lsFiles: "target/data.csv\x00", changed: " M target/data.csv\x00", wantState: gitDirty},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gitOutput = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				joined := strings.Join(args, " ")
+				if !strings.Contains(joined, "--literal-pathspecs") || !strings.Contains(joined, "-C /p/app") {
+					t.Fatalf("unexpected git args %q", joined)
+				}
+				switch {
+				case strings.Contains(joined, "ls-files"):
+					return []byte(tc.lsFiles), nil
+				case strings.Contains(joined, "--untracked-files=no "):
+					return []byte(tc.changed), nil
+				}
+				return []byte(tc.status), nil
+			}
+			if got := inspectGit(context.Background(), "/usr/bin/git", "/p/app/artifact"); got != tc.wantState {
+				t.Fatalf("inspectGit = %d, want %d", got, tc.wantState)
+			}
+		})
+	}
+	if got := inspectGit(context.Background(), "", "/p/app/artifact"); got != gitUnknown {
+		t.Fatalf("expected unknown state without git, got %d", got)
+	}
+}
+
+func TestRunRespectsGitStatus(t *testing.T) {
+	git := findGit()
+	if git == "" {
+		t.Skip("trusted git executable not available")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	repo := filepath.Join(home, "Projects", "app")
+	ignored := filepath.Join(repo, "node_modules")
+	tracked := filepath.Join(repo, "dist")
+	untracked := filepath.Join(repo, "build")
+	for _, f := range []string{
+		filepath.Join(repo, "package.json"),
+		filepath.Join(repo, ".gitignore"),
+		filepath.Join(ignored, "a.js"),
+		filepath.Join(tracked, "bundle.js"),
+		filepath.Join(untracked, "out.js"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		data := []byte("x")
+		if filepath.Base(f) == ".gitignore" {
+			data = []byte("node_modules/\n")
+		}
+		if err := os.WriteFile(f, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "package.json", ".gitignore", "dist"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command(git, append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	for _, dir := range []string{ignored, tracked, untracked} {
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, []string{filepath.Join(home, "Projects")}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]model.CandidateItem{}
+	for _, item := range res.Items {
+		byPath[item.Path] = item
+	}
+	if it := byPath[ignored]; !it.Selected || it.Risk != model.RiskLow || it.Reason != "" {
+		t.Fatalf("expected ignored node_modules to stay selected, got %+v", it)
+	}
+	if it := byPath[tracked]; it.Selected || it.Result != "skipped" || it.Reason != "tracked by git" {
+		t.Fatalf("expected tracked dist to be refused, got %+v", it)
+	}
+	if it := byPath[untracked]; !it.Selected || it.Risk != model.RiskMedium || it.Reason != "not ignored by git" {
+		t.Fatalf("expected untracked build to be flagged, got %+v", it)
+	}
+
+	m := res.Metrics.(ProjectMetrics)
+	if len(m.Projects) != 1 || m.Projects[0].Root != repo || m.Projects[0].VCS != "git" || m.Projects[0].LastCommit == nil {
+		t.Fatalf("expected one git project with a last commit, got %+v", m.Projects)
+	}
+
+	if err := os.WriteFile(filepath.Join(tracked, "bundle.js"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := inspectGit(context.Background(), git, tracked); got != gitDirty {
+		t.Fatalf("expected modified tracked dir to be dirty, got %d", got)
+	}
+}
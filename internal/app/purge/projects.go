@@ -0,0 +1,228 @@
+package purge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/rules"
+	"talpa/internal/infra/filesystem"
+)
+
+var (
+	vcsMarkers      = []string{".git", ".hg", ".svn"}
+	manifestMarkers = []string{"go.mod", "Gemfile", "composer.json", "mix.exs"}
+)
+
+type ProjectArtifact struct {
+	Path      string          `json:"path"`
+	RuleID    string          `json:"rule_id"`
+	SizeBytes int64           `json:"size_bytes"`
+	Risk      model.RiskLevel `json:"risk"`
+	Selected  bool            `json:"selected"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+type Project struct {
+	Rank             int               `json:"rank,omitempty"`
+	Root             string            `json:"root"`
+	VCS              string            `json:"vcs,omitempty"`
+	LastCommit       *time.Time        `json:"last_commit,omitempty"`
+	LastModified     time.Time         `json:"last_modified"`
+	IdleDays         int               `json:"idle_days"`
+	ReclaimableBytes int64             `json:"reclaimable_bytes"`
+	Artifacts        []ProjectArtifact `json:"artifacts"`
+}
+
+type ProjectMetrics struct {
+	OlderThanDays int       `json:"older_than_days,omitempty"`
+	Top           int       `json:"top,omitempty"`
+	Projects      []Project `json:"projects"`
+}
+
+type projectGroup struct {
+	project Project
+	items   []int
+	score   float64
+}
+
+func projectRoot(artifact, scanRoot string, markers []string) (string, string) {
+	stop := filepath.Clean(scanRoot)
+	for dir := filepath.Dir(artifact); dir == stop || strings.HasPrefix(dir, stop+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		for _, m := range vcsMarkers {
+			if _, err := os.Lstat(filepath.Join(dir, m)); err == nil {
+				return dir, strings.TrimPrefix(m, ".")
+			}
+		}
+		if rules.HasMarkerIn(dir, markers) {
+			return dir, ""
+		}
+		if dir == stop {
+			break
+		}
+	}
+	return filepath.Dir(artifact), ""
+}
+
+func projectMarkers(ruleSet []model.Rule) []string {
+	seen := map[string]bool{}
+	out := append([]string(nil), manifestMarkers...)
+	for _, m := range out {
+		seen[m] = true
+	}
+	for _, r := range ruleSet {
+		for _, m := range r.Markers {
+			if !seen[m] {
+				seen[m] = true
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+func groupProjects(ctx context.Context, git string, items []model.CandidateItem, usages []filesystem.Usage, roots, vcs []string, opts Options, now time.Time) ProjectMetrics {
+	index := map[string]*projectGroup{}
+	var groups []*projectGroup
+	for i := range items {
+		g, ok := index[roots[i]]
+		if !ok {
+			g = &projectGroup{project: Project{Root: roots[i], VCS: vcs[i]}}
+			index[roots[i]] = g
+			groups = append(groups, g)
+		}
+		g.items = append(g.items, i)
+	}
+
+	for _, g := range groups {
+		p := &g.project
+		artifacts := map[string]bool{}
+		for _, i := range g.items {
+			artifacts[items[i].Path] = true
+		}
+		p.LastModified = newestEntry(p.Root, artifacts)
+		if p.VCS == "git" || p.VCS == "" {
+			if t := lastCommit(ctx, git, p.Root); !t.IsZero() {
+				p.LastCommit = &t
+				if p.VCS == "" {
+					p.VCS = "git"
+				}
+			}
+		}
+		active := p.LastModified
+		if p.LastCommit != nil && p.LastCommit.After(active) {
+			active = *p.LastCommit
+		}
+		if !active.IsZero() && now.After(active) {
+			p.IdleDays = int(now.Sub(active) / (24 * time.Hour))
+		}
+		if opts.OlderThanDays > 0 && p.IdleDays < opts.OlderThanDays {
+			for _, i := range g.items {
+				if items[i].Selected {
+					items[i].Selected = false
+					items[i].Reason = fmt.Sprintf("project active %dd ago", p.IdleDays)
+					items[i].Result = "skipped"
+				}
+			}
+		}
+		g.score = float64(selectedBytes(items, usages, g.items, opts.SizeMode)) * float64(p.IdleDays+1)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].score != groups[j].score {
+			return groups[i].score > groups[j].score
+		}
+		return groups[i].project.Root < groups[j].project.Root
+	})
+	rank := 0
+	for _, g := range groups {
+		if g.score <= 0 {
+			continue
+		}
+		rank++
+		if opts.Top > 0 && rank > opts.Top {
+			for _, i := range g.items {
+				if items[i].Selected {
+					items[i].Selected = false
+					items[i].Reason = fmt.Sprintf("not in --top %d", opts.Top)
+					items[i].Result = "skipped"
+				}
+			}
+			continue
+		}
+		g.project.Rank = rank
+	}
+
+	metrics := ProjectMetrics{OlderThanDays: opts.OlderThanDays, Top: opts.Top, Projects: make([]Project, 0, len(groups))}
+	for _, g := range groups {
+		p := g.project
+		p.ReclaimableBytes = selectedBytes(items, usages, g.items, opts.SizeMode)
+		p.Artifacts = make([]ProjectArtifact, 0, len(g.items))
+		for _, i := range g.items {
+			it := items[i]
+			p.Artifacts = append(p.Artifacts, ProjectArtifact{Path: it.Path, RuleID: it.RuleID, SizeBytes: it.SizeBytes, Risk: it.Risk, Selected: it.Selected, Reason: it.Reason})
+		}
+		sort.SliceStable(p.Artifacts, func(i, j int) bool { return p.Artifacts[i].SizeBytes > p.Artifacts[j].SizeBytes })
+		metrics.Projects = append(metrics.Projects, p)
+	}
+	sort.SliceStable(metrics.Projects, func(i, j int) bool {
+		a, b := metrics.Projects[i], metrics.Projects[j]
+		if (a.Rank > 0) != (b.Rank > 0) {
+			return a.Rank > 0
+		}
+		if a.Rank > 0 {
+			return a.Rank < b.Rank
+		}
+		if a.ReclaimableBytes != b.ReclaimableBytes {
+			return a.ReclaimableBytes > b.ReclaimableBytes
+		}
+		return a.Root < b.Root
+	})
+	return metrics
+}
+
+func selectedBytes(items []model.CandidateItem, usages []filesystem.Usage, idx []int, sizeMode string) int64 {
+	var selected []filesystem.Usage
+	for _, i := range idx {
+		if items[i].Selected {
+			selected = append(selected, usages[i])
+		}
+	}
+	return filesystem.Reclaimable(selected).Bytes(sizeMode)
+}
+
+func newestEntry(root string, skip map[string]bool) time.Time {
+	var newest time.Time
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return newest
+	}
+	for _, e := range entries {
+		path := filepath.Join(root, e.Name())
+		if skip[path] || isVCSDir(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime().UTC()
+		}
+	}
+	return newest
+}
+
+func isVCSDir(name string) bool {
+	for _, m := range vcsMarkers {
+		if name == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package purge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/infra/logging"
+)
+
+func newProject(t *testing.T, dir string, idle time.Duration, artifactBytes int) string {
+	t.Helper()
+	artifact := filepath.Join(dir, "node_modules")
+	if err := os.MkdirAll(artifact, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(artifact, "blob.js"), make([]byte, artifactBytes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeMarker(t, artifact, "package.json")
+	at := time.Now().Add(-idle)
+	for _, p := range []string{filepath.Join(dir, "package.json"), artifact} {
+		if err := os.Chtimes(p, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return artifact
+}
+
+func TestRunGroupsArtifactsByProjectAndRanksStaleLarge(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	projects := filepath.Join(home, "Projects")
+	day := 24 * time.Hour
+
+	stale := newProject(t, filepath.Join(projects, "stale"), 200*day, 1000)
+	huge := newProject(t, filepath.Join(projects, "huge"), 100*day, 4000)
+	fresh := newProject(t, filepath.Join(projects, "fresh"), 10*day, 9000)
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, []string{projects}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := res.Metrics.(ProjectMetrics)
+	if !ok || len(m.Projects) != 3 {
+		t.Fatalf("expected three projects, got %+v", res.Metrics)
+	}
+	order := []string{m.Projects[0].Root, m.Projects[1].Root, m.Projects[2].Root}
+	want := []string{filepath.Dir(huge), filepath.Dir(stale), filepath.Dir(fresh)}
+	for i := range want {
+		if order[i] != want[i] || m.Projects[i].Rank != i+1 {
+			t.Fatalf("unexpected ranking %v, want %v", order, want)
+		}
+	}
+	if p := m.Projects[1]; p.IdleDays != 200 || p.ReclaimableBytes != 1000 || len(p.Artifacts) != 1 || p.Artifacts[0].Path != stale {
+		t.Fatalf("unexpected project %+v", p)
+	}
+
+	res, err = NewService().Run(context.Background(), app, []string{projects}, Options{OlderThanDays: 90, Top: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := map[string]string{}
+	for _, item := range res.Items {
+		if item.Selected {
+			reasons[item.Path] = "selected"
+			continue
+		}
+		if item.Result != "skipped" {
+			t.Fatalf("expected deselected %s to be skipped, got %q", item.Path, item.Result)
+		}
+		reasons[item.Path] = item.Reason
+	}
+	if reasons[huge] != "selected" || reasons[stale] != "not in --top 1" || reasons[fresh] != "project active 10d ago" {
+		t.Fatalf("unexpected selection %v", reasons)
+	}
+	if res.Summary.ItemsSelected != 1 || res.Summary.EstimatedFreedBytes != 4000 {
+		t.Fatalf("unexpected summary %+v", res.Summary)
+	}
+	m = res.Metrics.(ProjectMetrics)
+	if m.Projects[0].Rank != 1 || m.Projects[1].Rank != 0 || m.Projects[1].ReclaimableBytes != 0 {
+		t.Fatalf("expected only the top project to keep a rank, got %+v", m.Projects)
+	}
+}
+
+func TestProjectRootFindsNearestMarker(t *testing.T) {
+	root := t.TempDir()
+	pkg := filepath.Join(root, "mono", "packages", "web")
+	loose := filepath.Join(root, "loose", "scratch")
+	for _, dir := range []string{filepath.Join(root, "mono", ".git"), pkg, loose} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "package.json"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	markers := projectMarkers(nil)
+
+	if got, vcs := projectRoot(filepath.Join(pkg, "node_modules"), root, append(markers, "package.json")); got != pkg || vcs != "" {
+		t.Fatalf("expected package root, got %s %q", got, vcs)
+	}
+	if got, vcs := projectRoot(filepath.Join(root, "mono", "tools", "build"), root, markers); got != filepath.Join(root, "mono") || vcs != "git" {
+		t.Fatalf("expected repository root, got %s %q", got, vcs)
+	}
+	if got, _ := projectRoot(filepath.Join(loose, "dist"), root, markers); got != loose {
+		t.Fatalf("expected fallback to the artifact parent, got %s", got)
+	}
+}
@@ -3,6 +3,7 @@ package purge
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,19 +13,26 @@ import (
 	"talpa/internal/domain/model"
 	"talpa/internal/domain/rules"
 	"talpa/internal/domain/safety"
+	"talpa/internal/infra/filesystem"
 )
 
 type Service struct{}
 
 type Options struct {
-	MaxDepth   int
-	RecentDays int
+	MaxDepth      int
+	RecentDays    int
+	OlderThanDays int
+	Top           int
+	SizeMode      string
 }
 
 func NewService() Service { return Service{} }
 
 func (Service) Run(ctx context.Context, app *common.AppContext, paths []string, opts Options) (model.CommandResult, error) {
 	start := time.Now()
+	if len(paths) == 0 {
+		paths = app.Config.PurgeRoots
+	}
 	if len(paths) == 0 {
 		paths = defaultPaths()
 	}
@@ -35,16 +43,23 @@ func (Service) Run(ctx context.Context, app *common.AppContext, paths []string,
 		opts.RecentDays = 7
 	}
 
-	ruleSet := rules.PurgeArtifactRules()
+	ruleSet := rules.ApplyProfile(app.Profile, rules.PurgeRules(app.Rules))
 	ruleByName := make(map[string]model.Rule, len(ruleSet))
+	globRules := make([]model.Rule, 0, len(ruleSet))
 	for _, r := range ruleSet {
+		if rules.HasGlob(r.Pattern) {
+			globRules = append(globRules, r)
+			continue
+		}
 		ruleByName[r.Pattern] = r
 	}
 
 	home, _ := os.UserHomeDir()
+	git := findGit()
+	markers := projectMarkers(ruleSet)
 	items := make([]model.CandidateItem, 0, 64)
-	selected := 0
-	var estimate int64
+	var usages []filesystem.Usage
+	var projectRoots, projectVCS []string
 	errCount := 0
 	seenPaths := make(map[string]struct{}, 64)
 
@@ -69,11 +84,15 @@ func (Service) Run(ctx context.Context, app *common.AppContext, paths []string,
 
 			name := d.Name()
 			rule, ok := ruleByName[name]
+			if !ok {
+				rule, ok = matchGlobRule(globRules, name)
+			}
 			if !ok {
 				return nil
 			}
 
-			size := dirSize(path)
+			usage := filesystem.PathUsage(path)
+			size := usage.Bytes(opts.SizeMode)
 			recent := isRecent(path, opts.RecentDays)
 			modified := time.Now().UTC()
 			if st, statErr := os.Stat(path); statErr == nil {
@@ -87,9 +106,29 @@ func (Service) Run(ctx context.Context, app *common.AppContext, paths []string,
 				LastModified: modified,
 				Category:     rule.Category,
 				Risk:         rule.Risk,
-				Selected:     !recent,
+				Selected:     !recent && rules.MeetsThresholds(rule, size, modified, start),
 				RequiresRoot: rule.RequiresRoot,
 				Result:       "planned",
+				SharedLinks:  usage.SharedLinks,
+			}
+			if !rules.HasProjectMarker(rule, path) {
+				item.Risk = model.RiskHigh
+				item.Selected = false
+				item.Result = "skipped"
+				item.Reason = "no project marker"
+			} else {
+				switch state := inspectGit(ctx, git, path); state {
+				case gitTracked, gitDirty:
+					item.Risk = model.RiskHigh
+					item.Selected = false
+					item.Result = "skipped"
+					item.Reason = state.reason()
+				case gitUntracked:
+					if item.Risk == model.RiskLow {
+						item.Risk = model.RiskMedium
+					}
+					item.Reason = state.reason()
+				}
 			}
 			if _, exists := seenPaths[item.Path]; exists {
 				item.Selected = false
@@ -103,12 +142,14 @@ func (Service) Run(ctx context.Context, app *common.AppContext, paths []string,
 			}
 
 			if item.Selected {
-				selected++
-				estimate += size
 				seenPaths[item.Path] = struct{}{}
 			}
 
+			projRoot, vcs := projectRoot(path, root, markers)
 			items = append(items, item)
+			usages = append(usages, usage)
+			projectRoots = append(projectRoots, projRoot)
+			projectVCS = append(projectVCS, vcs)
 			return filepath.SkipDir
 		})
 		if errors.Is(ctx.Err(), context.Canceled) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
@@ -117,6 +158,29 @@ func (Service) Run(ctx context.Context, app *common.AppContext, paths []string,
 		}
 	}
 
+	projects := groupProjects(ctx, git, items, usages, projectRoots, projectVCS, opts, start)
+	selected := 0
+	var selectedUsage []filesystem.Usage
+	for i, item := range items {
+		if item.Selected {
+			selected++
+			selectedUsage = append(selectedUsage, usages[i])
+		}
+	}
+
+	planID := common.NewPlanID("purge")
+	if app.Options.DryRun {
+		planItems := make([]model.PlanItem, 0, len(items))
+		for _, item := range items {
+			planItems = append(planItems, common.NewPlanItem(item, "delete", []string{home}))
+		}
+		plan := common.NewPlan(planID, "purge", planItems)
+		plan.SizeMode = opts.SizeMode
+		if err := common.SavePlan(app, plan); err != nil {
+			return model.CommandResult{}, err
+		}
+	}
+
 	if !app.Options.DryRun {
 		if !app.Options.Yes {
 			return model.CommandResult{}, errors.New("confirmation required for destructive action: use --yes or --dry-run")
@@ -136,7 +200,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, paths []string,
 
 			if err := app.Logger.Log(ctx, model.OperationLogEntry{
 				Timestamp: time.Now().UTC(),
-				PlanID:    "plan-purge",
+				PlanID:    planID,
 				Command:   "purge",
 				Action:    "delete",
 				Path:      items[i].Path,
@@ -152,22 +216,56 @@ func (Service) Run(ctx context.Context, app *common.AppContext, paths []string,
 		}
 	}
 
+	estimate := filesystem.Reclaimable(selectedUsage)
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "purge",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DurationMS:    time.Since(start).Milliseconds(),
 		DryRun:        app.Options.DryRun,
 		Summary: model.Summary{
-			ItemsTotal:          len(items),
-			ItemsSelected:       selected,
-			EstimatedFreedBytes: estimate,
-			Errors:              errCount,
+			ItemsTotal:                  len(items),
+			ItemsSelected:               selected,
+			EstimatedFreedBytes:         estimate.Bytes(opts.SizeMode),
+			SizeMode:                    opts.SizeMode,
+			EstimatedFreedApparentBytes: estimate.ApparentBytes,
+			EstimatedFreedDiskBytes:     estimate.AllocatedBytes,
+			Errors:                      errCount,
 		},
-		Items: items,
+		Items:   items,
+		Metrics: projects,
 	}, nil
 }
 
+func (Service) Apply(ctx context.Context, app *common.AppContext, plan model.Plan) (model.CommandResult, error) {
+	if err := common.RequirePlanCommand(plan, "purge"); err != nil {
+		return model.CommandResult{}, err
+	}
+	if err := common.RequireConfirmationOrDryRun(app.Options, "purge apply"); err != nil {
+		return model.CommandResult{}, err
+	}
+	git := findGit()
+	return common.ExecutePlan(ctx, app, plan, common.UsageSizeOf(plan.SizeMode), func(item model.PlanItem) (string, model.TrashedEntry, error) {
+		if state := inspectGit(ctx, git, item.Path); state == gitTracked || state == gitDirty {
+			return "error", model.TrashedEntry{}, fmt.Errorf("refusing to delete %s: %s", item.Path, state.reason())
+		}
+		if err := safety.SafeDeleteWithIdentity(item.Path, item.AllowedRoots, app.Whitelist, false, item.Device, item.Inode); err != nil {
+			return "error", model.TrashedEntry{}, err
+		}
+		return "deleted", model.TrashedEntry{}, nil
+	}), nil
+}
+
+func matchGlobRule(globRules []model.Rule, name string) (model.Rule, bool) {
+	for _, r := range globRules {
+		if ok, _ := filepath.Match(r.Pattern, name); ok {
+			return r, true
+		}
+	}
+	return model.Rule{}, false
+}
+
 func defaultPaths() []string {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -211,17 +309,3 @@ func depth(root, path string) int {
 	}
 	return len(strings.Split(rel, string(filepath.Separator)))
 }
-
-func dirSize(path string) int64 {
-	var size int64
-	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-		if err != nil || info == nil {
-			return nil
-		}
-		if !info.IsDir() {
-			size += info.Size()
-		}
-		return nil
-	})
-	return size
-}
@@ -26,6 +26,7 @@ func TestRunDryRunGoldenJSON(t *testing.T) {
 	if err := os.WriteFile(filepath.Join(artifactDir, "index.js"), []byte("var a = 1;"), 0o644); err != nil {
 		t.Fatal(err)
 	}
+	writeMarker(t, artifactDir, "package.json")
 
 	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 	if err := os.Chtimes(artifactDir, old, old); err != nil {
@@ -59,10 +60,22 @@ func TestRunDryRunGoldenJSON(t *testing.T) {
 func normalizePurgeResult(res *model.CommandResult, home string) {
 	norm := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 	res.Timestamp = norm
+	res.PlanID = ""
 	res.DurationMS = 0
+	res.Summary.EstimatedFreedDiskBytes = 0
 	for i := range res.Items {
 		res.Items[i].ID = "purge-" + strconv.Itoa(i+1)
 		res.Items[i].Path = strings.ReplaceAll(res.Items[i].Path, home, "$HOME")
 		res.Items[i].LastModified = norm
 	}
+	if m, ok := res.Metrics.(ProjectMetrics); ok {
+		for i := range m.Projects {
+			p := &m.Projects[i]
+			p.Root = strings.ReplaceAll(p.Root, home, "$HOME")
+			p.LastModified = norm
+			for j := range p.Artifacts {
+				p.Artifacts[j].Path = strings.ReplaceAll(p.Artifacts[j].Path, home, "$HOME")
+			}
+		}
+	}
 }
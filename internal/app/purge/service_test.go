@@ -8,9 +8,17 @@ import (
 	"time"
 
 	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
 	"talpa/internal/infra/logging"
 )
 
+func writeMarker(t *testing.T, artifact, name string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(filepath.Dir(artifact), name), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestRunDryRunDoesNotDelete(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
@@ -88,6 +96,8 @@ func TestRunRespectsRecentDaysSelection(t *testing.T) {
 	if err := os.WriteFile(filepath.Join(recentArtifact, "b.js"), []byte("b"), 0o644); err != nil {
 		t.Fatal(err)
 	}
+	writeMarker(t, oldArtifact, "package.json")
+	writeMarker(t, recentArtifact, "package.json")
 
 	old := time.Now().Add(-10 * 24 * time.Hour)
 	if err := os.Chtimes(oldArtifact, old, old); err != nil {
@@ -111,3 +121,106 @@ func TestRunRespectsRecentDaysSelection(t *testing.T) {
 		t.Fatalf("expected recent artifact to be skipped by default")
 	}
 }
+
+func TestRunMatchesPluginGlobRules(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	artifact := filepath.Join(home, "Projects", "infra", ".terraform.d")
+	if err := os.MkdirAll(artifact, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(artifact, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &common.AppContext{
+		Options: common.GlobalOptions{DryRun: true},
+		Logger:  logging.NewNoopLogger(),
+		Rules:   []model.Rule{{ID: "purge.terraform", Command: "purge", Category: "project_artifact", Pattern: ".terraform*", Risk: model.RiskLow}},
+	}
+	res, err := NewService().Run(context.Background(), app, []string{filepath.Join(home, "Projects")}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].RuleID != "purge.terraform" || !res.Items[0].Selected {
+		t.Fatalf("expected plugin glob rule to match, got %+v", res.Items)
+	}
+}
+
+func TestRunSizeModeDiskReportsAllocatedBytes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	artifact := filepath.Join(home, "Projects", "app", "node_modules")
+	if err := os.MkdirAll(artifact, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(artifact, "index.js"), []byte("var a = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeMarker(t, artifact, "package.json")
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(artifact, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, []string{filepath.Join(home, "Projects")}, Options{SizeMode: "disk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || !res.Items[0].Selected {
+		t.Fatalf("expected one selected artifact, got %+v", res.Items)
+	}
+	s := res.Summary
+	if s.SizeMode != "disk" || s.EstimatedFreedApparentBytes != 10 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if s.EstimatedFreedBytes != s.EstimatedFreedDiskBytes || res.Items[0].SizeBytes != s.EstimatedFreedDiskBytes {
+		t.Fatalf("disk mode should report allocated bytes: item=%d summary=%+v", res.Items[0].SizeBytes, s)
+	}
+}
+
+func TestRunDowngradesArtifactsWithoutProjectMarker(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	projects := filepath.Join(home, "Projects")
+	crate := filepath.Join(projects, "crate", "target")
+	docsBuild := filepath.Join(projects, "site", "docs", "build")
+	data := filepath.Join(projects, "survey", "target")
+	for _, dir := range []string{crate, docsBuild, data} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-30 * 24 * time.Hour)
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeMarker(t, crate, "Cargo.toml")
+	writeMarker(t, filepath.Join(projects, "site", "docs"), "package.json")
+
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, []string{projects}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]model.CandidateItem{}
+	for _, item := range res.Items {
+		byPath[item.Path] = item
+	}
+	if it := byPath[crate]; !it.Selected || it.Risk != model.RiskLow || it.Result != "planned" {
+		t.Fatalf("expected marked target to be selected, got %+v", it)
+	}
+	for _, path := range []string{docsBuild, data} {
+		if it := byPath[path]; it.Selected || it.Risk != model.RiskHigh || it.Result != "skipped" {
+			t.Fatalf("expected unmarked %s to be skipped as high risk, got %+v", path, it)
+		}
+	}
+	if res.Summary.ItemsSelected != 1 {
+		t.Fatalf("unexpected summary %+v", res.Summary)
+	}
+}
@@ -27,6 +27,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext) (model.CommandRe
 		return model.CommandResult{}, err
 	}
 	target := exe
+	planID := common.NewPlanID("remove")
 	errCount := 0
 	item := model.CandidateItem{
 		ID:           "remove-1",
@@ -54,7 +55,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext) (model.CommandRe
 		}
 		if err := app.Logger.Log(ctx, model.OperationLogEntry{
 			Timestamp: time.Now().UTC(),
-			PlanID:    "plan-remove",
+			PlanID:    planID,
 			Command:   "remove",
 			Action:    "delete",
 			Path:      target,
@@ -79,6 +80,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext) (model.CommandRe
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "remove",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DurationMS:    0,
 		DryRun:        app.Options.DryRun,
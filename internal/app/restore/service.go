@@ -0,0 +1,187 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"talpa/internal/app/analyze"
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/logging"
+)
+
+type Service struct{}
+
+type Options struct {
+	PlanID   string
+	PathGlob string
+}
+
+var (
+	operationLogPath = logging.OperationLogPath
+	readOperationLog = logging.ReadAllOperationLogs
+	restoreFromTrash = analyze.RestoreFromTrash
+)
+
+func NewService() Service { return Service{} }
+
+func resolveLogPath(app *common.AppContext) (string, error) {
+	if app.Config.OplogPath != "" {
+		return app.Config.OplogPath, nil
+	}
+	return operationLogPath()
+}
+
+func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (model.CommandResult, error) {
+	start := time.Now()
+	opts.PlanID = strings.TrimSpace(opts.PlanID)
+	opts.PathGlob = strings.TrimSpace(opts.PathGlob)
+	if opts.PlanID == "" && opts.PathGlob == "" {
+		return model.CommandResult{}, errors.New("restore requires --plan or --path")
+	}
+
+	logPath, err := resolveLogPath(app)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	entries, err := readOperationLog(ctx, logPath)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	trashed := restorableEntries(entries, opts)
+
+	items := make([]model.CandidateItem, 0, len(trashed))
+	selected := 0
+	var estimate int64
+	for i, e := range trashed {
+		item := model.CandidateItem{
+			ID:           "restore-" + strconv.Itoa(i+1),
+			RuleID:       e.RuleID,
+			Path:         e.Path,
+			SizeBytes:    e.SizeBytes,
+			LastModified: e.Timestamp,
+			Category:     e.Category,
+			Risk:         model.RiskLevel(e.Risk),
+			Selected:     true,
+			Result:       "planned",
+		}
+		if _, err := os.Lstat(e.TrashPath); err != nil {
+			item.Selected = false
+			item.Result = "skipped"
+		} else if _, err := os.Lstat(e.Path); err == nil {
+			item.Selected = false
+			item.Result = "skipped"
+		}
+		if item.Selected {
+			selected++
+			estimate += item.SizeBytes
+		}
+		items = append(items, item)
+	}
+
+	planID := common.NewPlanID("restore")
+	errCount := 0
+	if !app.Options.DryRun && selected > 0 {
+		if err := common.RequireConfirmationOrDryRun(app.Options, "restore"); err != nil {
+			return model.CommandResult{}, err
+		}
+		for i := range items {
+			if !items[i].Selected {
+				continue
+			}
+			entry := model.OperationLogEntry{
+				Timestamp: time.Now().UTC(),
+				PlanID:    planID,
+				Command:   "restore",
+				Action:    "restore",
+				Path:      items[i].Path,
+				TrashPath: trashed[i].TrashPath,
+				RuleID:    items[i].RuleID,
+				Category:  items[i].Category,
+				SizeBytes: items[i].SizeBytes,
+				Risk:      string(items[i].Risk),
+				DryRun:    false,
+			}
+			err := restoreFromTrash(trashed[i].TrashPath, items[i].Path, app.Whitelist, trashed[i].TrashDevice, trashed[i].TrashInode)
+			switch {
+			case err == nil:
+				items[i].Result = "restored"
+			case errors.Is(err, analyze.ErrRestoreConflict):
+				items[i].Result = "skipped"
+			default:
+				items[i].Result = "error"
+				errCount++
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			entry.Result = items[i].Result
+			if err := app.Logger.Log(ctx, entry); err != nil {
+				errCount++
+			}
+		}
+	}
+
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "restore",
+		PlanID:        planID,
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		DryRun:        app.Options.DryRun,
+		Summary: model.Summary{
+			ItemsTotal:          len(items),
+			ItemsSelected:       selected,
+			EstimatedFreedBytes: 0,
+			Errors:              errCount,
+		},
+		Items: items,
+	}, nil
+}
+
+func restorableEntries(entries []model.OperationLogEntry, opts Options) []model.OperationLogEntry {
+	pending := make(map[string]model.OperationLogEntry)
+	for _, e := range entries {
+		if e.TrashPath == "" {
+			continue
+		}
+		switch {
+		case e.Action == "trash" && e.Result == "trashed":
+			pending[e.TrashPath] = e
+		case e.Action == "restore" && e.Result == "restored":
+			delete(pending, e.TrashPath)
+		}
+	}
+	out := make([]model.OperationLogEntry, 0, len(pending))
+	for _, e := range pending {
+		if opts.PlanID != "" && e.PlanID != opts.PlanID {
+			continue
+		}
+		if opts.PathGlob != "" && !matchPathGlob(opts.PathGlob, e.Path) {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Timestamp.Equal(out[j].Timestamp) {
+			return out[i].Timestamp.After(out[j].Timestamp)
+		}
+		return out[i].TrashPath < out[j].TrashPath
+	})
+	return out
+}
+
+func matchPathGlob(pattern, path string) bool {
+	pattern = filepath.Clean(pattern)
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := filepath.Match(pattern, path)
+		return ok
+	}
+	return path == pattern || strings.HasPrefix(path, pattern+string(filepath.Separator))
+}
@@ -0,0 +1,185 @@
+package restore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"talpa/internal/app/analyze"
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/logging"
+)
+
+func TestRunRestoresTrashedItemsByPlan(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+
+	target := filepath.Join(home, "project", "cache", "a.tmp")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := logging.NewOperationLoggerWithOptions(context.Background(), false, logging.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &common.AppContext{Options: common.GlobalOptions{Yes: true}, Logger: logger}
+	trashed, err := analyze.NewService().Run(context.Background(), app, filepath.Join(home, "project"), analyze.Options{Depth: 4, Limit: 10, SortBy: "size", Action: "trash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected target to be trashed")
+	}
+
+	res, err := NewService().Run(context.Background(), app, Options{PlanID: trashed.PlanID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Result != "restored" {
+		t.Fatalf("expected one restored item, got %+v", res.Items)
+	}
+	b, err := os.ReadFile(target)
+	if err != nil || string(b) != "payload" {
+		t.Fatalf("expected restored payload, got %q (%v)", b, err)
+	}
+
+	again, err := NewService().Run(context.Background(), app, Options{PlanID: trashed.PlanID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Items) != 0 {
+		t.Fatalf("expected restored entries to be excluded on second run, got %d", len(again.Items))
+	}
+}
+
+func TestRunRefusesTrashEntryReplacedSinceTrashing(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+
+	target := filepath.Join(home, "project", "cache", "a.tmp")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	logger, err := logging.NewOperationLoggerWithOptions(context.Background(), false, logging.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &common.AppContext{Options: common.GlobalOptions{Yes: true}, Logger: logger}
+	trashed, err := analyze.NewService().Run(context.Background(), app, filepath.Join(home, "project"), analyze.Options{Depth: 4, Limit: 10, SortBy: "size", Action: "trash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPath, err := logging.OperationLogPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := logging.ReadOperationLog(context.Background(), logPath)
+	if err != nil || len(entries) != 1 || entries[0].TrashInode == 0 {
+		t.Fatalf("expected the trash step to log the trashed identity, got %+v (%v)", entries, err)
+	}
+
+	swap := filepath.Join(home, "swap")
+	if err := os.MkdirAll(swap, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(entries[0].TrashPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(swap, entries[0].TrashPath); err != nil {
+		t.Fatal(err)
+	}
+	res, err := NewService().Run(context.Background(), app, Options{PlanID: trashed.PlanID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Result != "error" || res.Summary.Errors != 1 {
+		t.Fatalf("expected the swapped entry to be refused, got %+v", res.Items)
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be restored, got %v", err)
+	}
+}
+
+func TestRunRefusesToOverwriteExistingPath(t *testing.T) {
+	home := t.TempDir()
+	original := filepath.Join(home, "cache")
+	trashedPath := filepath.Join(home, "trash", "files", "cache")
+	if err := os.MkdirAll(original, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(trashedPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	restoreCalled := false
+	withLog(t, []model.OperationLogEntry{{PlanID: "p1", Command: "analyze", Action: "trash", Path: original, TrashPath: trashedPath, Result: "trashed"}})
+	originalRestore := restoreFromTrash
+	restoreFromTrash = func(string, string, []string, uint64, uint64) error {
+		restoreCalled = true
+		return nil
+	}
+	t.Cleanup(func() { restoreFromTrash = originalRestore })
+
+	app := &common.AppContext{Options: common.GlobalOptions{Yes: true}, Logger: logging.NewNoopLogger()}
+	res, err := NewService().Run(context.Background(), app, Options{PlanID: "p1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoreCalled {
+		t.Fatalf("expected restore to be skipped when original path exists")
+	}
+	if len(res.Items) != 1 || res.Items[0].Result != "skipped" || res.Items[0].Selected {
+		t.Fatalf("expected skipped item, got %+v", res.Items)
+	}
+}
+
+func TestRunRequiresFilter(t *testing.T) {
+	app := &common.AppContext{Options: common.GlobalOptions{DryRun: true}, Logger: logging.NewNoopLogger()}
+	if _, err := NewService().Run(context.Background(), app, Options{}); err == nil {
+		t.Fatalf("expected error without --plan or --path")
+	}
+}
+
+func TestRestorableEntriesFiltersByGlobAndRestoredState(t *testing.T) {
+	now := time.Now().UTC()
+	entries := []model.OperationLogEntry{
+		{Timestamp: now, PlanID: "p1", Action: "trash", Result: "trashed", Path: "/home/u/a/cache", TrashPath: "/t/files/cache"},
+		{Timestamp: now, PlanID: "p1", Action: "trash", Result: "trashed", Path: "/home/u/b/log.txt", TrashPath: "/t/files/log.txt"},
+		{Timestamp: now, PlanID: "p1", Action: "trash", Result: "error", Path: "/home/u/c", TrashPath: "/t/files/c"},
+		{Timestamp: now, PlanID: "p2", Action: "restore", Result: "restored", Path: "/home/u/b/log.txt", TrashPath: "/t/files/log.txt"},
+	}
+	got := restorableEntries(entries, Options{PathGlob: "/home/u/*/cache"})
+	if len(got) != 1 || got[0].TrashPath != "/t/files/cache" {
+		t.Fatalf("unexpected glob match result: %+v", got)
+	}
+	got = restorableEntries(entries, Options{PathGlob: "/home/u"})
+	if len(got) != 1 {
+		t.Fatalf("expected prefix match to exclude restored and failed entries, got %+v", got)
+	}
+}
+
+func withLog(t *testing.T, entries []model.OperationLogEntry) {
+	t.Helper()
+	originalPath := operationLogPath
+	originalRead := readOperationLog
+	operationLogPath = func() (string, error) { return "operations.log", nil }
+	readOperationLog = func(context.Context, string) ([]model.OperationLogEntry, error) { return entries, nil }
+	t.Cleanup(func() {
+		operationLogPath = originalPath
+		readOperationLog = originalRead
+	})
+}
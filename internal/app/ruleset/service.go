@@ -0,0 +1,164 @@
+package ruleset
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/rules"
+	"talpa/internal/infra/config"
+)
+
+type Service struct{}
+
+type Catalog struct {
+	Profile string        `json:"profile,omitempty"`
+	Dirs    []string      `json:"dirs,omitempty"`
+	Files   []string      `json:"files"`
+	Rules   []RuleInfo    `json:"rules"`
+	Issues  []rules.Issue `json:"issues"`
+}
+
+type RuleInfo struct {
+	ID           string   `json:"id"`
+	Command      string   `json:"command"`
+	Category     string   `json:"category"`
+	Pattern      string   `json:"pattern"`
+	Risk         string   `json:"risk"`
+	RequiresRoot bool     `json:"requires_root"`
+	MinAge       string   `json:"min_age,omitempty"`
+	MinSizeBytes int64    `json:"min_size_bytes,omitempty"`
+	OlderThan    string   `json:"older_than,omitempty"`
+	KeepNewest   int      `json:"keep_newest,omitempty"`
+	EntryDepth   string   `json:"entry_depth,omitempty"`
+	Markers      []string `json:"markers,omitempty"`
+	Source       string   `json:"source"`
+	Enabled      bool     `json:"enabled"`
+}
+
+var (
+	osUserHomeDir = os.UserHomeDir
+	ruleDirs      = config.RuleDirs
+	ruleFiles     = config.RuleFiles
+	lookupEnv     = os.LookupEnv
+)
+
+func NewService() Service { return Service{} }
+
+func (Service) List(ctx context.Context, app *common.AppContext) (model.CommandResult, error) {
+	start := time.Now()
+	home, err := osUserHomeDir()
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	dirs := ruleDirs()
+	files, err := ruleFiles(ctx, dirs)
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	plugins, issues := rules.LoadPlugins(files, home, lookupEnv)
+
+	merged := rules.Merge(append(rules.CleanRules(home), rules.CleanSystemRules()...), plugins, "clean")
+	merged = append(merged, rules.PurgeRules(plugins)...)
+	catalog := newCatalog(dirs, files, merged, issues)
+	if app.Profile != nil {
+		catalog.Profile = app.Profile.Name
+		for i, r := range merged {
+			catalog.Rules[i].Enabled = rules.ProfileAllows(app.Profile, r)
+		}
+	}
+	return catalogResult("rules list", start, catalog), nil
+}
+
+func (Service) Validate(ctx context.Context, app *common.AppContext, paths []string) (model.CommandResult, error) {
+	_ = app
+	start := time.Now()
+	home, err := osUserHomeDir()
+	if err != nil {
+		return model.CommandResult{}, err
+	}
+	var dirs []string
+	var files []rules.PluginFile
+	if len(paths) == 0 {
+		dirs = ruleDirs()
+		files, err = ruleFiles(ctx, dirs)
+		if err != nil {
+			return model.CommandResult{}, err
+		}
+	} else {
+		for _, p := range paths {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return model.CommandResult{}, err
+			}
+			files = append(files, rules.PluginFile{Path: p, Data: data})
+		}
+	}
+	plugins, issues := rules.LoadPlugins(files, home, lookupEnv)
+	catalog := newCatalog(dirs, files, plugins, issues)
+	return catalogResult("rules validate", start, catalog), nil
+}
+
+func newCatalog(dirs []string, files []rules.PluginFile, ruleSet []model.Rule, issues []rules.Issue) Catalog {
+	c := Catalog{
+		Dirs:   dirs,
+		Files:  make([]string, 0, len(files)),
+		Rules:  make([]RuleInfo, 0, len(ruleSet)),
+		Issues: issues,
+	}
+	if c.Issues == nil {
+		c.Issues = []rules.Issue{}
+	}
+	for _, f := range files {
+		c.Files = append(c.Files, f.Path)
+	}
+	for _, r := range ruleSet {
+		info := RuleInfo{
+			ID:           r.ID,
+			Command:      r.Command,
+			Category:     r.Category,
+			Pattern:      r.Pattern,
+			Risk:         string(r.Risk),
+			RequiresRoot: r.RequiresRoot,
+			MinSizeBytes: r.MinSizeBytes,
+			KeepNewest:   r.KeepNewest,
+			Markers:      r.Markers,
+			Source:       r.Source,
+			Enabled:      true,
+		}
+		if r.MinAge > 0 {
+			info.MinAge = r.MinAge.String()
+		}
+		if r.OlderThan > 0 {
+			info.OlderThan = r.OlderThan.String()
+		}
+		switch {
+		case r.EntryDepth == rules.EntryDepthFiles:
+			info.EntryDepth = "files"
+		case r.EntryDepth > 0:
+			info.EntryDepth = strconv.Itoa(r.EntryDepth)
+		}
+		if info.Source == "" {
+			info.Source = rules.BuiltinSource
+		}
+		c.Rules = append(c.Rules, info)
+	}
+	return c
+}
+
+func catalogResult(command string, start time.Time, c Catalog) model.CommandResult {
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       command,
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		Summary: model.Summary{
+			ItemsTotal: len(c.Rules),
+			Errors:     len(c.Issues),
+		},
+		Metrics: c,
+	}
+}
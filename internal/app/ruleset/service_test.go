@@ -0,0 +1,73 @@
+package ruleset
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"talpa/internal/app/common"
+	"talpa/internal/infra/logging"
+)
+
+func writeRuleFile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestListMergesPluginRulesAndReportsIssues(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, "rules.d")
+	writeRuleFile(t, dir, "10-acme.yaml", "rules:\n  - {id: clean.acme, command: clean, category: acme, pattern: ~/.acme/cache, risk: low}\n")
+	writeRuleFile(t, dir, "20-bad.yaml", "rules:\n  - {id: clean.bad, command: clean, category: acme, pattern: /usr/share, risk: low}\n")
+	writeRuleFile(t, dir, "README.md", "not a rule file")
+
+	old := ruleDirs
+	ruleDirs = func() []string { return []string{dir} }
+	t.Cleanup(func() { ruleDirs = old })
+
+	app := &common.AppContext{Logger: logging.NewNoopLogger()}
+	res, err := NewService().List(context.Background(), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := res.Metrics.(Catalog)
+	if len(catalog.Files) != 2 || len(catalog.Issues) != 1 || res.Summary.Errors != 1 {
+		t.Fatalf("unexpected catalog: files=%v issues=%+v", catalog.Files, catalog.Issues)
+	}
+	found := false
+	for _, r := range catalog.Rules {
+		if r.ID == "clean.acme" {
+			found = r.Source == filepath.Join(dir, "10-acme.yaml") && r.Pattern == filepath.Join(home, ".acme", "cache")
+		}
+		if r.ID == "purge.node_modules" && r.Source != "builtin" {
+			t.Fatalf("expected builtin source, got %q", r.Source)
+		}
+	}
+	if !found {
+		t.Fatalf("expected plugin rule in merged list, got %+v", catalog.Rules)
+	}
+}
+
+func TestValidateExplicitFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	good := writeRuleFile(t, home, "good.json", `{"rules": [{"id": "purge.bazel", "command": "purge", "category": "build", "pattern": "bazel-*", "risk": "low"}]}`)
+
+	app := &common.AppContext{Logger: logging.NewNoopLogger()}
+	res, err := NewService().Validate(context.Background(), app, []string{good})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary.Errors != 0 || res.Summary.ItemsTotal != 1 {
+		t.Fatalf("unexpected validate summary: %+v", res.Summary)
+	}
+}
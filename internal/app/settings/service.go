@@ -0,0 +1,57 @@
+package settings
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"talpa/internal/app/common"
+	"talpa/internal/domain/model"
+	"talpa/internal/infra/config"
+)
+
+type Service struct{}
+
+type Options struct {
+	Effective bool
+}
+
+type Metrics struct {
+	Files    []ConfigFile     `json:"files"`
+	Settings []config.Setting `json:"settings"`
+}
+
+type ConfigFile struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+var configFiles = config.ConfigFiles
+
+func NewService() Service { return Service{} }
+
+func (Service) Show(ctx context.Context, app *common.AppContext, opts Options) (model.CommandResult, error) {
+	_ = ctx
+	start := time.Now()
+	metrics := Metrics{Settings: []config.Setting{}}
+	for _, path := range configFiles() {
+		_, err := os.Stat(path)
+		metrics.Files = append(metrics.Files, ConfigFile{Path: path, Exists: err == nil})
+	}
+	for _, s := range app.Config.Settings() {
+		if !opts.Effective && (s.Source == "" || s.Source == config.SourceDefault) {
+			continue
+		}
+		metrics.Settings = append(metrics.Settings, s)
+	}
+	return model.CommandResult{
+		SchemaVersion: "1.0",
+		Command:       "config show",
+		Timestamp:     time.Now().UTC(),
+		DurationMS:    time.Since(start).Milliseconds(),
+		Summary: model.Summary{
+			ItemsTotal: len(metrics.Settings),
+		},
+		Metrics: metrics,
+	}, nil
+}
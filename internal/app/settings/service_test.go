@@ -0,0 +1,44 @@
+package settings
+
+import (
+	"context"
+	"testing"
+
+	"talpa/internal/app/common"
+	"talpa/internal/infra/config"
+)
+
+func TestShowFiltersDefaultsUnlessEffective(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	origFiles := configFiles
+	configFiles = func() []string { return []string{"/nonexistent/talpa/config.yaml"} }
+	defer func() { configFiles = origFiles }()
+
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.OplogEnabled = false
+	cfg.SetFlag("oplog.enabled")
+	app := &common.AppContext{Config: cfg}
+
+	res, err := NewService().Show(context.Background(), app, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := res.Metrics.(Metrics)
+	if len(m.Settings) != 1 || m.Settings[0].Key != "oplog.enabled" || m.Settings[0].Source != config.SourceFlag {
+		t.Fatalf("expected only the flag-set value, got %+v", m.Settings)
+	}
+	if len(m.Files) != 1 || m.Files[0].Exists {
+		t.Fatalf("unexpected files: %+v", m.Files)
+	}
+
+	res, err = NewService().Show(context.Background(), app, Options{Effective: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(res.Metrics.(Metrics).Settings); got != len(cfg.Settings()) {
+		t.Fatalf("expected all %d settings, got %d", len(cfg.Settings()), got)
+	}
+}
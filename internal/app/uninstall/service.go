@@ -135,6 +135,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 		}
 	}
 
+	planID := common.NewPlanID("uninstall")
 	errCount := 0
 	if opts.Apply {
 		if err := common.RequireHighRiskConfirmationOrDryRun(app.Options, "uninstall"); err != nil {
@@ -151,7 +152,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 						}
 						entry := model.OperationLogEntry{
 							Timestamp: time.Now().UTC(),
-							PlanID:    "plan-uninstall",
+							PlanID:    planID,
 							Command:   "uninstall",
 							Action:    "skip",
 							Path:      items[i].Path,
@@ -167,7 +168,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 						}
 						continue
 					}
-					if err := common.LogApplySkip(ctx, app.Logger, "plan-uninstall", "uninstall", items[i]); err != nil {
+					if err := common.LogApplySkip(ctx, app.Logger, planID, "uninstall", items[i]); err != nil {
 						errCount++
 					}
 					continue
@@ -175,7 +176,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 				if strings.HasPrefix(items[i].RuleID, "uninstall.pkg.") {
 					entry := model.OperationLogEntry{
 						Timestamp: time.Now().UTC(),
-						PlanID:    "plan-uninstall",
+						PlanID:    planID,
 						Command:   "uninstall",
 						Action:    "exec",
 						Path:      items[i].Path,
@@ -254,7 +255,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 
 				entry := model.OperationLogEntry{
 					Timestamp: time.Now().UTC(),
-					PlanID:    "plan-uninstall",
+					PlanID:    planID,
 					Command:   "uninstall",
 					Action:    "delete",
 					Path:      items[i].Path,
@@ -316,6 +317,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext, opts Options) (m
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "uninstall",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DryRun:        app.Options.DryRun,
 		Summary: model.Summary{
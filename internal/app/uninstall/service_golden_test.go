@@ -140,6 +140,7 @@ func TestRunDryRunMissingArtifactsGoldenJSON(t *testing.T) {
 func normalizeUninstallResult(res *model.CommandResult, home string) {
 	norm := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 	res.Timestamp = norm
+	res.PlanID = ""
 	for i := range res.Items {
 		res.Items[i].Path = strings.ReplaceAll(res.Items[i].Path, home, "$HOME")
 		res.Items[i].LastModified = norm
@@ -41,6 +41,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext) (model.CommandRe
 		Result:       "planned",
 	}
 
+	planID := common.NewPlanID("update")
 	errCount := 0
 	if !app.Options.DryRun {
 		if err := common.RequireConfirmationOrDryRun(app.Options, "update"); err != nil {
@@ -65,7 +66,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext) (model.CommandRe
 
 		if err := app.Logger.Log(ctx, model.OperationLogEntry{
 			Timestamp: time.Now().UTC(),
-			PlanID:    "plan-update",
+			PlanID:    planID,
 			Command:   "update",
 			Action:    "exec",
 			Path:      target,
@@ -90,6 +91,7 @@ func (Service) Run(ctx context.Context, app *common.AppContext) (model.CommandRe
 	return model.CommandResult{
 		SchemaVersion: "1.0",
 		Command:       "update",
+		PlanID:        planID,
 		Timestamp:     time.Now().UTC(),
 		DurationMS:    0,
 		DryRun:        app.Options.DryRun,
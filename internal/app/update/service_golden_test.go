@@ -57,6 +57,7 @@ func TestRunDryRunGoldenJSON(t *testing.T) {
 func normalizeUpdateResult(res *model.CommandResult, home string) {
 	norm := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 	res.Timestamp = norm
+	res.PlanID = ""
 	res.DurationMS = 0
 	for i := range res.Items {
 		res.Items[i].Path = strings.ReplaceAll(res.Items[i].Path, home, "$HOME")
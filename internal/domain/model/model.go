@@ -17,6 +17,31 @@ type Rule struct {
 	Pattern      string
 	RequiresRoot bool
 	Risk         RiskLevel
+	MinAge       time.Duration
+	MinSizeBytes int64
+	OlderThan    time.Duration
+	KeepNewest   int
+	EntryDepth   int
+	Markers      []string
+	Source       string
+}
+
+type Profile struct {
+	Name              string          `json:"name"`
+	Description       string          `json:"description,omitempty"`
+	EnableRules       []string        `json:"enable_rules,omitempty"`
+	DisableRules      []string        `json:"disable_rules,omitempty"`
+	EnableCategories  []string        `json:"enable_categories,omitempty"`
+	DisableCategories []string        `json:"disable_categories,omitempty"`
+	Defaults          ProfileDefaults `json:"defaults"`
+	Source            string          `json:"source"`
+}
+
+type ProfileDefaults struct {
+	PurgePaths   []string `json:"purge_paths,omitempty"`
+	RecentDays   int      `json:"recent_days,omitempty"`
+	AnalyzeDepth int      `json:"analyze_depth,omitempty"`
+	System       *bool    `json:"system,omitempty"`
 }
 
 type CandidateItem struct {
@@ -30,6 +55,12 @@ type CandidateItem struct {
 	Selected     bool      `json:"selected"`
 	RequiresRoot bool      `json:"requires_root"`
 	Result       string    `json:"result"`
+	Kind         string    `json:"kind,omitempty"`
+	Depth        int       `json:"depth,omitempty"`
+	FileCount    int64     `json:"file_count,omitempty"`
+	SharedLinks  int64     `json:"shared_links,omitempty"`
+	DuplicateOf  string    `json:"duplicate_of,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
 }
 
 type Summary struct {
@@ -37,32 +68,85 @@ type Summary struct {
 	ItemsSelected       int   `json:"items_selected"`
 	EstimatedFreedBytes int64 `json:"estimated_freed_bytes"`
 	Errors              int   `json:"errors"`
+
+	SizeMode                    string `json:"size_mode,omitempty"`
+	EstimatedFreedApparentBytes int64  `json:"estimated_freed_apparent_bytes,omitempty"`
+	EstimatedFreedDiskBytes     int64  `json:"estimated_freed_disk_bytes,omitempty"`
 }
 
 type CommandResult struct {
 	SchemaVersion string          `json:"schema_version"`
 	Command       string          `json:"command"`
+	PlanID        string          `json:"plan_id,omitempty"`
 	Timestamp     time.Time       `json:"timestamp"`
 	DurationMS    int64           `json:"duration_ms"`
 	DryRun        bool            `json:"dry_run,omitempty"`
 	Summary       Summary         `json:"summary,omitempty"`
 	Items         []CandidateItem `json:"items,omitempty"`
 	Metrics       any             `json:"metrics,omitempty"`
+	Partial       bool            `json:"partial,omitempty"`
+	Unvisited     []string        `json:"unvisited,omitempty"`
+}
+
+type PlanItem struct {
+	CandidateItem
+	Action       string   `json:"action"`
+	Device       uint64   `json:"device"`
+	Inode        uint64   `json:"inode"`
+	AllowedRoots []string `json:"allowed_roots,omitempty"`
+}
+
+type Plan struct {
+	SchemaVersion string     `json:"schema_version"`
+	PlanID        string     `json:"plan_id"`
+	Command       string     `json:"command"`
+	CreatedAt     time.Time  `json:"created_at"`
+	AppliedAt     *time.Time `json:"applied_at,omitempty"`
+	TrashDir      string     `json:"trash_dir,omitempty"`
+	SizeMode      string     `json:"size_mode,omitempty"`
+	Items         []PlanItem `json:"items"`
+}
+
+type SnapshotEntry struct {
+	Path           string    `json:"path"`
+	IsDir          bool      `json:"is_dir,omitempty"`
+	SizeBytes      int64     `json:"size_bytes"`
+	AllocatedBytes int64     `json:"allocated_bytes"`
+	FileCount      int64     `json:"file_count,omitempty"`
+	LastModified   time.Time `json:"last_modified"`
+}
+
+type Snapshot struct {
+	SchemaVersion string          `json:"schema_version"`
+	Name          string          `json:"name"`
+	Root          string          `json:"root"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Entries       []SnapshotEntry `json:"entries"`
+}
+
+type TrashedEntry struct {
+	Path   string
+	Device uint64
+	Inode  uint64
 }
 
 type OperationLogEntry struct {
-	Timestamp  time.Time `json:"timestamp"`
-	PlanID     string    `json:"plan_id"`
-	Command    string    `json:"command"`
-	Action     string    `json:"action"`
-	Path       string    `json:"path"`
-	RuleID     string    `json:"rule_id"`
-	Category   string    `json:"category"`
-	SizeBytes  int64     `json:"size_bytes"`
-	Risk       string    `json:"risk"`
-	Result     string    `json:"result"`
-	Error      string    `json:"error"`
-	DurationMS int64     `json:"duration_ms"`
-	DryRun     bool      `json:"dry_run"`
-	UserID     int       `json:"user_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	PlanID      string    `json:"plan_id"`
+	Command     string    `json:"command"`
+	Action      string    `json:"action"`
+	Path        string    `json:"path"`
+	TrashPath   string    `json:"trash_path,omitempty"`
+	TrashDevice uint64    `json:"trash_device,omitempty"`
+	TrashInode  uint64    `json:"trash_inode,omitempty"`
+	RuleID      string    `json:"rule_id"`
+	Category    string    `json:"category"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Risk        string    `json:"risk"`
+	Result      string    `json:"result"`
+	Error       string    `json:"error"`
+	DurationMS  int64     `json:"duration_ms"`
+	DryRun      bool      `json:"dry_run"`
+	UserID      int       `json:"user_id"`
+	PrevHash    string    `json:"prev_hash,omitempty"`
 }
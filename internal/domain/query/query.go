@@ -0,0 +1,490 @@
+package query
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"talpa/internal/domain/units"
+)
+
+type Entry struct {
+	Path      string
+	IsDir     bool
+	SizeBytes int64
+	ModTime   time.Time
+	UID       uint32
+}
+
+type Query struct {
+	source string
+	match  matcher
+}
+
+type matcher func(Entry) bool
+
+var lookupUser = user.Lookup
+var userHomeDir = os.UserHomeDir
+
+func Parse(src string, now time.Time) (*Query, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	p := &parser{src: src, now: now}
+	m, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return &Query{source: src, match: m}, nil
+}
+
+func (q *Query) Match(e Entry) bool {
+	if q == nil {
+		return true
+	}
+	return q.match(e)
+}
+
+func (q *Query) String() string {
+	if q == nil {
+		return ""
+	}
+	return q.source
+}
+
+type parser struct {
+	src string
+	pos int
+	now time.Time
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("query: "+format+" at offset %d", append(args, p.pos)...)
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) peekWord() string {
+	p.skipSpace()
+	end := p.pos
+	for end < len(p.src) && !isDelim(p.src[end]) {
+		end++
+	}
+	return p.src[p.pos:end]
+}
+
+func (p *parser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *parser) consumeKeyword(kw string) bool {
+	if strings.EqualFold(p.peekWord(), kw) {
+		p.pos += len(kw)
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (matcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") || p.consumeKeyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e Entry) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (matcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if !p.consume("&&") && !p.consumeKeyword("and") {
+			p.skipSpace()
+			if p.eof() || p.src[p.pos] == ')' || strings.HasPrefix(p.src[p.pos:], "||") || strings.EqualFold(p.peekWord(), "or") {
+				return left, nil
+			}
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e Entry) bool { return l(e) && right(e) }
+	}
+}
+
+func (p *parser) parseUnary() (matcher, error) {
+	p.skipSpace()
+	rest := p.src[p.pos:]
+	negate := strings.HasPrefix(rest, "!") && !strings.HasPrefix(rest, "!=") && !strings.HasPrefix(rest, "!~")
+	if negate {
+		p.pos++
+	}
+	if negate || p.consumeKeyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e Entry) bool { return !inner(e) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (matcher, error) {
+	if p.consume("(") {
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing )")
+		}
+		return m, nil
+	}
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("expected a filter")
+	}
+	start := p.pos
+	field := p.readIdent()
+	if field != "" {
+		if op, ok := p.readOp(); ok {
+			return p.parsePredicate(strings.ToLower(field), op)
+		}
+	}
+	p.pos = start
+	word, err := p.readValue(false)
+	if err != nil {
+		return nil, err
+	}
+	if word == "" {
+		return nil, p.errorf("expected a filter")
+	}
+	needle := strings.ToLower(word)
+	return func(e Entry) bool { return strings.Contains(strings.ToLower(e.Path), needle) }, nil
+}
+
+func (p *parser) readIdent() string {
+	start := p.pos
+	for !p.eof() && (unicode.IsLetter(rune(p.src[p.pos])) || p.src[p.pos] == '_') {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) readOp() (string, bool) {
+	for _, op := range []string{"<=", ">=", "!=", "!~", "==", "=", "<", ">", "~"} {
+		if p.consume(op) {
+			if op == "==" {
+				op = "="
+			}
+			return op, true
+		}
+	}
+	if p.consumeKeyword("in") {
+		return "in", true
+	}
+	return "", false
+}
+
+func (p *parser) readValue(inList bool) (string, error) {
+	p.skipSpace()
+	if p.eof() {
+		return "", p.errorf("expected a value")
+	}
+	if q := p.src[p.pos]; q == '"' || q == '\'' {
+		end := strings.IndexByte(p.src[p.pos+1:], q)
+		if end < 0 {
+			return "", p.errorf("unterminated string")
+		}
+		v := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return v, nil
+	}
+	start := p.pos
+	for !p.eof() && !isDelim(p.src[p.pos]) && !(inList && p.src[p.pos] == ',') {
+		p.pos++
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *parser) readList() ([]string, error) {
+	if !p.consume("(") {
+		v, err := p.readValue(false)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Split(v, ","), nil
+	}
+	var out []string
+	for {
+		v, err := p.readValue(true)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		if p.consume(",") {
+			continue
+		}
+		if p.consume(")") {
+			return out, nil
+		}
+		return nil, p.errorf("expected , or ) in list")
+	}
+}
+
+func isDelim(c byte) bool {
+	return c == '(' || c == ')' || unicode.IsSpace(rune(c))
+}
+
+func (p *parser) parsePredicate(field, op string) (matcher, error) {
+	switch field {
+	case "path", "name":
+		return p.textPredicate(field, op)
+	case "ext":
+		return p.setPredicate(op, func(v string) (string, error) {
+			return strings.ToLower(strings.TrimPrefix(v, ".")), nil
+		}, func(e Entry) string {
+			if e.IsDir {
+				return ""
+			}
+			return strings.ToLower(strings.TrimPrefix(filepath.Ext(e.Path), "."))
+		})
+	case "type":
+		return p.setPredicate(op, func(v string) (string, error) {
+			v = strings.ToLower(v)
+			if v != "file" && v != "dir" {
+				return "", fmt.Errorf("query: type must be file or dir, got %q", v)
+			}
+			return v, nil
+		}, func(e Entry) string {
+			if e.IsDir {
+				return "dir"
+			}
+			return "file"
+		})
+	case "owner":
+		return p.setPredicate(op, resolveOwner, func(e Entry) string {
+			return strconv.FormatUint(uint64(e.UID), 10)
+		})
+	case "size":
+		return p.rangePredicate(op, units.ParseSize, func(e Entry) int64 { return e.SizeBytes })
+	case "mtime", "age":
+		now := p.now
+		return p.rangePredicate(op, func(v string) (int64, error) {
+			d, err := units.ParseAge(v)
+			return int64(d), err
+		}, func(e Entry) int64 { return int64(now.Sub(e.ModTime)) })
+	}
+	return nil, p.errorf("unknown field %q (use path, name, ext, size, mtime, owner, or type)", field)
+}
+
+func (p *parser) textPredicate(field, op string) (matcher, error) {
+	value, err := p.readValue(false)
+	if err != nil {
+		return nil, err
+	}
+	subject := func(e Entry) string { return e.Path }
+	if field == "name" {
+		subject = func(e Entry) string { return filepath.Base(e.Path) }
+	}
+	var test func(string) bool
+	switch op {
+	case "=", "!=":
+		test, err = globMatcher(field, value)
+	case "~", "!~":
+		var re *regexp.Regexp
+		re, err = regexp.Compile(value)
+		if re != nil {
+			test = re.MatchString
+		}
+	default:
+		return nil, p.errorf("%s supports =, !=, ~, and !~, not %s", field, op)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query: %s %s %q: %w", field, op, value, err)
+	}
+	if op == "!=" || op == "!~" {
+		return func(e Entry) bool { return !test(subject(e)) }, nil
+	}
+	return func(e Entry) bool { return test(subject(e)) }, nil
+}
+
+func (p *parser) setPredicate(op string, normalize func(string) (string, error), subject func(Entry) string) (matcher, error) {
+	var values []string
+	var err error
+	switch op {
+	case "in":
+		values, err = p.readList()
+	case "=", "!=":
+		var v string
+		v, err = p.readValue(false)
+		values = []string{v}
+	default:
+		return nil, p.errorf("operator %s is not supported here; use =, !=, or in", op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]bool{}
+	for _, v := range values {
+		n, err := normalize(strings.TrimSpace(v))
+		if err != nil {
+			return nil, err
+		}
+		set[n] = true
+	}
+	if op == "!=" {
+		return func(e Entry) bool { return !set[subject(e)] }, nil
+	}
+	return func(e Entry) bool { return set[subject(e)] }, nil
+}
+
+func (p *parser) rangePredicate(op string, parse func(string) (int64, error), subject func(Entry) int64) (matcher, error) {
+	value, err := p.readValue(false)
+	if err != nil {
+		return nil, err
+	}
+	if op == "in" {
+		lo, hi, ok := strings.Cut(value, "..")
+		if !ok {
+			return nil, p.errorf("range must look like 100MB..1GB")
+		}
+		min, err := parse(lo)
+		if err != nil {
+			return nil, err
+		}
+		max, err := parse(hi)
+		if err != nil {
+			return nil, err
+		}
+		return func(e Entry) bool { v := subject(e); return v >= min && v <= max }, nil
+	}
+	n, err := parse(value)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "<":
+		return func(e Entry) bool { return subject(e) < n }, nil
+	case "<=":
+		return func(e Entry) bool { return subject(e) <= n }, nil
+	case ">":
+		return func(e Entry) bool { return subject(e) > n }, nil
+	case ">=":
+		return func(e Entry) bool { return subject(e) >= n }, nil
+	case "=":
+		return func(e Entry) bool { return subject(e) == n }, nil
+	case "!=":
+		return func(e Entry) bool { return subject(e) != n }, nil
+	}
+	return nil, p.errorf("operator %s is not supported here; use <, <=, >, >=, =, !=, or in", op)
+}
+
+func resolveOwner(v string) (string, error) {
+	if _, err := strconv.ParseUint(v, 10, 32); err == nil {
+		return v, nil
+	}
+	u, err := lookupUser(v)
+	if err != nil {
+		return "", fmt.Errorf("query: unknown owner %q", v)
+	}
+	return u.Uid, nil
+}
+
+func globMatcher(field, pattern string) (func(string) bool, error) {
+	if field == "name" {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		return func(s string) bool {
+			ok, _ := filepath.Match(pattern, s)
+			return ok
+		}, nil
+	}
+	if rest, ok := strings.CutPrefix(pattern, "~"); ok && (rest == "" || rest[0] == '/') {
+		home, err := userHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		pattern = home + rest
+	}
+	re, err := regexp.Compile(globRegexp(filepath.ToSlash(pattern)))
+	if err != nil {
+		return nil, err
+	}
+	return func(s string) bool { return re.MatchString(filepath.ToSlash(s)) }, nil
+}
+
+func globRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.HasPrefix(pattern, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
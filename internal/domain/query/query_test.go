@@ -0,0 +1,98 @@
+package query
+
+import (
+	"errors"
+	"os/user"
+	"testing"
+	"time"
+)
+
+func TestParseMatchesPredicates(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	video := Entry{Path: "/home/u/Downloads/trip/clip.MP4", SizeBytes: 2 << 30, ModTime: now.AddDate(0, -8, 0), UID: 1000}
+	notes := Entry{Path: "/home/u/docs/notes.txt", SizeBytes: 4 << 10, ModTime: now.AddDate(0, 0, -3), UID: 1000}
+	dir := Entry{Path: "/home/u/Downloads", IsDir: true, SizeBytes: 3 << 30, ModTime: now, UID: 0}
+
+	restore := userHomeDir
+	userHomeDir = func() (string, error) { return "/home/u", nil }
+	defer func() { userHomeDir = restore }()
+
+	tests := []struct {
+		expr string
+		want []bool
+	}{
+		{"downloads", []bool{true, false, true}},
+		{"ext in (mp4, mkv) and size > 1GB and mtime > 6mo and path = ~/Downloads/**", []bool{true, false, false}},
+		{"ext in mp4,mkv", []bool{true, false, false}},
+		{"ext = .txt", []bool{false, true, false}},
+		{"size > 500MiB", []bool{true, false, true}},
+		{"size in 1KiB..1MiB", []bool{false, true, false}},
+		{"mtime < 7d", []bool{false, true, true}},
+		{"name = '*.txt' || type = dir", []bool{false, true, true}},
+		{"name ~ '(?i)\\.mp4$'", []bool{true, false, false}},
+		{"path != '**/docs/**'", []bool{true, false, true}},
+		{"not type = dir and owner = 1000", []bool{true, true, false}},
+		{"!(size < 1GB) type=file", []bool{true, false, false}},
+		{"path = 'trip/*'", []bool{true, false, false}},
+	}
+	for _, tc := range tests {
+		q, err := Parse(tc.expr, now)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		for i, e := range []Entry{video, notes, dir} {
+			if got := q.Match(e); got != tc.want[i] {
+				t.Fatalf("%q on %s: got %v want %v", tc.expr, e.Path, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestParseEmptyMatchesEverything(t *testing.T) {
+	q, err := Parse("  ", time.Now())
+	if err != nil || q != nil {
+		t.Fatalf("expected nil query, got %v %v", q, err)
+	}
+	if !q.Match(Entry{Path: "/x"}) {
+		t.Fatalf("nil query must match")
+	}
+}
+
+func TestParseResolvesOwnerNames(t *testing.T) {
+	restore := lookupUser
+	defer func() { lookupUser = restore }()
+	lookupUser = func(name string) (*user.User, error) {
+		if name == "alice" {
+			return &user.User{Uid: "1001"}, nil
+		}
+		return nil, errors.New("unknown user")
+	}
+	q, err := Parse("owner = alice", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Match(Entry{UID: 1001}) || q.Match(Entry{UID: 1000}) {
+		t.Fatalf("unexpected owner match")
+	}
+	if _, err := Parse("owner = bob", time.Now()); err == nil {
+		t.Fatalf("expected unknown owner error")
+	}
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"size > huge",
+		"mtime < soon",
+		"colour = red",
+		"(size > 1GB",
+		"size ~ 1GB",
+		"name ~ '('",
+		"type = socket",
+		"size in 1GB",
+		"name = 'unterminated",
+	} {
+		if _, err := Parse(expr, time.Now()); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/safety"
+	"talpa/internal/domain/units"
+)
+
+const BuiltinSource = "builtin"
+
+type PluginFile struct {
+	Path string
+	Data []byte
+}
+
+type Issue struct {
+	Source string `json:"source"`
+	RuleID string `json:"rule_id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type pluginDoc struct {
+	Rules []pluginRule `yaml:"rules"`
+}
+
+type pluginRule struct {
+	ID           string   `yaml:"id"`
+	Command      string   `yaml:"command"`
+	Category     string   `yaml:"category"`
+	Pattern      string   `yaml:"pattern"`
+	Risk         string   `yaml:"risk"`
+	RequiresRoot bool     `yaml:"requires_root"`
+	MinAge       string   `yaml:"min_age"`
+	MinSize      string   `yaml:"min_size"`
+	OlderThan    string   `yaml:"older_than"`
+	KeepNewest   int      `yaml:"keep_newest"`
+	EntryDepth   string   `yaml:"entry_depth"`
+	Markers      []string `yaml:"markers"`
+}
+
+var (
+	validRuleID = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+	envRef      = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+)
+
+func LoadPlugins(files []PluginFile, home string, lookupEnv func(string) (string, bool)) ([]model.Rule, []Issue) {
+	var out []model.Rule
+	var issues []Issue
+	index := map[string]int{}
+	for _, f := range files {
+		parsed, fileIssues := ParsePluginFile(f, home, lookupEnv)
+		issues = append(issues, fileIssues...)
+		for _, r := range parsed {
+			if i, ok := index[r.ID]; ok {
+				out[i] = r
+				continue
+			}
+			index[r.ID] = len(out)
+			out = append(out, r)
+		}
+	}
+	return out, issues
+}
+
+func ParsePluginFile(f PluginFile, home string, lookupEnv func(string) (string, bool)) ([]model.Rule, []Issue) {
+	var doc pluginDoc
+	dec := yaml.NewDecoder(bytes.NewReader(f.Data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, []Issue{{Source: f.Path, Reason: "parse: " + err.Error()}}
+	}
+
+	var out []model.Rule
+	var issues []Issue
+	seen := map[string]struct{}{}
+	for _, spec := range doc.Rules {
+		r, active, err := buildPluginRule(spec, home, lookupEnv)
+		if err != nil {
+			issues = append(issues, Issue{Source: f.Path, RuleID: spec.ID, Reason: err.Error()})
+			continue
+		}
+		if _, dup := seen[r.ID]; dup {
+			issues = append(issues, Issue{Source: f.Path, RuleID: r.ID, Reason: "duplicate rule id in file"})
+			continue
+		}
+		seen[r.ID] = struct{}{}
+		if !active {
+			continue
+		}
+		r.Source = f.Path
+		out = append(out, r)
+	}
+	return out, issues
+}
+
+func buildPluginRule(spec pluginRule, home string, lookupEnv func(string) (string, bool)) (model.Rule, bool, error) {
+	r := model.Rule{
+		ID:           strings.TrimSpace(spec.ID),
+		Command:      strings.TrimSpace(spec.Command),
+		Category:     strings.TrimSpace(spec.Category),
+		RequiresRoot: spec.RequiresRoot,
+		Risk:         model.RiskLevel(strings.ToLower(strings.TrimSpace(spec.Risk))),
+	}
+	if !validRuleID.MatchString(r.ID) {
+		return r, false, errors.New("id must match " + validRuleID.String())
+	}
+	if r.Command != "clean" && r.Command != "purge" {
+		return r, false, fmt.Errorf("command must be clean or purge, got %q", r.Command)
+	}
+	if !strings.HasPrefix(r.ID, r.Command+".") {
+		return r, false, fmt.Errorf("id must start with %q", r.Command+".")
+	}
+	if r.Category == "" {
+		return r, false, errors.New("category is required")
+	}
+	switch r.Risk {
+	case model.RiskLow, model.RiskMedium, model.RiskHigh:
+	default:
+		return r, false, fmt.Errorf("risk must be low, medium, or high, got %q", spec.Risk)
+	}
+	if spec.MinAge != "" {
+		d, err := units.ParseAge(spec.MinAge)
+		if err != nil {
+			return r, false, err
+		}
+		r.MinAge = d
+	}
+	if spec.MinSize != "" {
+		n, err := units.ParseSize(spec.MinSize)
+		if err != nil {
+			return r, false, err
+		}
+		r.MinSizeBytes = n
+	}
+	if spec.OlderThan != "" {
+		d, err := units.ParseAge(spec.OlderThan)
+		if err != nil {
+			return r, false, err
+		}
+		r.OlderThan = d
+	}
+	if spec.KeepNewest < 0 {
+		return r, false, errors.New("keep_newest must be >= 0")
+	}
+	r.KeepNewest = spec.KeepNewest
+	if v := strings.TrimSpace(spec.EntryDepth); v != "" {
+		depth, err := parseEntryDepth(v)
+		if err != nil {
+			return r, false, err
+		}
+		if !HasEntryPolicy(r) {
+			return r, false, errors.New("entry_depth requires older_than or keep_newest")
+		}
+		r.EntryDepth = depth
+	}
+
+	if len(spec.Markers) > 0 && r.Command != "purge" {
+		return r, false, errors.New("markers are only supported for purge rules")
+	}
+
+	pattern := strings.TrimSpace(spec.Pattern)
+	if pattern == "" {
+		return r, false, errors.New("pattern is required")
+	}
+	if r.Command == "purge" {
+		if r.RequiresRoot {
+			return r, false, errors.New("requires_root is not supported for purge rules")
+		}
+		if HasEntryPolicy(r) {
+			return r, false, errors.New("older_than and keep_newest are only supported for clean rules")
+		}
+		if err := validatePurgePattern(pattern); err != nil {
+			return r, false, err
+		}
+		for _, m := range spec.Markers {
+			m = strings.TrimSpace(m)
+			if err := validateMarker(m); err != nil {
+				return r, false, err
+			}
+			r.Markers = append(r.Markers, m)
+		}
+		r.Pattern = pattern
+		return r, true, nil
+	}
+
+	expanded, ok := expandPattern(pattern, home, lookupEnv)
+	if !ok {
+		return r, false, nil
+	}
+	if err := validateCleanPattern(expanded, home); err != nil {
+		return r, false, err
+	}
+	r.Pattern = filepath.Clean(expanded)
+	return r, true, nil
+}
+
+func expandPattern(pattern, home string, lookupEnv func(string) (string, bool)) (string, bool) {
+	if pattern == "~" {
+		pattern = home
+	} else if strings.HasPrefix(pattern, "~/") {
+		pattern = filepath.Join(home, pattern[2:])
+	}
+	ok := true
+	expanded := envRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		v, found := lookupEnv(name)
+		if !found || strings.TrimSpace(v) == "" {
+			ok = false
+			return ""
+		}
+		return v
+	})
+	return expanded, ok
+}
+
+func validateCleanPattern(pattern, home string) error {
+	if !filepath.IsAbs(pattern) {
+		return fmt.Errorf("pattern must be an absolute path, ~/ path, or $VAR path: %s", pattern)
+	}
+	if err := safety.ValidatePath(pattern, nil, nil); err != nil {
+		return err
+	}
+	static := staticPrefix(pattern)
+	if err := safety.ValidatePath(static, nil, nil); err != nil {
+		return err
+	}
+	h := filepath.Clean(home)
+	if static == h || !strings.HasPrefix(static, h+string(filepath.Separator)) {
+		return fmt.Errorf("PATH_BLOCKED: clean rule must target a path inside %s", h)
+	}
+	return nil
+}
+
+func validatePurgePattern(pattern string) error {
+	if strings.ContainsRune(pattern, '/') || strings.ContainsRune(pattern, filepath.Separator) {
+		return errors.New("purge pattern must be a directory name, not a path")
+	}
+	if pattern == "." || pattern == ".." {
+		return errors.New("purge pattern must be a directory name")
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob: %w", err)
+	}
+	if strings.Trim(pattern, "*?") == "" {
+		return errors.New("purge pattern must not match every directory")
+	}
+	return nil
+}
+
+func validateMarker(marker string) error {
+	if marker == "" || marker == "." || marker == ".." || strings.ContainsRune(marker, '/') || strings.ContainsRune(marker, filepath.Separator) {
+		return fmt.Errorf("marker must be a file name next to the artifact, got %q", marker)
+	}
+	if _, err := filepath.Match(marker, ""); err != nil {
+		return fmt.Errorf("invalid marker glob: %w", err)
+	}
+	return nil
+}
+
+func staticPrefix(pattern string) string {
+	i := strings.IndexAny(pattern, "*?[")
+	if i < 0 {
+		return filepath.Clean(pattern)
+	}
+	return filepath.Dir(pattern[:i+1])
+}
+
+func HasGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+func MeetsThresholds(r model.Rule, sizeBytes int64, modTime time.Time, now time.Time) bool {
+	if r.MinSizeBytes > 0 && sizeBytes < r.MinSizeBytes {
+		return false
+	}
+	if r.MinAge <= 0 {
+		return true
+	}
+	if modTime.IsZero() {
+		return false
+	}
+	return now.Sub(modTime) >= r.MinAge
+}
+
+func parseEntryDepth(value string) (int, error) {
+	if value == "files" {
+		return EntryDepthFiles, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid entry_depth %q: use a number >= 1 or files", value)
+	}
+	return n, nil
+}
@@ -0,0 +1,139 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"talpa/internal/domain/model"
+)
+
+func envOf(m map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+func TestParsePluginFileYAML(t *testing.T) {
+	data := []byte(`
+rules:
+  - id: clean.acme.cache
+    command: clean
+    category: dev_cache
+    pattern: ~/.cache/acme
+    risk: low
+    min_age: 7d
+    min_size: 500MB
+    older_than: 30d
+    keep_newest: 3
+    entry_depth: 2
+  - id: clean.go.cache
+    command: clean
+    category: dev_cache
+    pattern: $GOCACHE
+    risk: low
+  - id: clean.unset.env
+    command: clean
+    category: dev_cache
+    pattern: ${ACME_UNSET}/cache
+    risk: low
+  - id: purge.terraform
+    command: purge
+    category: project_artifact
+    pattern: .terraform*
+    risk: medium
+    markers: ["*.tf", .terraform.lock.hcl]
+`)
+	got, issues := ParsePluginFile(PluginFile{Path: "acme.yaml", Data: data}, "/home/u", envOf(map[string]string{"GOCACHE": "/home/u/.cache/go-build"}))
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues: %+v", issues)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 active rules (unset env rule dropped), got %+v", got)
+	}
+	if got[0].Pattern != "/home/u/.cache/acme" || got[0].MinAge != 7*24*time.Hour || got[0].MinSizeBytes != 500_000_000 || got[0].OlderThan != 30*24*time.Hour || got[0].KeepNewest != 3 || got[0].EntryDepth != 2 || got[0].Source != "acme.yaml" {
+		t.Fatalf("unexpected clean rule: %+v", got[0])
+	}
+	if got[1].Pattern != "/home/u/.cache/go-build" {
+		t.Fatalf("expected env pattern to expand, got %q", got[1].Pattern)
+	}
+	if got[2].Command != "purge" || got[2].Pattern != ".terraform*" || got[2].Risk != model.RiskMedium || strings.Join(got[2].Markers, ",") != "*.tf,.terraform.lock.hcl" {
+		t.Fatalf("unexpected purge rule: %+v", got[2])
+	}
+}
+
+func TestParsePluginFileJSON(t *testing.T) {
+	data := []byte(`{"rules": [{"id": "purge.bazel", "command": "purge", "category": "project_artifact", "pattern": "bazel-out", "risk": "low"}]}`)
+	got, issues := ParsePluginFile(PluginFile{Path: "bazel.json", Data: data}, "/home/u", envOf(nil))
+	if len(issues) != 0 || len(got) != 1 || got[0].ID != "purge.bazel" {
+		t.Fatalf("unexpected parse result: %+v %+v", got, issues)
+	}
+}
+
+func TestParsePluginFileRejectsUnsafeRules(t *testing.T) {
+	cases := map[string]string{
+		"blocked":       `{id: clean.etc, command: clean, category: x, pattern: /etc/acme, risk: low}`,
+		"outside home":  `{id: clean.opt, command: clean, category: x, pattern: /opt/acme/cache, risk: low}`,
+		"home itself":   `{id: clean.home, command: clean, category: x, pattern: "~", risk: low}`,
+		"glob at root":  `{id: clean.glob, command: clean, category: x, pattern: "/*/cache", risk: low}`,
+		"traversal":     `{id: clean.up, command: clean, category: x, pattern: ~/../other, risk: low}`,
+		"env to usr":    `{id: clean.env, command: clean, category: x, pattern: $EVIL, risk: low}`,
+		"purge path":    `{id: purge.path, command: purge, category: x, pattern: a/b, risk: low}`,
+		"purge all":     `{id: purge.all, command: purge, category: x, pattern: "*", risk: low}`,
+		"bad risk":      `{id: clean.risk, command: clean, category: x, pattern: ~/.cache/x, risk: extreme}`,
+		"bad command":   `{id: clean.cmd, command: analyze, category: x, pattern: ~/.cache/x, risk: low}`,
+		"id prefix":     `{id: acme.cache, command: clean, category: x, pattern: ~/.cache/x, risk: low}`,
+		"bad age":       `{id: clean.age, command: clean, category: x, pattern: ~/.cache/x, risk: low, min_age: soon}`,
+		"bad size":      `{id: clean.size, command: clean, category: x, pattern: ~/.cache/x, risk: low, min_size: 5XB}`,
+		"bad older":     `{id: clean.older, command: clean, category: x, pattern: ~/.cache/x, risk: low, older_than: old}`,
+		"neg keep":      `{id: clean.keep, command: clean, category: x, pattern: ~/.cache/x, risk: low, keep_newest: -1}`,
+		"purge policy":  `{id: purge.old, command: purge, category: x, pattern: dist, risk: low, older_than: 30d}`,
+		"bad depth":     `{id: clean.depth, command: clean, category: x, pattern: ~/.cache/x, risk: low, older_than: 30d, entry_depth: 0}`,
+		"depth alone":   `{id: clean.depth, command: clean, category: x, pattern: ~/.cache/x, risk: low, entry_depth: files}`,
+		"clean markers": `{id: clean.mark, command: clean, category: x, pattern: ~/.cache/x, risk: low, markers: [a]}`,
+		"marker path":   `{id: purge.mark, command: purge, category: x, pattern: dist, risk: low, markers: [../package.json]}`,
+		"marker glob":   `{id: purge.glob, command: purge, category: x, pattern: dist, risk: low, markers: ["[a"]}`,
+		"unknown field": `{id: clean.field, command: clean, category: x, pattern: ~/.cache/x, risk: low, recursive: true}`,
+	}
+	for name, rule := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("rules:\n  - " + rule + "\n")
+			got, issues := ParsePluginFile(PluginFile{Path: "bad.yaml", Data: data}, "/home/u", envOf(map[string]string{"EVIL": "/usr/lib"}))
+			if len(got) != 0 || len(issues) != 1 {
+				t.Fatalf("expected rule to be rejected, got rules=%+v issues=%+v", got, issues)
+			}
+		})
+	}
+}
+
+func TestLoadPluginsLaterFilesOverride(t *testing.T) {
+	first := PluginFile{Path: "/etc/talpa/rules.d/a.yaml", Data: []byte("rules:\n  - {id: purge.x, command: purge, category: a, pattern: x, risk: low}\n")}
+	second := PluginFile{Path: "/home/u/.config/talpa/rules.d/a.yaml", Data: []byte("rules:\n  - {id: purge.x, command: purge, category: b, pattern: x, risk: medium}\n")}
+	got, issues := LoadPlugins([]PluginFile{first, second}, "/home/u", envOf(nil))
+	if len(issues) != 0 || len(got) != 1 || got[0].Category != "b" || !strings.HasPrefix(got[0].Source, "/home/u") {
+		t.Fatalf("expected user rule to override system rule, got %+v %+v", got, issues)
+	}
+}
+
+func TestMergeOverridesBuiltinByID(t *testing.T) {
+	extra := []model.Rule{
+		{ID: "purge.node_modules", Command: "purge", Category: "project_artifact", Pattern: "node_modules", Risk: model.RiskMedium, Source: "x.yaml"},
+		{ID: "clean.ignored", Command: "clean", Pattern: "/home/u/x"},
+	}
+	got := PurgeRules(extra)
+	if len(got) != len(PurgeArtifactRules()) {
+		t.Fatalf("expected override without growth, got %d rules", len(got))
+	}
+	if got[0].Risk != model.RiskMedium || got[0].Source != "x.yaml" {
+		t.Fatalf("expected builtin to be overridden, got %+v", got[0])
+	}
+}
+
+func TestParsePluginFileEntryDepthFiles(t *testing.T) {
+	data := []byte("rules:\n  - {id: clean.dev.npm, command: clean, category: dev_cache, pattern: ~/.npm/_cacache, risk: low, older_than: 30d, entry_depth: files}\n")
+	got, issues := ParsePluginFile(PluginFile{Path: "npm.yaml", Data: data}, "/home/u", envOf(nil))
+	if len(issues) != 0 || len(got) != 1 || got[0].EntryDepth != EntryDepthFiles {
+		t.Fatalf("expected file-level entries, got %+v %+v", got, issues)
+	}
+}
@@ -0,0 +1,45 @@
+package rules
+
+import (
+	"sort"
+	"time"
+
+	"talpa/internal/domain/model"
+)
+
+const EntryDepthFiles = -1
+
+type Entry struct {
+	Path      string
+	SizeBytes int64
+	ModTime   time.Time
+}
+
+func HasEntryPolicy(r model.Rule) bool {
+	return r.OlderThan > 0 || r.KeepNewest > 0
+}
+
+func SelectEntries(r model.Rule, entries []Entry, now time.Time) []Entry {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].ModTime.Equal(sorted[j].ModTime) {
+			return sorted[i].ModTime.After(sorted[j].ModTime)
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+	if r.KeepNewest > 0 {
+		if r.KeepNewest >= len(sorted) {
+			return nil
+		}
+		sorted = sorted[r.KeepNewest:]
+	}
+	out := make([]Entry, 0, len(sorted))
+	for _, e := range sorted {
+		if r.OlderThan > 0 && now.Sub(e.ModTime) < r.OlderThan {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
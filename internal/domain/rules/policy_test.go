@@ -0,0 +1,42 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"talpa/internal/domain/model"
+)
+
+func TestSelectEntries(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	entries := []Entry{
+		{Path: "/c/a", ModTime: now.Add(-1 * day)},
+		{Path: "/c/b", ModTime: now.Add(-40 * day)},
+		{Path: "/c/c", ModTime: now.Add(-60 * day)},
+		{Path: "/c/d", ModTime: now.Add(-90 * day)},
+	}
+	cases := []struct {
+		name string
+		rule model.Rule
+		want []string
+	}{
+		{"older than", model.Rule{OlderThan: 30 * day}, []string{"/c/b", "/c/c", "/c/d"}},
+		{"keep newest", model.Rule{KeepNewest: 3}, []string{"/c/d"}},
+		{"both", model.Rule{OlderThan: 30 * day, KeepNewest: 2}, []string{"/c/c", "/c/d"}},
+		{"keep all", model.Rule{KeepNewest: 10}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SelectEntries(tc.rule, entries, now)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %+v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].Path != tc.want[i] {
+					t.Fatalf("expected %v, got %+v", tc.want, got)
+				}
+			}
+		})
+	}
+}
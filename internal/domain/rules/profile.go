@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"talpa/internal/domain/model"
+)
+
+var validProfileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func BuiltinProfiles() []model.Profile {
+	system := true
+	return []model.Profile{
+		{
+			Name:        "ci",
+			Description: "Aggressive cleanup for disposable CI runners: every rule, system scope, short recency window",
+			Defaults:    model.ProfileDefaults{RecentDays: 1, AnalyzeDepth: 8, System: &system},
+			Source:      BuiltinSource,
+		},
+		{
+			Name:             "desktop",
+			Description:      "Desktop caches, thumbnails and trash; leaves developer toolchain caches alone",
+			EnableCategories: []string{"xdg_cache", "trash", "thumbnails", "browser_cache", "electron_cache", "project_artifact"},
+			Source:           BuiltinSource,
+		},
+		{
+			Name:         "dev",
+			Description:  "Developer workstation: keeps dependency stores that are slow to re-download",
+			DisableRules: []string{"clean.dev.go.mod", "clean.dev.cargo", "clean.dev.gradle", "clean.dev.maven", "clean.trash"},
+			Defaults:     model.ProfileDefaults{RecentDays: 14},
+			Source:       BuiltinSource,
+		},
+		{
+			Name:             "minimal",
+			Description:      "Only regenerable, low-risk caches",
+			EnableCategories: []string{"thumbnails", "browser_cache"},
+			EnableRules:      []string{"purge.python.pycache", "purge.python.pytest"},
+			Defaults:         model.ProfileDefaults{RecentDays: 30},
+			Source:           BuiltinSource,
+		},
+	}
+}
+
+func ValidateProfile(p model.Profile) error {
+	if !validProfileName.MatchString(p.Name) {
+		return fmt.Errorf("profile name %q must match %s", p.Name, validProfileName.String())
+	}
+	for _, patterns := range [][]string{p.EnableRules, p.DisableRules, p.EnableCategories, p.DisableCategories} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("profile %s: invalid pattern %q: %w", p.Name, pattern, err)
+			}
+		}
+	}
+	if p.Defaults.RecentDays < 0 || p.Defaults.AnalyzeDepth < 0 {
+		return fmt.Errorf("profile %s: recent_days and analyze_depth must be >= 0", p.Name)
+	}
+	return nil
+}
+
+func MergeProfiles(base []model.Profile, extra []model.Profile) []model.Profile {
+	byName := make(map[string]model.Profile, len(base)+len(extra))
+	for _, p := range base {
+		byName[p.Name] = p
+	}
+	for _, p := range extra {
+		byName[p.Name] = p
+	}
+	out := make([]model.Profile, 0, len(byName))
+	for _, p := range byName {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func FindProfile(profiles []model.Profile, name string) (model.Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return model.Profile{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+func ProfileAllows(p *model.Profile, r model.Rule) bool {
+	if p == nil {
+		return true
+	}
+	if len(p.EnableRules) > 0 || len(p.EnableCategories) > 0 {
+		if !matchesAny(p.EnableRules, r.ID) && !matchesAny(p.EnableCategories, r.Category) {
+			return false
+		}
+	}
+	return !matchesAny(p.DisableRules, r.ID) && !matchesAny(p.DisableCategories, r.Category)
+}
+
+func ApplyProfile(p *model.Profile, ruleSet []model.Rule) []model.Rule {
+	if p == nil {
+		return ruleSet
+	}
+	out := make([]model.Rule, 0, len(ruleSet))
+	for _, r := range ruleSet {
+		if ProfileAllows(p, r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package rules
+
+import (
+	"testing"
+
+	"talpa/internal/domain/model"
+)
+
+func TestBuiltinProfilesAreValid(t *testing.T) {
+	for _, p := range BuiltinProfiles() {
+		if err := ValidateProfile(p); err != nil {
+			t.Fatalf("builtin profile %s invalid: %v", p.Name, err)
+		}
+	}
+}
+
+func TestProfileAllows(t *testing.T) {
+	npm := model.Rule{ID: "clean.dev.npm", Category: "dev_cache"}
+	gomod := model.Rule{ID: "clean.dev.go.mod", Category: "dev_cache"}
+	thumbs := model.Rule{ID: "clean.thumbnails", Category: "thumbnails"}
+
+	p := &model.Profile{EnableCategories: []string{"dev_cache"}, DisableRules: []string{"clean.dev.go.*"}}
+	if !ProfileAllows(p, npm) || ProfileAllows(p, gomod) || ProfileAllows(p, thumbs) {
+		t.Fatalf("unexpected allow/deny for %+v", p)
+	}
+	if !ProfileAllows(nil, gomod) {
+		t.Fatal("nil profile must allow every rule")
+	}
+	if got := ApplyProfile(&model.Profile{DisableCategories: []string{"thumbnails"}}, []model.Rule{npm, thumbs}); len(got) != 1 || got[0].ID != npm.ID {
+		t.Fatalf("expected thumbnails to be filtered, got %+v", got)
+	}
+}
+
+func TestFindProfileAndMerge(t *testing.T) {
+	custom := model.Profile{Name: "ci", Description: "custom", Source: "profiles.yaml"}
+	merged := MergeProfiles(BuiltinProfiles(), []model.Profile{custom})
+	p, err := FindProfile(merged, " CI ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Source != "profiles.yaml" {
+		t.Fatalf("expected config profile to replace builtin, got %+v", p)
+	}
+	if _, err := FindProfile(merged, "nope"); err == nil {
+		t.Fatal("expected unknown profile error")
+	}
+}
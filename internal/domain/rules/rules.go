@@ -46,32 +46,112 @@ func CleanSystemRules() []model.Rule {
 }
 
 func PurgeArtifactRules() []model.Rule {
+	node := []string{"package.json"}
+	python := []string{"pyproject.toml", "requirements.txt", "setup.py", "Pipfile"}
 	return []model.Rule{
-		{ID: "purge.node_modules", Command: "purge", Category: "project_artifact", Pattern: "node_modules", Risk: model.RiskLow},
-		{ID: "purge.node.next", Command: "purge", Category: "project_artifact", Pattern: ".next", Risk: model.RiskLow},
-		{ID: "purge.web.dist", Command: "purge", Category: "project_artifact", Pattern: "dist", Risk: model.RiskLow},
-		{ID: "purge.web.build", Command: "purge", Category: "project_artifact", Pattern: "build", Risk: model.RiskLow},
-		{ID: "purge.rust.target", Command: "purge", Category: "project_artifact", Pattern: "target", Risk: model.RiskLow},
-		{ID: "purge.python.venv", Command: "purge", Category: "project_artifact", Pattern: ".venv", Risk: model.RiskLow},
-		{ID: "purge.python.venv_alt", Command: "purge", Category: "project_artifact", Pattern: "venv", Risk: model.RiskLow},
+		{ID: "purge.node_modules", Command: "purge", Category: "project_artifact", Pattern: "node_modules", Risk: model.RiskLow, Markers: node},
+		{ID: "purge.node.next", Command: "purge", Category: "project_artifact", Pattern: ".next", Risk: model.RiskLow, Markers: node},
+		{ID: "purge.web.dist", Command: "purge", Category: "project_artifact", Pattern: "dist", Risk: model.RiskLow, Markers: append([]string{"package.json"}, python...)},
+		{ID: "purge.web.build", Command: "purge", Category: "project_artifact", Pattern: "build", Risk: model.RiskLow, Markers: []string{"package.json", "build.gradle", "build.gradle.kts", "CMakeLists.txt", "pubspec.yaml", "setup.py", "pyproject.toml"}},
+		{ID: "purge.rust.target", Command: "purge", Category: "project_artifact", Pattern: "target", Risk: model.RiskLow, Markers: []string{"Cargo.toml", "pom.xml", "build.sbt"}},
+		{ID: "purge.python.venv", Command: "purge", Category: "project_artifact", Pattern: ".venv", Risk: model.RiskLow, Markers: python},
+		{ID: "purge.python.venv_alt", Command: "purge", Category: "project_artifact", Pattern: "venv", Risk: model.RiskLow, Markers: python},
 		{ID: "purge.python.pycache", Command: "purge", Category: "project_artifact", Pattern: "__pycache__", Risk: model.RiskLow},
 		{ID: "purge.python.pytest", Command: "purge", Category: "project_artifact", Pattern: ".pytest_cache", Risk: model.RiskLow},
-		{ID: "purge.mobile.dart_tool", Command: "purge", Category: "project_artifact", Pattern: ".dart_tool", Risk: model.RiskLow},
-		{ID: "purge.mobile.pods", Command: "purge", Category: "project_artifact", Pattern: "Pods", Risk: model.RiskLow},
+		{ID: "purge.mobile.dart_tool", Command: "purge", Category: "project_artifact", Pattern: ".dart_tool", Risk: model.RiskLow, Markers: []string{"pubspec.yaml"}},
+		{ID: "purge.mobile.pods", Command: "purge", Category: "project_artifact", Pattern: "Pods", Risk: model.RiskLow, Markers: []string{"Podfile"}},
 		{ID: "purge.mobile.derived_data", Command: "purge", Category: "project_artifact", Pattern: "DerivedData", Risk: model.RiskLow},
 	}
 }
 
-func ExistingCleanRules(home string, includeSystem bool) []model.Rule {
-	all := CleanRules(home)
+func HasProjectMarker(r model.Rule, artifact string) bool {
+	if len(r.Markers) == 0 {
+		return true
+	}
+	return HasMarkerIn(filepath.Dir(artifact), r.Markers)
+}
+
+func HasMarkerIn(dir string, markers []string) bool {
+	var names []string
+	for _, m := range markers {
+		if !HasGlob(m) {
+			if _, err := os.Lstat(filepath.Join(dir, m)); err == nil {
+				return true
+			}
+			continue
+		}
+		if names == nil {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return false
+			}
+			names = make([]string, 0, len(entries))
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+		}
+		for _, name := range names {
+			if ok, _ := filepath.Match(m, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ExistingCleanRules(home string, includeSystem bool, extra []model.Rule) []model.Rule {
+	builtin := CleanRules(home)
 	if includeSystem {
-		all = append(all, CleanSystemRules()...)
+		builtin = append(builtin, CleanSystemRules()...)
 	}
+	all := Merge(builtin, extra, "clean")
 	out := make([]model.Rule, 0, len(all))
 	for _, r := range all {
-		if st, err := os.Stat(r.Pattern); err == nil && st.IsDir() {
-			out = append(out, r)
+		if r.RequiresRoot && !includeSystem {
+			continue
+		}
+		if !HasGlob(r.Pattern) {
+			if st, err := os.Stat(r.Pattern); err == nil && st.IsDir() {
+				out = append(out, r)
+			}
+			continue
+		}
+		matches, err := filepath.Glob(r.Pattern)
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			if st, err := os.Stat(m); err == nil && st.IsDir() {
+				expanded := r
+				expanded.Pattern = m
+				out = append(out, expanded)
+			}
+		}
+	}
+	return out
+}
+
+func PurgeRules(extra []model.Rule) []model.Rule {
+	return Merge(PurgeArtifactRules(), extra, "purge")
+}
+
+func Merge(builtin []model.Rule, extra []model.Rule, command string) []model.Rule {
+	out := make([]model.Rule, 0, len(builtin)+len(extra))
+	index := make(map[string]int, len(builtin)+len(extra))
+	for _, r := range builtin {
+		index[r.ID] = len(out)
+		out = append(out, r)
+	}
+	for _, r := range extra {
+		if r.Command != command {
+			continue
+		}
+		if i, ok := index[r.ID]; ok {
+			out[i] = r
+			continue
 		}
+		index[r.ID] = len(out)
+		out = append(out, r)
 	}
 	return out
 }
@@ -1,7 +1,11 @@
 package rules
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"talpa/internal/domain/model"
 )
 
 func TestCleanRulesNotEmpty(t *testing.T) {
@@ -17,3 +21,23 @@ func TestPurgeRulesNotEmpty(t *testing.T) {
 		t.Fatal("expected purge rules")
 	}
 }
+
+func TestHasProjectMarkerChecksArtifactSiblings(t *testing.T) {
+	project := t.TempDir()
+	artifact := filepath.Join(project, "bin")
+	if err := os.WriteFile(filepath.Join(project, "App.csproj"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !HasProjectMarker(model.Rule{}, artifact) {
+		t.Fatal("expected a rule without markers to always match")
+	}
+	if !HasProjectMarker(model.Rule{Markers: []string{"*.csproj"}}, artifact) {
+		t.Fatal("expected glob marker to match")
+	}
+	if HasProjectMarker(model.Rule{Markers: []string{"Cargo.toml", "*.sln"}}, artifact) {
+		t.Fatal("expected missing markers not to match")
+	}
+	if HasProjectMarker(model.Rule{Markers: []string{"App.csproj"}}, filepath.Join(project, "sub", "bin")) {
+		t.Fatal("expected markers to be looked up next to the artifact only")
+	}
+}
@@ -0,0 +1,109 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type unitSuffix struct {
+	suffix string
+	unit   time.Duration
+}
+
+var (
+	sizeValue = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([A-Za-z]*)$`)
+	sizeUnits = map[string]float64{
+		"": 1, "b": 1,
+		"k": 1 << 10, "kb": 1e3, "kib": 1 << 10,
+		"m": 1 << 20, "mb": 1e6, "mib": 1 << 20,
+		"g": 1 << 30, "gb": 1e9, "gib": 1 << 30,
+		"t": 1 << 40, "tb": 1e12, "tib": 1 << 40,
+	}
+	ageUnits = []unitSuffix{
+		{"min", time.Minute},
+		{"mo", 30 * 24 * time.Hour},
+		{"d", 24 * time.Hour},
+		{"w", 7 * 24 * time.Hour},
+		{"y", 365 * 24 * time.Hour},
+	}
+	durationUnits = []unitSuffix{
+		{"d", 24 * time.Hour},
+		{"w", 7 * 24 * time.Hour},
+	}
+	bareMinutes = regexp.MustCompile(`[0-9.]m([0-9.]|$)`)
+)
+
+func ParseAge(value string) (time.Duration, error) {
+	v := strings.TrimSpace(value)
+	if d, ok, err := parseCount(v, ageUnits); ok {
+		if err != nil {
+			return 0, fmt.Errorf("age %q %w", value, err)
+		}
+		return d, nil
+	}
+	if bareMinutes.MatchString(v) {
+		return 0, fmt.Errorf("ambiguous age %q: use mo for months or min for minutes", value)
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q: use a duration like 7d, 2w, 6mo, 1y, 12h, or 30min", value)
+	}
+	return d, nil
+}
+
+func ParseDuration(value string) (time.Duration, error) {
+	v := strings.TrimSpace(value)
+	if d, ok, err := parseCount(v, durationUnits); ok {
+		if err != nil {
+			return 0, fmt.Errorf("duration %q %w", value, err)
+		}
+		return d, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q: use a duration like 30s, 5m, 2h, or 1d", value)
+	}
+	return d, nil
+}
+
+func parseCount(v string, table []unitSuffix) (time.Duration, bool, error) {
+	for _, u := range table {
+		n, ok := strings.CutSuffix(v, u.suffix)
+		if !ok {
+			continue
+		}
+		count, err := strconv.ParseInt(n, 10, 64)
+		if err != nil || count < 0 {
+			return 0, false, nil
+		}
+		if count > int64(math.MaxInt64/u.unit) {
+			return 0, true, errors.New("is too large")
+		}
+		return time.Duration(count) * u.unit, true, nil
+	}
+	return 0, false, nil
+}
+
+func ParseSize(value string) (int64, error) {
+	m := sizeValue.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q: use a size like 500MB or 1GiB", value)
+	}
+	mult, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q", m[2])
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	if n*mult >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return int64(n * mult), nil
+}
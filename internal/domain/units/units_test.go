@@ -0,0 +1,57 @@
+package units
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int64{"1024": 1024, "1KiB": 1024, "1.5GB": 1_500_000_000, "2M": 2 << 20} {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"1e7TB", "10000000TB", "9999999TiB", "5XB", "-1MB", ""} {
+		if got, err := ParseSize(in); err == nil {
+			t.Fatalf("ParseSize(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestParseAgeUnits(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"7d":    7 * 24 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"6mo":   180 * 24 * time.Hour,
+		"1y":    365 * 24 * time.Hour,
+		"12h":   12 * time.Hour,
+		"30min": 30 * time.Minute,
+		"90s":   90 * time.Second,
+		"1h30s": time.Hour + 30*time.Second,
+	} {
+		got, err := ParseAge(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"6m0", "1m", "1h30m", "99999999y", "9999999999999d", "-1d", "soon"} {
+		if got, err := ParseAge(in); err == nil {
+			t.Fatalf("ParseAge(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseDurationTreatsMAsMinutes(t *testing.T) {
+	for in, want := range map[string]time.Duration{"5m": 5 * time.Minute, "30s": 30 * time.Second, "1d": 24 * time.Hour, "2w": 14 * 24 * time.Hour} {
+		got, err := ParseDuration(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"6mo", "99999999w", "-5m", "soon"} {
+		if got, err := ParseDuration(in); err == nil {
+			t.Fatalf("ParseDuration(%q) = %v, want an error", in, got)
+		}
+	}
+}
@@ -0,0 +1,624 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/rules"
+	"talpa/internal/domain/units"
+	"talpa/internal/infra/filesystem"
+	"talpa/internal/infra/logging"
+)
+
+const (
+	SourceDefault    = "default"
+	SourceEnv        = "env"
+	SourceFlag       = "flag"
+	systemConfigFile = "/etc/talpa/config.yaml"
+)
+
+type Config struct {
+	Profile         string
+	Profiles        []model.Profile
+	Whitelist       []string
+	Excludes        []string
+	PurgeRoots      []string
+	ScanConcurrency int
+	ScanTimeout     time.Duration
+	ScanIndex       bool
+	ScanIndexPath   string
+	ScanIndexMaxAge time.Duration
+	StatusInterval  int
+	OplogEnabled    bool
+	OplogPath       string
+	OplogMaxBytes   int64
+	OplogMaxAge     time.Duration
+	OplogRetention  time.Duration
+	OplogHashChain  bool
+	TrashDir        string
+	Sources         map[string]string
+}
+
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+type fileConfig struct {
+	Profile   *string                `yaml:"profile"`
+	Profiles  map[string]profileSpec `yaml:"profiles"`
+	Whitelist []string               `yaml:"whitelist"`
+	Excludes  []string               `yaml:"excludes"`
+	Purge     struct {
+		Roots []string `yaml:"roots"`
+	} `yaml:"purge"`
+	Scanner struct {
+		Concurrency *int    `yaml:"concurrency"`
+		Timeout     *string `yaml:"timeout"`
+		Index       *bool   `yaml:"index"`
+		IndexPath   *string `yaml:"index_path"`
+		IndexMaxAge *string `yaml:"index_max_age"`
+	} `yaml:"scanner"`
+	Status struct {
+		Interval *int `yaml:"interval"`
+	} `yaml:"status"`
+	Oplog struct {
+		Enabled   *bool   `yaml:"enabled"`
+		Path      *string `yaml:"path"`
+		MaxSize   *string `yaml:"max_size"`
+		MaxAge    *string `yaml:"max_age"`
+		Retention *string `yaml:"retention"`
+		HashChain *bool   `yaml:"hash_chain"`
+	} `yaml:"oplog"`
+	Trash struct {
+		Dir *string `yaml:"dir"`
+	} `yaml:"trash"`
+}
+
+func (s Store) Load(ctx context.Context) (Config, error) {
+	cfg, err := Defaults()
+	if err != nil {
+		return Config{}, err
+	}
+	for _, path := range ProfileFiles() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return Config{}, err
+		}
+		loaded, err := ParseProfiles(path, data)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.mergeProfiles(path, loaded)
+	}
+	for _, path := range ConfigFiles() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return Config{}, err
+		}
+		if err := cfg.applyFile(path, data); err != nil {
+			return Config{}, err
+		}
+	}
+	whitelist, err := s.LoadWhitelist(ctx)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(whitelist) > 0 {
+		path, _ := whitelistPath()
+		cfg.Whitelist = appendUnique(cfg.Whitelist, whitelist)
+		cfg.addSource("whitelist", path)
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func Defaults() (Config, error) {
+	logPath, err := logging.OperationLogPath()
+	if err != nil {
+		return Config{}, err
+	}
+	trashDir, err := defaultTrashDir()
+	if err != nil {
+		return Config{}, err
+	}
+	indexPath, err := filesystem.DefaultScanIndexPath()
+	if err != nil {
+		return Config{}, err
+	}
+	log := logging.DefaultOptions()
+	cfg := Config{
+		Profiles:        rules.BuiltinProfiles(),
+		ScanTimeout:     2 * time.Minute,
+		ScanIndex:       true,
+		ScanIndexPath:   indexPath,
+		ScanIndexMaxAge: filesystem.DefaultScanIndexMaxAge,
+		StatusInterval:  1,
+		OplogEnabled:    true,
+		OplogPath:       logPath,
+		OplogMaxBytes:   log.MaxBytes,
+		OplogMaxAge:     log.MaxAge,
+		OplogRetention:  log.Retention,
+		OplogHashChain:  log.HashChain,
+		TrashDir:        trashDir,
+		Sources:         map[string]string{},
+	}
+	for _, key := range settingKeys {
+		cfg.Sources[key] = SourceDefault
+	}
+	return cfg, nil
+}
+
+func ConfigFiles() []string {
+	files := []string{systemConfigFile}
+	if configHome, err := configDir(); err == nil {
+		files = append(files, filepath.Join(configHome, "talpa", "config.yaml"))
+	}
+	return files
+}
+
+func (c *Config) SetFlag(key string) {
+	c.Sources[key] = SourceFlag
+}
+
+func (c *Config) ApplyFlag(assignment string) error {
+	key, value, ok := strings.Cut(assignment, "=")
+	key = strings.TrimSpace(key)
+	if !ok || !isFlagKey(key) {
+		return fmt.Errorf("--set %q: expected key=value with one of: %s", assignment, strings.Join(flagKeys(), ", "))
+	}
+	var f fileConfig
+	if err := flagNode(key, value).Decode(&f); err != nil {
+		return fmt.Errorf("--set %s: %w", key, err)
+	}
+	return c.apply(SourceFlag, f)
+}
+
+func (c Config) LoggingOptions() logging.Options {
+	return logging.Options{
+		Path:      c.OplogPath,
+		MaxBytes:  c.OplogMaxBytes,
+		MaxAge:    c.OplogMaxAge,
+		Retention: c.OplogRetention,
+		HashChain: c.OplogHashChain,
+	}
+}
+
+var settingKeys = []string{
+	"profile",
+	"profiles",
+	"whitelist",
+	"excludes",
+	"purge.roots",
+	"scanner.concurrency",
+	"scanner.timeout",
+	"scanner.index",
+	"scanner.index_path",
+	"scanner.index_max_age",
+	"status.interval",
+	"oplog.enabled",
+	"oplog.path",
+	"oplog.max_size",
+	"oplog.max_age",
+	"oplog.retention",
+	"oplog.hash_chain",
+	"trash.dir",
+}
+
+func (c Config) Settings() []Setting {
+	values := map[string]any{
+		"profile":               c.Profile,
+		"profiles":              profileNames(c.Profiles),
+		"whitelist":             sortedList(c.Whitelist),
+		"excludes":              sortedList(c.Excludes),
+		"purge.roots":           sortedList(c.PurgeRoots),
+		"scanner.concurrency":   c.ScanConcurrency,
+		"scanner.timeout":       c.ScanTimeout.String(),
+		"scanner.index":         c.ScanIndex,
+		"scanner.index_path":    c.ScanIndexPath,
+		"scanner.index_max_age": c.ScanIndexMaxAge.String(),
+		"status.interval":       c.StatusInterval,
+		"oplog.enabled":         c.OplogEnabled,
+		"oplog.path":            c.OplogPath,
+		"oplog.max_size":        c.OplogMaxBytes,
+		"oplog.max_age":         c.OplogMaxAge.String(),
+		"oplog.retention":       c.OplogRetention.String(),
+		"oplog.hash_chain":      c.OplogHashChain,
+		"trash.dir":             c.TrashDir,
+	}
+	out := make([]Setting, 0, len(settingKeys))
+	for _, key := range settingKeys {
+		out = append(out, Setting{Key: key, Value: values[key], Source: c.Sources[key]})
+	}
+	return out
+}
+
+func (c *Config) applyFile(source string, data []byte) error {
+	var f fileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	return c.apply(source, f)
+}
+
+func (c *Config) apply(source string, f fileConfig) error {
+	wrap := func(err error) error { return fmt.Errorf("%s: %w", source, err) }
+
+	if f.Profile != nil {
+		c.Profile = strings.TrimSpace(*f.Profile)
+		c.Sources["profile"] = source
+	}
+	if len(f.Profiles) > 0 {
+		loaded, err := profilesFromSpecs(source, f.Profiles)
+		if err != nil {
+			return err
+		}
+		c.mergeProfiles(source, loaded)
+	}
+	if len(f.Whitelist) > 0 {
+		c.Whitelist = appendUnique(c.Whitelist, absPaths(f.Whitelist))
+		c.addSource("whitelist", source)
+	}
+	if len(f.Excludes) > 0 {
+		c.Excludes = appendUnique(c.Excludes, absPaths(f.Excludes))
+		c.addSource("excludes", source)
+	}
+	if f.Purge.Roots != nil {
+		c.PurgeRoots = absPaths(f.Purge.Roots)
+		c.Sources["purge.roots"] = source
+	}
+	if v := f.Scanner.Concurrency; v != nil {
+		if *v < 0 {
+			return wrap(errors.New("scanner.concurrency must be >= 0"))
+		}
+		c.ScanConcurrency = *v
+		c.Sources["scanner.concurrency"] = source
+	}
+	if v := f.Scanner.Timeout; v != nil {
+		d, err := parsePositiveDuration("scanner.timeout", *v)
+		if err != nil {
+			return wrap(err)
+		}
+		c.ScanTimeout = d
+		c.Sources["scanner.timeout"] = source
+	}
+	if v := f.Scanner.Index; v != nil {
+		c.ScanIndex = *v
+		c.Sources["scanner.index"] = source
+	}
+	if v := f.Scanner.IndexPath; v != nil {
+		p, err := absPath("scanner.index_path", *v)
+		if err != nil {
+			return wrap(err)
+		}
+		c.ScanIndexPath = p
+		c.Sources["scanner.index_path"] = source
+	}
+	if v := f.Scanner.IndexMaxAge; v != nil {
+		d, err := units.ParseAge(*v)
+		if err != nil {
+			return wrap(fmt.Errorf("scanner.index_max_age: %w", err))
+		}
+		c.ScanIndexMaxAge = d
+		c.Sources["scanner.index_max_age"] = source
+	}
+	if v := f.Status.Interval; v != nil {
+		if *v < 1 {
+			return wrap(errors.New("status.interval must be >= 1"))
+		}
+		c.StatusInterval = *v
+		c.Sources["status.interval"] = source
+	}
+	if v := f.Oplog.Enabled; v != nil {
+		c.OplogEnabled = *v
+		c.Sources["oplog.enabled"] = source
+	}
+	if v := f.Oplog.Path; v != nil {
+		p, err := absPath("oplog.path", *v)
+		if err != nil {
+			return wrap(err)
+		}
+		c.OplogPath = p
+		c.Sources["oplog.path"] = source
+	}
+	if v := f.Oplog.MaxSize; v != nil {
+		n, err := units.ParseSize(*v)
+		if err != nil {
+			return wrap(fmt.Errorf("oplog.max_size: %w", err))
+		}
+		c.OplogMaxBytes = n
+		c.Sources["oplog.max_size"] = source
+	}
+	if v := f.Oplog.MaxAge; v != nil {
+		d, err := units.ParseAge(*v)
+		if err != nil {
+			return wrap(fmt.Errorf("oplog.max_age: %w", err))
+		}
+		c.OplogMaxAge = d
+		c.Sources["oplog.max_age"] = source
+	}
+	if v := f.Oplog.Retention; v != nil {
+		d, err := units.ParseAge(*v)
+		if err != nil {
+			return wrap(fmt.Errorf("oplog.retention: %w", err))
+		}
+		c.OplogRetention = d
+		c.Sources["oplog.retention"] = source
+	}
+	if v := f.Oplog.HashChain; v != nil {
+		c.OplogHashChain = *v
+		c.Sources["oplog.hash_chain"] = source
+	}
+	if v := f.Trash.Dir; v != nil {
+		p, err := absPath("trash.dir", *v)
+		if err != nil {
+			return wrap(err)
+		}
+		c.TrashDir = p
+		c.Sources["trash.dir"] = source
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	env := func(name string) (string, bool) {
+		v, ok := lookup(name)
+		v = strings.TrimSpace(v)
+		return v, ok && v != ""
+	}
+	if v, ok := env("TALPA_PROFILE"); ok {
+		c.Profile = v
+		c.Sources["profile"] = SourceEnv
+	}
+	if v, ok := env("TALPA_PURGE_ROOTS"); ok {
+		c.PurgeRoots = absPaths(strings.Split(v, ","))
+		c.Sources["purge.roots"] = SourceEnv
+	}
+	if v, ok := env("TALPA_SCAN_CONCURRENCY"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("TALPA_SCAN_CONCURRENCY: invalid value %q", v)
+		}
+		c.ScanConcurrency = n
+		c.Sources["scanner.concurrency"] = SourceEnv
+	}
+	if v, ok := env("TALPA_SCAN_TIMEOUT"); ok {
+		d, err := parsePositiveDuration("TALPA_SCAN_TIMEOUT", v)
+		if err != nil {
+			return err
+		}
+		c.ScanTimeout = d
+		c.Sources["scanner.timeout"] = SourceEnv
+	}
+	if v, ok := env("TALPA_NO_SCAN_INDEX"); ok {
+		off, err := envBool("TALPA_NO_SCAN_INDEX", v)
+		if err != nil {
+			return err
+		}
+		c.ScanIndex = !off
+		c.Sources["scanner.index"] = SourceEnv
+	}
+	if v, ok := env("TALPA_NO_OPLOG"); ok {
+		off, err := envBool("TALPA_NO_OPLOG", v)
+		if err != nil {
+			return err
+		}
+		c.OplogEnabled = !off
+		c.Sources["oplog.enabled"] = SourceEnv
+	}
+	if v, ok := env("TALPA_OPLOG_PATH"); ok {
+		p, err := absPath("TALPA_OPLOG_PATH", v)
+		if err != nil {
+			return err
+		}
+		c.OplogPath = p
+		c.Sources["oplog.path"] = SourceEnv
+	}
+	if v, ok := env("TALPA_OPLOG_MAX_BYTES"); ok {
+		n, err := units.ParseSize(v)
+		if err != nil {
+			return fmt.Errorf("TALPA_OPLOG_MAX_BYTES: %w", err)
+		}
+		c.OplogMaxBytes = n
+		c.Sources["oplog.max_size"] = SourceEnv
+	}
+	if v, ok := env("TALPA_OPLOG_MAX_AGE_DAYS"); ok {
+		d, err := envDays("TALPA_OPLOG_MAX_AGE_DAYS", v)
+		if err != nil {
+			return err
+		}
+		c.OplogMaxAge = d
+		c.Sources["oplog.max_age"] = SourceEnv
+	}
+	if v, ok := env("TALPA_OPLOG_RETENTION_DAYS"); ok {
+		d, err := envDays("TALPA_OPLOG_RETENTION_DAYS", v)
+		if err != nil {
+			return err
+		}
+		c.OplogRetention = d
+		c.Sources["oplog.retention"] = SourceEnv
+	}
+	if v, ok := env("TALPA_OPLOG_HASH_CHAIN"); ok {
+		on, err := envBool("TALPA_OPLOG_HASH_CHAIN", v)
+		if err != nil {
+			return err
+		}
+		c.OplogHashChain = on
+		c.Sources["oplog.hash_chain"] = SourceEnv
+	}
+	if v, ok := env("TALPA_TRASH_DIR"); ok {
+		p, err := absPath("TALPA_TRASH_DIR", v)
+		if err != nil {
+			return err
+		}
+		c.TrashDir = p
+		c.Sources["trash.dir"] = SourceEnv
+	}
+	return nil
+}
+
+func (c *Config) mergeProfiles(source string, loaded []model.Profile) {
+	c.Profiles = rules.MergeProfiles(c.Profiles, loaded)
+	c.addSource("profiles", source)
+}
+
+func (c *Config) addSource(key, source string) {
+	if cur := c.Sources[key]; cur != "" && cur != SourceDefault {
+		c.Sources[key] = cur + ", " + source
+		return
+	}
+	c.Sources[key] = source
+}
+
+func defaultTrashDir() (string, error) {
+	if dataHome := strings.TrimSpace(os.Getenv("XDG_DATA_HOME")); dataHome != "" && filepath.IsAbs(dataHome) {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+func envBool(name, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid value %q: use 1/0 or true/false", name, value)
+	}
+	return b, nil
+}
+
+func envDays(name, value string) (time.Duration, error) {
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "d"
+	}
+	d, err := units.ParseAge(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+func isFlagKey(key string) bool {
+	for _, k := range flagKeys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func flagKeys() []string {
+	out := make([]string, 0, len(settingKeys))
+	for _, k := range settingKeys {
+		if k != "profiles" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func flagNode(key, value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: strings.TrimSpace(value)}
+	switch key {
+	case "whitelist", "excludes", "purge.roots":
+		node = &yaml.Node{Kind: yaml.SequenceNode}
+		for _, v := range strings.Split(value, ",") {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: strings.TrimSpace(v)})
+		}
+	}
+	parts := strings.Split(key, ".")
+	for i := len(parts) - 1; i >= 0; i-- {
+		node = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: parts[i]}, node}}
+	}
+	return node
+}
+
+func profileNames(profiles []model.Profile) []string {
+	out := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		out = append(out, p.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func parsePositiveDuration(key, value string) (time.Duration, error) {
+	d, err := units.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: invalid duration %q", key, value)
+	}
+	return d, nil
+}
+
+func absPath(key, value string) (string, error) {
+	p := expandHome(strings.TrimSpace(value))
+	if !filepath.IsAbs(p) {
+		return "", fmt.Errorf("%s: path must be absolute or start with ~/: %q", key, value)
+	}
+	return filepath.Clean(p), nil
+}
+
+func absPaths(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		p := filepath.Clean(expandHome(v))
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func appendUnique(base []string, extra []string) []string {
+	seen := make(map[string]struct{}, len(base)+len(extra))
+	for _, v := range base {
+		seen[v] = struct{}{}
+	}
+	for _, v := range extra {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		base = append(base, v)
+	}
+	return base
+}
+
+func sortedList(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	sorted := append([]string(nil), v...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadMergesUserConfigAndLegacyWhitelist(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", "")
+	dir := filepath.Join(home, ".config", "talpa")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	userFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(userFile, []byte(`
+whitelist: [~/keep]
+excludes: [~/vm]
+purge:
+  roots: [~/src]
+scanner:
+  concurrency: 4
+  timeout: 30s
+oplog:
+  max_size: 1MiB
+  retention: 90d
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "whitelist"), []byte("~/legacy\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewStore().Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(home, "keep"), filepath.Join(home, "legacy")}
+	if len(cfg.Whitelist) != 2 || cfg.Whitelist[0] != want[0] || cfg.Whitelist[1] != want[1] {
+		t.Fatalf("unexpected whitelist: %v", cfg.Whitelist)
+	}
+	if !strings.Contains(cfg.Sources["whitelist"], userFile) || !strings.Contains(cfg.Sources["whitelist"], filepath.Join(dir, "whitelist")) {
+		t.Fatalf("expected both whitelist sources, got %q", cfg.Sources["whitelist"])
+	}
+	if cfg.ScanConcurrency != 4 || cfg.ScanTimeout != 30*time.Second || cfg.Sources["scanner.timeout"] != userFile {
+		t.Fatalf("unexpected scanner settings: %+v", cfg)
+	}
+	if cfg.OplogMaxBytes != 1<<20 || cfg.OplogRetention != 90*24*time.Hour {
+		t.Fatalf("unexpected oplog settings: %d %s", cfg.OplogMaxBytes, cfg.OplogRetention)
+	}
+	if cfg.Sources["status.interval"] != SourceDefault || cfg.TrashDir != filepath.Join(home, ".local", "share", "Trash") {
+		t.Fatalf("expected untouched defaults, got %+v", cfg)
+	}
+}
+
+func TestApplyLayersLaterFilesAndEnvOverride(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	cfg, err := Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.applyFile("/etc/talpa/config.yaml", []byte("scanner: {timeout: 5m}\nstatus: {interval: 5}\npurge: {roots: [/srv]}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.applyFile("user.yaml", []byte("status: {interval: 2}\n")); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"TALPA_SCAN_TIMEOUT": "10s", "TALPA_NO_OPLOG": "1"}
+	if err := cfg.applyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetFlag("profile")
+
+	sources := map[string]string{}
+	for _, s := range cfg.Settings() {
+		sources[s.Key] = s.Source
+	}
+	want := map[string]string{
+		"purge.roots":     "/etc/talpa/config.yaml",
+		"status.interval": "user.yaml",
+		"scanner.timeout": SourceEnv,
+		"oplog.enabled":   SourceEnv,
+		"profile":         SourceFlag,
+		"trash.dir":       SourceDefault,
+	}
+	for key, src := range want {
+		if sources[key] != src {
+			t.Fatalf("%s: expected source %q, got %q", key, src, sources[key])
+		}
+	}
+	if cfg.StatusInterval != 2 || cfg.ScanTimeout != 10*time.Second || cfg.OplogEnabled {
+		t.Fatalf("unexpected merged values: %+v", cfg)
+	}
+}
+
+func TestApplyFileRejectsInvalid(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	for _, data := range []string{
+		"scanner: {concurrency: -1}\n",
+		"scanner: {timeout: 0s}\n",
+		"status: {interval: 0}\n",
+		"oplog: {max_size: lots}\n",
+		"oplog: {path: relative/log}\n",
+		"unknown: true\n",
+	} {
+		cfg, err := Defaults()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.applyFile("c.yaml", []byte(data)); err == nil {
+			t.Fatalf("expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestApplyEnvParsesUnits(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	cfg, err := Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"TALPA_OPLOG_MAX_BYTES":      "10MB",
+		"TALPA_OPLOG_MAX_AGE_DAYS":   "7",
+		"TALPA_OPLOG_RETENTION_DAYS": "30d",
+		"TALPA_OPLOG_HASH_CHAIN":     "false",
+		"TALPA_NO_SCAN_INDEX":        "true",
+	}
+	if err := cfg.applyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OplogMaxBytes != 10e6 || cfg.OplogMaxAge != 7*24*time.Hour || cfg.OplogRetention != 30*24*time.Hour {
+		t.Fatalf("unexpected oplog settings: %d %s %s", cfg.OplogMaxBytes, cfg.OplogMaxAge, cfg.OplogRetention)
+	}
+	if cfg.OplogHashChain || cfg.ScanIndex || cfg.Sources["scanner.index"] != SourceEnv {
+		t.Fatalf("unexpected boolean settings: %+v", cfg)
+	}
+}
+
+func TestApplyEnvRejectsInvalid(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	for name, value := range map[string]string{
+		"TALPA_SCAN_CONCURRENCY":     "-1",
+		"TALPA_OPLOG_MAX_BYTES":      "lots",
+		"TALPA_OPLOG_MAX_AGE_DAYS":   "-3",
+		"TALPA_OPLOG_RETENTION_DAYS": "3m",
+		"TALPA_OPLOG_HASH_CHAIN":     "yes please",
+		"TALPA_NO_OPLOG":             "2",
+		"TALPA_NO_SCAN_INDEX":        "on",
+	} {
+		cfg, err := Defaults()
+		if err != nil {
+			t.Fatal(err)
+		}
+		lookup := func(k string) (string, bool) { return value, k == name }
+		if err := cfg.applyEnv(lookup); err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("expected %s=%q to be rejected, got %v", name, value, err)
+		}
+	}
+}
+
+func TestApplyFileDefinesProfiles(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	cfg, err := Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("profile: runner\nprofiles:\n  runner:\n    disable_categories: [browser_cache]\n    defaults: {recent_days: 2}\n")
+	if err := cfg.applyFile("user.yaml", data); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.applyFile("later.yaml", []byte("profiles:\n  dev: {description: replaced}\n")); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]string{}
+	for _, p := range cfg.Profiles {
+		byName[p.Name] = p.Source
+	}
+	if byName["runner"] != "user.yaml" || byName["dev"] != "later.yaml" || byName["ci"] != "builtin" {
+		t.Fatalf("unexpected profiles: %v", byName)
+	}
+	if cfg.Sources["profiles"] != "user.yaml, later.yaml" {
+		t.Fatalf("expected both files as profile sources, got %q", cfg.Sources["profiles"])
+	}
+	if err := cfg.applyFile("bad.yaml", []byte("profiles:\n  x: {defaults: {recent_days: -1}}\n")); err == nil {
+		t.Fatal("expected an invalid profile to be rejected")
+	}
+}
+
+func TestApplyFlagOverridesEnv(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	cfg, err := Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"TALPA_SCAN_TIMEOUT": "10s", "TALPA_OPLOG_MAX_BYTES": "1MiB"}
+	if err := cfg.applyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err != nil {
+		t.Fatal(err)
+	}
+	for _, assignment := range []string{
+		"scanner.timeout=30s",
+		"oplog.max_size=2048",
+		"oplog.hash_chain=true",
+		"purge.roots=~/src, /srv",
+	} {
+		if err := cfg.ApplyFlag(assignment); err != nil {
+			t.Fatalf("%s: %v", assignment, err)
+		}
+	}
+	if cfg.ScanTimeout != 30*time.Second || cfg.OplogMaxBytes != 2048 || !cfg.OplogHashChain {
+		t.Fatalf("expected flags to win over env, got %+v", cfg)
+	}
+	if len(cfg.PurgeRoots) != 2 || cfg.PurgeRoots[0] != filepath.Join("/home/u", "src") || cfg.PurgeRoots[1] != "/srv" {
+		t.Fatalf("unexpected purge roots: %v", cfg.PurgeRoots)
+	}
+	for _, key := range []string{"scanner.timeout", "oplog.max_size", "purge.roots"} {
+		if cfg.Sources[key] != SourceFlag {
+			t.Fatalf("%s: expected source %q, got %q", key, SourceFlag, cfg.Sources[key])
+		}
+	}
+	for _, assignment := range []string{"scanner.timeout", "nope=1", "profiles=x", "scanner.concurrency=many", "scanner.timeout=0s"} {
+		if err := cfg.ApplyFlag(assignment); err == nil {
+			t.Fatalf("expected --set %q to be rejected", assignment)
+		}
+	}
+}
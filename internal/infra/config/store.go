@@ -2,10 +2,25 @@ package config
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"talpa/internal/domain/model"
+	"talpa/internal/domain/rules"
+)
+
+const (
+	systemRulesDir     = "/etc/talpa/rules.d"
+	systemProfilesFile = "/etc/talpa/profiles.yaml"
 )
 
 type Store struct{}
@@ -56,13 +71,152 @@ func expandHome(path string) string {
 }
 
 func whitelistPath() (string, error) {
-	configHome := os.Getenv("XDG_CONFIG_HOME")
-	if configHome == "" {
-		home, err := os.UserHomeDir()
+	configHome, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configHome, "talpa", "whitelist"), nil
+}
+
+func configDir() (string, error) {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return configHome, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config"), nil
+}
+
+func (Store) LoadRules(ctx context.Context) ([]model.Rule, []rules.Issue, error) {
+	files, err := RuleFiles(ctx, RuleDirs())
+	if err != nil {
+		return nil, nil, err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	loaded, issues := rules.LoadPlugins(files, home, os.LookupEnv)
+	return loaded, issues, nil
+}
+
+func RuleDirs() []string {
+	dirs := []string{systemRulesDir}
+	if configHome, err := configDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configHome, "talpa", "rules.d"))
+	}
+	return dirs
+}
+
+func RuleFiles(ctx context.Context, dirs []string) ([]rules.PluginFile, error) {
+	var out []rules.PluginFile
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
 		if err != nil {
-			return "", err
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if e.IsDir() || !isRuleFile(e.Name()) {
+				continue
+			}
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			path := filepath.Join(dir, name)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rules.PluginFile{Path: path, Data: data})
 		}
-		configHome = filepath.Join(home, ".config")
 	}
-	return filepath.Join(configHome, "talpa", "whitelist"), nil
+	return out, nil
+}
+
+func isRuleFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return !strings.HasPrefix(name, ".")
+	}
+	return false
+}
+
+type profileDoc struct {
+	Profiles map[string]profileSpec `yaml:"profiles"`
+}
+
+type profileSpec struct {
+	Description       string   `yaml:"description"`
+	EnableRules       []string `yaml:"enable_rules"`
+	DisableRules      []string `yaml:"disable_rules"`
+	EnableCategories  []string `yaml:"enable_categories"`
+	DisableCategories []string `yaml:"disable_categories"`
+	Defaults          struct {
+		PurgePaths   []string `yaml:"purge_paths"`
+		RecentDays   int      `yaml:"recent_days"`
+		AnalyzeDepth int      `yaml:"analyze_depth"`
+		System       *bool    `yaml:"system"`
+	} `yaml:"defaults"`
+}
+
+func ProfileFiles() []string {
+	files := []string{systemProfilesFile}
+	if configHome, err := configDir(); err == nil {
+		files = append(files, filepath.Join(configHome, "talpa", "profiles.yaml"))
+	}
+	return files
+}
+
+func ParseProfiles(source string, data []byte) ([]model.Profile, error) {
+	var doc profileDoc
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return profilesFromSpecs(source, doc.Profiles)
+}
+
+func profilesFromSpecs(source string, specs map[string]profileSpec) ([]model.Profile, error) {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]model.Profile, 0, len(names))
+	for _, name := range names {
+		spec := specs[name]
+		p := model.Profile{
+			Name:              name,
+			Description:       spec.Description,
+			EnableRules:       spec.EnableRules,
+			DisableRules:      spec.DisableRules,
+			EnableCategories:  spec.EnableCategories,
+			DisableCategories: spec.DisableCategories,
+			Defaults: model.ProfileDefaults{
+				RecentDays:   spec.Defaults.RecentDays,
+				AnalyzeDepth: spec.Defaults.AnalyzeDepth,
+				System:       spec.Defaults.System,
+			},
+			Source: source,
+		}
+		for _, path := range spec.Defaults.PurgePaths {
+			p.Defaults.PurgePaths = append(p.Defaults.PurgePaths, expandHome(path))
+		}
+		if err := rules.ValidateProfile(p); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
 }
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseProfiles(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	data := []byte(`
+profiles:
+  runner:
+    description: CI runners
+    disable_categories: [browser_cache]
+    defaults:
+      purge_paths: [~/work, /srv/build]
+      recent_days: 2
+      analyze_depth: 6
+      system: true
+`)
+	got, err := ParseProfiles("profiles.yaml", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "runner" || got[0].Source != "profiles.yaml" {
+		t.Fatalf("unexpected profiles: %+v", got)
+	}
+	d := got[0].Defaults
+	if d.RecentDays != 2 || d.AnalyzeDepth != 6 || d.System == nil || !*d.System {
+		t.Fatalf("unexpected defaults: %+v", d)
+	}
+	if len(d.PurgePaths) != 2 || d.PurgePaths[0] != filepath.Join("/home/u", "work") {
+		t.Fatalf("expected ~ to expand in purge paths, got %v", d.PurgePaths)
+	}
+}
+
+func TestParseProfilesRejectsInvalid(t *testing.T) {
+	for _, data := range []string{
+		"profiles:\n  Bad Name: {}\n",
+		"profiles:\n  x: {enable_rules: ['[']}\n",
+		"profiles:\n  x: {defaults: {recent_days: -1}}\n",
+		"profiles:\n  x: {unknown: 1}\n",
+	} {
+		if _, err := ParseProfiles("p.yaml", []byte(data)); err == nil {
+			t.Fatalf("expected %q to be rejected", data)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package filesystem
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func SameContent(a, b string) (bool, error) {
+	ai, err := os.Lstat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Lstat(b)
+	if err != nil {
+		return false, err
+	}
+	if !ai.Mode().IsRegular() || !bi.Mode().IsRegular() || ai.Size() != bi.Size() {
+		return false, nil
+	}
+	ah, err := hashFile(a, 0)
+	if err != nil {
+		return false, err
+	}
+	bh, err := hashFile(b, 0)
+	if err != nil {
+		return false, err
+	}
+	return ah == bh, nil
+}
+
+func ReplaceWithHardlink(canonical, dup string) error {
+	if err := checkDedupPair(canonical, dup); err != nil {
+		return err
+	}
+	return replaceVia(dup, func(tmp string) error {
+		return os.Link(canonical, tmp)
+	})
+}
+
+func ReplaceWithReflink(canonical, dup string) error {
+	if err := checkDedupPair(canonical, dup); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dup)
+	if err != nil {
+		return err
+	}
+	return replaceVia(dup, func(tmp string) error {
+		src, err := os.Open(canonical)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if err := cloneFile(dst, src); err != nil {
+			_ = dst.Close()
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+		return os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	})
+}
+
+func VerifyDuplicate(canonical, dup string) error {
+	_, err := verifyDuplicate(canonical, dup)
+	return err
+}
+
+func checkDedupPair(canonical, dup string) error {
+	sameDevice, err := verifyDuplicate(canonical, dup)
+	if err != nil {
+		return err
+	}
+	if !sameDevice {
+		return fmt.Errorf("%s and %s are on different filesystems", dup, canonical)
+	}
+	return nil
+}
+
+func verifyDuplicate(canonical, dup string) (bool, error) {
+	if filepath.Clean(canonical) == filepath.Clean(dup) {
+		return false, errors.New("duplicate and canonical copy are the same path")
+	}
+	same, err := SameContent(canonical, dup)
+	if err != nil {
+		return false, err
+	}
+	if !same {
+		return false, fmt.Errorf("%s no longer matches %s", dup, canonical)
+	}
+	cdev, cino, err := PathIdentity(canonical)
+	if err != nil {
+		return false, err
+	}
+	ddev, dino, err := PathIdentity(dup)
+	if err != nil {
+		return false, err
+	}
+	if cdev == ddev && cino != 0 && cino == dino {
+		return false, fmt.Errorf("%s is already linked to %s", dup, canonical)
+	}
+	return cdev == ddev, nil
+}
+
+func replaceVia(dup string, create func(tmp string) error) error {
+	for attempt := 0; attempt < 5; attempt++ {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		tmp := filepath.Join(filepath.Dir(dup), "."+filepath.Base(dup)+".talpa-dedup-"+hex.EncodeToString(buf))
+		if err := create(tmp); err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			_ = os.Remove(tmp)
+			return err
+		}
+		if err := os.Rename(tmp, dup); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("could not create a temporary entry next to %s", dup)
+}
@@ -0,0 +1,185 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const partialHashBytes = 64 << 10
+
+type DuplicateOptions struct {
+	MinSizeBytes int64
+	Concurrency  int
+	Context      context.Context
+}
+
+type DuplicateSet struct {
+	Hash        string
+	SizeBytes   int64
+	WastedBytes int64
+	Files       []ScanItem
+}
+
+type hashGroup struct {
+	hash  string
+	items []ScanItem
+}
+
+var hashFile = fileHash
+
+func FindDuplicates(items []ScanItem, opts DuplicateOptions) ([]DuplicateSet, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = runtime.NumCPU()
+		if opts.Concurrency < 2 {
+			opts.Concurrency = 2
+		}
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	minSize := opts.MinSizeBytes
+	if minSize < 1 {
+		minSize = 1
+	}
+
+	bySize := map[int64][]ScanItem{}
+	seen := map[linkKey]bool{}
+	for _, it := range items {
+		if it.IsDir || it.SizeBytes < minSize {
+			continue
+		}
+		if it.Device != 0 || it.Inode != 0 {
+			key := linkKey{dev: it.Device, ino: it.Inode}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		bySize[it.SizeBytes] = append(bySize[it.SizeBytes], it)
+	}
+	var groups [][]ScanItem
+	for _, g := range bySize {
+		if len(g) > 1 {
+			groups = append(groups, g)
+		}
+	}
+
+	partial, err := refineGroups(ctx, groups, opts.Concurrency, partialHashBytes)
+	if err != nil {
+		return nil, err
+	}
+	var done []hashGroup
+	var large [][]ScanItem
+	for _, g := range partial {
+		if g.items[0].SizeBytes <= partialHashBytes {
+			done = append(done, g)
+		} else {
+			large = append(large, g.items)
+		}
+	}
+	full, err := refineGroups(ctx, large, opts.Concurrency, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]DuplicateSet, 0, len(done)+len(full))
+	for _, g := range append(done, full...) {
+		files := g.items
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+		sets = append(sets, DuplicateSet{
+			Hash:        g.hash,
+			SizeBytes:   files[0].SizeBytes,
+			WastedBytes: files[0].SizeBytes * int64(len(files)-1),
+			Files:       files,
+		})
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].WastedBytes != sets[j].WastedBytes {
+			return sets[i].WastedBytes > sets[j].WastedBytes
+		}
+		return sets[i].Files[0].Path < sets[j].Files[0].Path
+	})
+	return sets, nil
+}
+
+func refineGroups(ctx context.Context, groups [][]ScanItem, concurrency int, limit int64) ([]hashGroup, error) {
+	type job struct {
+		group int
+		item  ScanItem
+	}
+	jobs := make(chan job)
+	var (
+		mu     sync.Mutex
+		hashed = make([]map[string][]ScanItem, len(groups))
+	)
+	for i := range hashed {
+		hashed[i] = map[string][]ScanItem{}
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range jobs {
+				h, err := hashFile(j.item.Path, limit)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				hashed[j.group][h] = append(hashed[j.group][h], j.item)
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for gi, g := range groups {
+		for _, it := range g {
+			select {
+			case <-ctx.Done():
+				break feed
+			case jobs <- job{group: gi, item: it}:
+			}
+		}
+	}
+	close(jobs)
+	workers.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []hashGroup
+	for _, m := range hashed {
+		for h, g := range m {
+			if len(g) > 1 {
+				out = append(out, hashGroup{hash: h, items: g})
+			}
+		}
+	}
+	return out, nil
+}
+
+func fileHash(path string, limit int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if limit > 0 {
+		r = io.LimitReader(f, limit)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
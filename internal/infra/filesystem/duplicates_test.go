@@ -0,0 +1,128 @@
+package filesystem
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindDuplicatesGroupsBySizeAndHash(t *testing.T) {
+	root := t.TempDir()
+	big := bytes.Repeat([]byte("x"), partialHashBytes+100)
+	tail := append(append([]byte(nil), big[:len(big)-1]...), 'y')
+	files := map[string][]byte{
+		"a/image.iso":   big,
+		"b/image.iso":   big,
+		"c/near.iso":    tail,
+		"d/notes.txt":   []byte("same"),
+		"e/notes.txt":   []byte("same"),
+		"f/unique.txt":  []byte("uniq"),
+		"g/empty.txt":   nil,
+		"h/empty-2.txt": nil,
+	}
+	for rel, data := range files {
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(root, "a", "image.iso"), filepath.Join(root, "a", "image-link.iso")); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := Scan(root, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets, err := FindDuplicates(items, DuplicateOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 2 {
+		t.Fatalf("expected two duplicate sets, got %+v", sets)
+	}
+	first := sets[0]
+	if first.SizeBytes != int64(len(big)) || first.WastedBytes != int64(len(big)) || len(first.Files) != 2 {
+		t.Fatalf("unexpected large set: %+v", first)
+	}
+	if first.Files[1].Path != filepath.Join(root, "b", "image.iso") || first.Hash == "" {
+		t.Fatalf("unexpected large set members: %+v", first.Files)
+	}
+	if sets[1].SizeBytes != 4 || len(sets[1].Files) != 2 || sets[1].Files[0].Path != filepath.Join(root, "d", "notes.txt") {
+		t.Fatalf("unexpected small set: %+v", sets[1])
+	}
+}
+
+func TestReplaceWithHardlinkSharesInode(t *testing.T) {
+	dir := t.TempDir()
+	canonical := filepath.Join(dir, "keep.bin")
+	dup := filepath.Join(dir, "copy.bin")
+	other := filepath.Join(dir, "other.bin")
+	for p, data := range map[string]string{canonical: "payload", dup: "payload", other: "changed"} {
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ReplaceWithHardlink(canonical, other); err == nil {
+		t.Fatal("expected content mismatch to be refused")
+	}
+	if err := ReplaceWithHardlink(canonical, dup); err != nil {
+		t.Fatal(err)
+	}
+	cdev, cino, _ := PathIdentity(canonical)
+	ddev, dino, _ := PathIdentity(dup)
+	if cdev != ddev || cino != dino {
+		t.Fatalf("expected %s to be linked to %s", dup, canonical)
+	}
+	if err := ReplaceWithHardlink(canonical, dup); err == nil {
+		t.Fatal("expected already-linked pair to be refused")
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, ".copy.bin.talpa-dedup*")); len(left) != 0 {
+		t.Fatalf("temporary link left behind: %v", left)
+	}
+}
+
+func TestReplaceViaNeverReusesExistingTempEntry(t *testing.T) {
+	dir := t.TempDir()
+	canonical := filepath.Join(dir, "keep.bin")
+	dup := filepath.Join(dir, "copy.bin")
+	for _, p := range []string{canonical, dup} {
+		if err := os.WriteFile(p, []byte("payload"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	planted := filepath.Join(dir, ".copy.bin.talpa-dedup")
+	if err := os.WriteFile(planted, []byte("planted"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var squatted string
+	err := replaceVia(dup, func(tmp string) error {
+		if squatted == "" {
+			squatted = tmp
+			if err := os.WriteFile(tmp, []byte("someone else's"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return os.Link(canonical, tmp)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(squatted); err != nil || string(data) != "someone else's" {
+		t.Fatalf("expected the colliding entry to be left alone, got %q (%v)", data, err)
+	}
+	if _, err := os.Lstat(planted); err != nil {
+		t.Fatalf("expected the planted entry to be left alone: %v", err)
+	}
+	cdev, cino, _ := PathIdentity(canonical)
+	ddev, dino, _ := PathIdentity(dup)
+	if cdev != ddev || cino != dino {
+		t.Fatalf("expected %s to be linked to %s after retrying", dup, canonical)
+	}
+}
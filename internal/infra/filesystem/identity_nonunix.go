@@ -9,3 +9,35 @@ func statIdentity(fi os.FileInfo) (uint64, uint64) {
 	_ = fi
 	return 0, 0
 }
+
+func allocatedSize(fi os.FileInfo) int64 {
+	if fi == nil || fi.IsDir() {
+		return 0
+	}
+	return fi.Size()
+}
+
+func linkCount(fi os.FileInfo) uint64 {
+	_ = fi
+	return 1
+}
+
+func fileOwner(fi os.FileInfo) uint32 {
+	_ = fi
+	return 0
+}
+
+func PathIdentity(path string) (uint64, uint64, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, nil
+}
+
+func lstatStamp(path string) (dirStamp, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return dirStamp{}, err
+	}
+	return dirStamp{modTime: fi.ModTime()}, nil
+}
@@ -6,6 +6,9 @@ package filesystem
 import (
 	"os"
 	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
 )
 
 func statIdentity(fi os.FileInfo) (uint64, uint64) {
@@ -18,3 +21,58 @@ func statIdentity(fi os.FileInfo) (uint64, uint64) {
 	}
 	return uint64(st.Dev), uint64(st.Ino)
 }
+
+func allocatedSize(fi os.FileInfo) int64 {
+	if fi == nil {
+		return 0
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fi.Size()
+	}
+	return int64(st.Blocks) * 512
+}
+
+func linkCount(fi os.FileInfo) uint64 {
+	if fi == nil {
+		return 1
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 1
+	}
+	return uint64(st.Nlink)
+}
+
+func fileOwner(fi os.FileInfo) uint32 {
+	if fi == nil {
+		return 0
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return st.Uid
+}
+
+func PathIdentity(path string) (uint64, uint64, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	dev, ino := statIdentity(fi)
+	return dev, ino, nil
+}
+
+func lstatStamp(path string) (dirStamp, error) {
+	var st unix.Stat_t
+	if err := unix.Lstat(path, &st); err != nil {
+		return dirStamp{}, err
+	}
+	return dirStamp{
+		device:     uint64(st.Dev),
+		inode:      uint64(st.Ino),
+		modTime:    time.Unix(int64(st.Mtim.Sec), int64(st.Mtim.Nsec)),
+		changeTime: time.Unix(int64(st.Ctim.Sec), int64(st.Ctim.Nsec)),
+	}, nil
+}
@@ -0,0 +1,343 @@
+package filesystem
+
+import (
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const scanIndexVersion = 3
+
+const DefaultScanIndexMaxAge = 24 * time.Hour
+
+var (
+	scanIndexSettle     = 2 * time.Second
+	scanIndexShardDepth = 3
+)
+
+type ScanIndex struct {
+	mu      sync.Mutex
+	path    string
+	maxAge  time.Duration
+	refresh bool
+	shards  map[string]*indexShard
+	visited map[string]bool
+	hits    int
+	misses  int
+}
+
+type indexShard struct {
+	key   string
+	dirs  map[string]indexedDir
+	dirty bool
+}
+
+type indexedDir struct {
+	Device     uint64
+	Inode      uint64
+	ModTime    int64
+	ChangeTime int64
+	VerifiedAt int64
+	Children   []indexedEntry
+}
+
+type indexedEntry struct {
+	Name           string
+	SizeBytes      int64
+	AllocatedBytes int64
+	ModTime        int64
+	Device         uint64
+	Inode          uint64
+	Links          uint64
+	UID            uint32
+	IsDir          bool
+}
+
+type dirStamp struct {
+	device     uint64
+	inode      uint64
+	modTime    time.Time
+	changeTime time.Time
+}
+
+type scanIndexHeader struct {
+	Version int
+	Key     string
+}
+
+func DefaultScanIndexPath() (string, error) {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "talpa", "scan-index"), nil
+}
+
+func NewScanIndex(path string, maxAge time.Duration) *ScanIndex {
+	idx := OpenScanIndex(path, maxAge)
+	idx.refresh = true
+	return idx
+}
+
+func OpenScanIndex(path string, maxAge time.Duration) *ScanIndex {
+	return &ScanIndex{path: path, maxAge: maxAge, shards: map[string]*indexShard{}, visited: map[string]bool{}}
+}
+
+func (x *ScanIndex) Save() error {
+	if x == nil {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err := os.MkdirAll(x.path, 0o700); err != nil {
+		return err
+	}
+	for _, s := range x.shards {
+		if !s.dirty {
+			continue
+		}
+		if err := x.saveShard(s); err != nil {
+			return err
+		}
+		s.dirty = false
+	}
+	return nil
+}
+
+func (x *ScanIndex) Stats() (hits, misses int) {
+	if x == nil {
+		return 0, 0
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.hits, x.misses
+}
+
+func (x *ScanIndex) lookup(dir string) ([]ScanItem, dirStamp, bool) {
+	if x == nil {
+		return nil, dirStamp{}, false
+	}
+	stamp, err := lstatStamp(dir)
+	if err != nil {
+		return nil, dirStamp{}, false
+	}
+
+	x.mu.Lock()
+	x.visited[dir] = true
+	cached, ok := x.shard(dir).dirs[dir]
+	if x.refresh || !ok || !cached.matches(stamp) || x.expired(cached) {
+		x.misses++
+		x.mu.Unlock()
+		return nil, stamp, false
+	}
+	x.hits++
+	x.mu.Unlock()
+	return cached.items(dir), stamp, true
+}
+
+func (x *ScanIndex) store(dir string, stamp dirStamp, children []ScanItem) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	s := x.shard(dir)
+	s.dirty = true
+	if !stamp.settled() {
+		delete(s.dirs, dir)
+		return
+	}
+	entries := make([]indexedEntry, 0, len(children))
+	for _, c := range children {
+		entries = append(entries, indexedEntry{
+			Name:           filepath.Base(c.Path),
+			SizeBytes:      c.SizeBytes,
+			AllocatedBytes: c.AllocatedBytes,
+			ModTime:        c.LastModified.UnixNano(),
+			Device:         c.Device,
+			Inode:          c.Inode,
+			Links:          c.Links,
+			UID:            c.UID,
+			IsDir:          c.IsDir,
+		})
+	}
+	s.dirs[dir] = indexedDir{
+		Device:     stamp.device,
+		Inode:      stamp.inode,
+		ModTime:    stamp.modTime.UnixNano(),
+		ChangeTime: unixNano(stamp.changeTime),
+		VerifiedAt: time.Now().UnixNano(),
+		Children:   entries,
+	}
+}
+
+func (x *ScanIndex) prune(root string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, s := range x.shards {
+		for dir := range s.dirs {
+			if !x.visited[dir] && withinRoot(dir, root) {
+				delete(s.dirs, dir)
+				s.dirty = true
+			}
+		}
+	}
+	entries, err := os.ReadDir(x.path)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".gob") {
+			continue
+		}
+		header, ok := readShardHeader(filepath.Join(x.path, entry.Name()))
+		if !ok {
+			continue
+		}
+		if _, loaded := x.shards[header.Key]; loaded || !withinRoot(header.Key, root) {
+			continue
+		}
+		x.shards[header.Key] = &indexShard{key: header.Key, dirs: map[string]indexedDir{}, dirty: true}
+	}
+}
+
+func (x *ScanIndex) shard(dir string) *indexShard {
+	key := shardKey(dir)
+	if s, ok := x.shards[key]; ok {
+		return s
+	}
+	s := &indexShard{key: key, dirs: map[string]indexedDir{}}
+	if dirs, ok := loadShard(x.shardPath(key), key); ok {
+		s.dirs = dirs
+	}
+	x.shards[key] = s
+	return s
+}
+
+func (x *ScanIndex) shardPath(key string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return filepath.Join(x.path, fmt.Sprintf("%016x.gob", h.Sum64()))
+}
+
+func (x *ScanIndex) saveShard(s *indexShard) error {
+	path := x.shardPath(s.key)
+	if len(s.dirs) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	tmp, err := os.CreateTemp(x.path, ".shard-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	enc := gob.NewEncoder(tmp)
+	if err := enc.Encode(scanIndexHeader{Version: scanIndexVersion, Key: s.key}); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := enc.Encode(s.dirs); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (x *ScanIndex) expired(d indexedDir) bool {
+	return x.maxAge > 0 && time.Since(time.Unix(0, d.VerifiedAt)) > x.maxAge
+}
+
+func loadShard(path, key string) (map[string]indexedDir, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(f)
+	var header scanIndexHeader
+	if err := dec.Decode(&header); err != nil || header.Version != scanIndexVersion || header.Key != key {
+		return nil, false
+	}
+	var dirs map[string]indexedDir
+	if err := dec.Decode(&dirs); err != nil || dirs == nil {
+		return nil, false
+	}
+	return dirs, true
+}
+
+func readShardHeader(path string) (scanIndexHeader, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return scanIndexHeader{}, false
+	}
+	defer f.Close()
+	var header scanIndexHeader
+	if err := gob.NewDecoder(f).Decode(&header); err != nil || header.Version != scanIndexVersion || header.Key == "" {
+		return scanIndexHeader{}, false
+	}
+	return header, true
+}
+
+func shardKey(dir string) string {
+	key := filepath.Clean(dir)
+	for strings.Count(filepath.ToSlash(key), "/") > scanIndexShardDepth {
+		key = filepath.Dir(key)
+	}
+	return key
+}
+
+func (d indexedDir) matches(stamp dirStamp) bool {
+	return d.Device == stamp.device &&
+		d.Inode == stamp.inode &&
+		d.ModTime == stamp.modTime.UnixNano() &&
+		d.ChangeTime == unixNano(stamp.changeTime)
+}
+
+func (d indexedDir) items(dir string) []ScanItem {
+	items := make([]ScanItem, 0, len(d.Children))
+	for _, c := range d.Children {
+		items = append(items, ScanItem{
+			Path:           filepath.Join(dir, c.Name),
+			SizeBytes:      c.SizeBytes,
+			AllocatedBytes: c.AllocatedBytes,
+			LastModified:   time.Unix(0, c.ModTime).UTC(),
+			Device:         c.Device,
+			Inode:          c.Inode,
+			Links:          c.Links,
+			UID:            c.UID,
+			IsDir:          c.IsDir,
+		})
+	}
+	return items
+}
+
+func (s dirStamp) settled() bool {
+	if s.modTime.IsZero() || time.Since(s.modTime) < scanIndexSettle {
+		return false
+	}
+	return s.changeTime.IsZero() || time.Since(s.changeTime) >= scanIndexSettle
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
@@ -0,0 +1,245 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withoutIndexSettle(t *testing.T) {
+	t.Helper()
+	prev := scanIndexSettle
+	scanIndexSettle = 0
+	t.Cleanup(func() { scanIndexSettle = prev })
+}
+
+func indexedDirs(idx *ScanIndex) map[string]bool {
+	out := map[string]bool{}
+	for _, s := range idx.shards {
+		for dir := range s.dirs {
+			out[dir] = true
+		}
+	}
+	return out
+}
+
+func TestScanIndexReusesUnchangedDirectories(t *testing.T) {
+	withoutIndexSettle(t)
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "a.txt"), []byte("aaaa"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(t.TempDir(), "talpa", "scan-index")
+
+	first := OpenScanIndex(indexPath, time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: first}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, misses := first.Stats(); hits != 0 || misses != 2 {
+		t.Fatalf("expected a cold index to miss both dirs, got hits=%d misses=%d", hits, misses)
+	}
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	warm := OpenScanIndex(indexPath, time.Hour)
+	items, err := Scan(root, ScanOptions{Index: warm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, misses := warm.Stats(); hits != 2 || misses != 0 {
+		t.Fatalf("expected both dirs to be reused, got hits=%d misses=%d", hits, misses)
+	}
+	if len(items) != 1 || items[0].Path != filepath.Join(sub, "a.txt") || items[0].SizeBytes != 4 {
+		t.Fatalf("expected the cached listing, got %+v", items)
+	}
+
+	if err := os.WriteFile(filepath.Join(sub, "b.txt"), []byte("bb"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed := OpenScanIndex(indexPath, time.Hour)
+	items, err = Scan(root, ScanOptions{Index: changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, misses := changed.Stats(); hits != 1 || misses != 1 {
+		t.Fatalf("expected only the changed dir to be re-read, got hits=%d misses=%d", hits, misses)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected a changed directory to be re-read, got %+v", items)
+	}
+
+	fresh := NewScanIndex(indexPath, time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: fresh}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := fresh.Stats(); hits != 0 {
+		t.Fatalf("expected a refreshed index to walk everything, got %d hits", hits)
+	}
+}
+
+func TestScanIndexRereadsEntriesOlderThanMaxAge(t *testing.T) {
+	withoutIndexSettle(t)
+	root := t.TempDir()
+	file := filepath.Join(root, "app.log")
+	if err := os.WriteFile(file, []byte("aaaa"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(t.TempDir(), "scan-index")
+	first := OpenScanIndex(indexPath, time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: first}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(make([]byte, 4096)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := Scan(root, ScanOptions{Index: OpenScanIndex(indexPath, time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].SizeBytes != 4 {
+		t.Fatalf("expected the cached size within max age, got %+v", items)
+	}
+
+	expired := OpenScanIndex(indexPath, time.Nanosecond)
+	items, err = Scan(root, ScanOptions{Index: expired})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := expired.Stats(); hits != 0 {
+		t.Fatalf("expected an expired entry to be re-read, got %d hits", hits)
+	}
+	if len(items) != 1 || items[0].SizeBytes != 4100 {
+		t.Fatalf("expected the current size after max age, got %+v", items)
+	}
+}
+
+func TestScanIndexDoesNotCacheRecentlyChangedDirectories(t *testing.T) {
+	root := t.TempDir()
+	idx := OpenScanIndex(filepath.Join(t.TempDir(), "scan-index"), time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: idx}); err != nil {
+		t.Fatal(err)
+	}
+	if dirs := indexedDirs(idx); len(dirs) != 0 {
+		t.Fatalf("expected a just-created directory not to be cached, got %v", dirs)
+	}
+}
+
+func TestScanIndexToleratesCorruptShard(t *testing.T) {
+	withoutIndexSettle(t)
+	root := t.TempDir()
+	indexPath := filepath.Join(t.TempDir(), "scan-index")
+	idx := OpenScanIndex(indexPath, time.Hour)
+	if err := os.MkdirAll(indexPath, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(idx.shardPath(shardKey(root)), []byte("not gob"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Scan(root, ScanOptions{Index: idx}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, misses := idx.Stats(); hits != 0 || misses != 1 {
+		t.Fatalf("expected a corrupt shard to read as empty, got hits=%d misses=%d", hits, misses)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loadShard(idx.shardPath(shardKey(root)), shardKey(root)); !ok {
+		t.Fatal("expected the corrupt shard to be rewritten")
+	}
+}
+
+func TestScanIndexShardsByLeadingPathComponents(t *testing.T) {
+	withoutIndexSettle(t)
+	root := t.TempDir()
+	prev := scanIndexShardDepth
+	scanIndexShardDepth = strings.Count(filepath.ToSlash(root), "/") + 1
+	t.Cleanup(func() { scanIndexShardDepth = prev })
+	for _, d := range []string{"a/deep", "b"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexPath := filepath.Join(t.TempDir(), "scan-index")
+	idx := OpenScanIndex(indexPath, time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: idx}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected one shard each for root, a and b, got %d", len(entries))
+	}
+
+	partial := OpenScanIndex(indexPath, time.Hour)
+	if _, err := Scan(filepath.Join(root, "a"), ScanOptions{Index: partial}); err != nil {
+		t.Fatal(err)
+	}
+	if len(partial.shards) != 1 {
+		t.Fatalf("expected only the shard for a to be loaded, got %d", len(partial.shards))
+	}
+	if hits, misses := partial.Stats(); hits != 2 || misses != 0 {
+		t.Fatalf("expected a and a/deep to be reused, got hits=%d misses=%d", hits, misses)
+	}
+}
+
+func TestScanIndexPrunesDeletedDirectories(t *testing.T) {
+	withoutIndexSettle(t)
+	root := t.TempDir()
+	prev := scanIndexShardDepth
+	scanIndexShardDepth = strings.Count(filepath.ToSlash(root), "/") + 1
+	t.Cleanup(func() { scanIndexShardDepth = prev })
+	gone := filepath.Join(root, "gone")
+	if err := os.MkdirAll(filepath.Join(gone, "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(t.TempDir(), "scan-index")
+	idx := OpenScanIndex(indexPath, time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: idx}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !indexedDirs(idx)[gone] {
+		t.Fatalf("expected %s to be indexed", gone)
+	}
+	if err := os.RemoveAll(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	next := OpenScanIndex(indexPath, time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: next}); err != nil {
+		t.Fatal(err)
+	}
+	if err := next.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(next.shardPath(gone)); !os.IsNotExist(err) {
+		t.Fatalf("expected the shard for %s to be removed, got %v", gone, err)
+	}
+}
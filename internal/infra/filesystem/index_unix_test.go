@@ -0,0 +1,44 @@
+//go:build unix
+// +build unix
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanIndexDetectsResetDirectoryMtime(t *testing.T) {
+	withoutIndexSettle(t)
+	root := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(root, old, old); err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(t.TempDir(), "scan-index")
+	first := OpenScanIndex(indexPath, time.Hour)
+	if _, err := Scan(root, ScanOptions{Index: first}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(root, "hidden.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(root, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := Scan(root, ScanOptions{Index: OpenScanIndex(indexPath, time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected a changed ctime to trigger a re-read, got %+v", items)
+	}
+}
@@ -0,0 +1,85 @@
+package filesystem
+
+type linkKey struct {
+	dev uint64
+	ino uint64
+}
+
+type linkShare struct {
+	apparent  int64
+	allocated int64
+	links     uint64
+	seen      uint64
+}
+
+type linkSet map[linkKey]*linkShare
+
+func (s linkSet) add(dev, ino, links uint64, apparent, allocated int64) bool {
+	key := linkKey{dev: dev, ino: ino}
+	if sh, ok := s[key]; ok {
+		sh.seen++
+		return false
+	}
+	s[key] = &linkShare{apparent: apparent, allocated: allocated, links: links, seen: 1}
+	return true
+}
+
+func (s linkSet) merge(o linkSet) (dupApparent, dupAllocated int64) {
+	for key, sh := range o {
+		if cur, ok := s[key]; ok {
+			cur.seen += sh.seen
+			dupApparent += sh.apparent
+			dupAllocated += sh.allocated
+			continue
+		}
+		cp := *sh
+		s[key] = &cp
+	}
+	return dupApparent, dupAllocated
+}
+
+func (s linkSet) prune() linkSet {
+	for key, sh := range s {
+		if sh.seen >= sh.links {
+			delete(s, key)
+		}
+	}
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func (s linkSet) outside() (links int64, apparent, allocated int64) {
+	for _, sh := range s {
+		links += int64(sh.links - sh.seen)
+		apparent += sh.apparent
+		allocated += sh.allocated
+	}
+	return links, apparent, allocated
+}
+
+func isHardLinked(links uint64, dev, ino uint64) bool {
+	return links > 1 && (dev != 0 || ino != 0)
+}
+
+func Reclaimable(usages []Usage) Usage {
+	var total Usage
+	merged := linkSet{}
+	for _, u := range usages {
+		total.ApparentBytes += u.ApparentBytes
+		total.AllocatedBytes += u.AllocatedBytes
+		total.FileCount += u.FileCount
+		if u.LastModified.After(total.LastModified) {
+			total.LastModified = u.LastModified
+		}
+		dupApparent, dupAllocated := merged.merge(u.links)
+		total.ApparentBytes -= dupApparent
+		total.AllocatedBytes -= dupAllocated
+	}
+	links, apparent, allocated := merged.prune().outside()
+	total.SharedLinks = links
+	total.ApparentBytes -= apparent
+	total.AllocatedBytes -= allocated
+	return total
+}
@@ -0,0 +1,98 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBuildTreeCountsHardLinkedFilesOnce(t *testing.T) {
+	items := []ScanItem{
+		{Path: "/r/store", IsDir: true},
+		{Path: "/r/store/pkg.js", SizeBytes: 100, Device: 1, Inode: 7, Links: 2},
+		{Path: "/r/app", IsDir: true},
+		{Path: "/r/app/node_modules", IsDir: true},
+		{Path: "/r/app/node_modules/pkg.js", SizeBytes: 100, Device: 1, Inode: 7, Links: 2},
+		{Path: "/r/app/node_modules/own.js", SizeBytes: 5, Device: 1, Inode: 8, Links: 1},
+	}
+	tree := BuildTree("/r", items, 0)
+	if tree.SizeBytes != 105 || tree.FileCount != 3 || tree.SharedLinks != 0 {
+		t.Fatalf("unexpected root totals: size=%d files=%d shared=%d", tree.SizeBytes, tree.FileCount, tree.SharedLinks)
+	}
+
+	nodes := map[string]*TreeNode{}
+	var walk func(n *TreeNode)
+	walk = func(n *TreeNode) {
+		nodes[n.Path] = n
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(tree)
+	store, modules := nodes["/r/store"], nodes["/r/app/node_modules"]
+	if store.SizeBytes != 100 || store.SharedLinks != 1 || modules.SizeBytes != 105 || modules.SharedLinks != 1 {
+		t.Fatalf("unexpected link accounting: store=%+v modules=%+v", store, modules)
+	}
+
+	if got := ReclaimableNodes([]*TreeNode{modules}).ApparentBytes; got != 5 {
+		t.Fatalf("deleting one link should only free unshared bytes, got %d", got)
+	}
+	if got := ReclaimableNodes([]*TreeNode{store, modules}).ApparentBytes; got != 105 {
+		t.Fatalf("deleting every link should free the file once, got %d", got)
+	}
+}
+
+func TestBuildTreeDedupesHardLinksFoldedBelowDepth(t *testing.T) {
+	items := []ScanItem{
+		{Path: "/r/a", IsDir: true},
+		{Path: "/r/a/x/one", SizeBytes: 50, Device: 1, Inode: 9, Links: 2},
+		{Path: "/r/a/y/two", SizeBytes: 50, Device: 1, Inode: 9, Links: 2},
+	}
+	tree := BuildTree("/r", items, 1)
+	a := tree.Children[0]
+	if a.SizeBytes != 50 || a.FileCount != 2 || a.SharedLinks != 0 {
+		t.Fatalf("unexpected folded totals: %+v", a)
+	}
+	if got := ReclaimableNodes([]*TreeNode{a}).ApparentBytes; got != 50 {
+		t.Fatalf("expected both links inside /r/a to free 50 bytes, got %d", got)
+	}
+}
+
+func TestPathUsageCreditsOnlyFullyContainedLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard link identity is not tracked on windows")
+	}
+	dir := t.TempDir()
+	store := filepath.Join(dir, "store")
+	app := filepath.Join(dir, "app")
+	for _, d := range []string{store, app} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(store, "blob"), make([]byte, 1000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(store, "blob"), filepath.Join(app, "blob")); err != nil {
+		t.Skipf("hard links unsupported: %v", err)
+	}
+	if err := os.Link(filepath.Join(store, "blob"), filepath.Join(app, "blob2")); err != nil {
+		t.Fatal(err)
+	}
+
+	appUsage := PathUsage(app)
+	if appUsage.ApparentBytes != 1000 || appUsage.FileCount != 2 || appUsage.SharedLinks != 1 {
+		t.Fatalf("unexpected app usage: %+v", appUsage)
+	}
+	if got := Reclaimable([]Usage{appUsage}).ApparentBytes; got != 0 {
+		t.Fatalf("store still links the blob, expected 0 reclaimable bytes, got %d", got)
+	}
+	both := Reclaimable([]Usage{appUsage, PathUsage(store)})
+	if both.ApparentBytes != 1000 || both.SharedLinks != 0 {
+		t.Fatalf("expected the blob to be credited once, got %+v", both)
+	}
+	if whole := PathUsage(dir); whole.ApparentBytes != 1000 || whole.SharedLinks != 0 {
+		t.Fatalf("expected parent usage to count the blob once, got %+v", whole)
+	}
+}
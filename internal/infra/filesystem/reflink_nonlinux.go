@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package filesystem
+
+import (
+	"errors"
+	"os"
+)
+
+func cloneFile(dst, src *os.File) error {
+	_ = dst
+	_ = src
+	return errors.New("reflink is not supported on this platform")
+}
@@ -3,23 +3,29 @@ package filesystem
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var readMountPointsFunc = readMountPoints
 
 type ScanItem struct {
-	Path         string
-	SizeBytes    int64
-	LastModified time.Time
-	Device       uint64
-	Inode        uint64
+	Path           string
+	SizeBytes      int64
+	AllocatedBytes int64
+	LastModified   time.Time
+	Device         uint64
+	Inode          uint64
+	Links          uint64
+	UID            uint32
+	IsDir          bool
 }
 
 type ScanOptions struct {
@@ -29,9 +35,32 @@ type ScanOptions struct {
 	Timeout        time.Duration
 	SkipNetworkFS  bool
 	SkipMountpoint bool
+	IncludeDirs    bool
+	Index          *ScanIndex
+	Progress       func(ScanProgress)
+	ProgressEvery  time.Duration
 	Context        context.Context
 }
 
+type ScanProgress struct {
+	DirsVisited int64
+	FilesSeen   int64
+	BytesSeen   int64
+	CurrentPath string
+	Done        bool
+}
+
+type PartialScanError struct {
+	Unvisited []string
+	Err       error
+}
+
+func (e *PartialScanError) Error() string {
+	return fmt.Sprintf("scan incomplete, %d directories not visited: %v", len(e.Unvisited), e.Err)
+}
+
+func (e *PartialScanError) Unwrap() error { return e.Err }
+
 func Scan(root string, opts ScanOptions) ([]ScanItem, error) {
 	if opts.Concurrency <= 0 {
 		opts.Concurrency = runtime.NumCPU()
@@ -71,9 +100,24 @@ func Scan(root string, opts ScanOptions) ([]ScanItem, error) {
 	defer cancel()
 
 	var (
-		itemsMu sync.Mutex
-		items   = make([]ScanItem, 0, 256)
+		itemsMu     sync.Mutex
+		items       = make([]ScanItem, 0, 256)
+		interrupted []string
+		dirsVisited atomic.Int64
+		filesSeen   atomic.Int64
+		bytesSeen   atomic.Int64
+		currentPath atomic.Value
 	)
+	currentPath.Store(rootAbs)
+	snapshot := func(finished bool) ScanProgress {
+		return ScanProgress{
+			DirsVisited: dirsVisited.Load(),
+			FilesSeen:   filesSeen.Load(),
+			BytesSeen:   bytesSeen.Load(),
+			CurrentPath: currentPath.Load().(string),
+			Done:        finished,
+		}
+	}
 
 	type dirQueue struct {
 		mu      sync.Mutex
@@ -140,9 +184,13 @@ func Scan(root string, opts ScanOptions) ([]ScanItem, error) {
 
 					select {
 					case <-ctx.Done():
+						itemsMu.Lock()
+						interrupted = append(interrupted, dir)
+						itemsMu.Unlock()
 						return
 					default:
 					}
+					currentPath.Store(dir)
 
 					resolvedDir := dir
 					if r, err := filepath.EvalSymlinks(dir); err == nil {
@@ -152,19 +200,28 @@ func Scan(root string, opts ScanOptions) ([]ScanItem, error) {
 						return
 					}
 
-					entries, err := os.ReadDir(dir)
-					if err != nil {
-						return
+					children, stamp, cached := opts.Index.lookup(dir)
+					if !cached {
+						var err error
+						children, err = readChildren(dir)
+						if err != nil {
+							return
+						}
+						opts.Index.store(dir, stamp, children)
 					}
 
-					for _, entry := range entries {
+					dirsVisited.Add(1)
+					for _, child := range children {
 						select {
 						case <-ctx.Done():
+							itemsMu.Lock()
+							interrupted = append(interrupted, dir)
+							itemsMu.Unlock()
 							return
 						default:
 						}
 
-						path := filepath.Clean(filepath.Join(dir, entry.Name()))
+						path := child.Path
 
 						if shouldSkip(path, excludes) {
 							continue
@@ -178,29 +235,20 @@ func Scan(root string, opts ScanOptions) ([]ScanItem, error) {
 							continue
 						}
 
-						if entry.Type()&os.ModeSymlink != 0 {
-							continue
-						}
-
-						info, err := entry.Info()
-						if err != nil || info == nil {
-							continue
-						}
-						dev, ino := statIdentity(info)
-
-						if info.IsDir() {
+						if child.IsDir {
+							if opts.IncludeDirs {
+								itemsMu.Lock()
+								items = append(items, child)
+								itemsMu.Unlock()
+							}
 							push(path)
 							continue
 						}
 
+						filesSeen.Add(1)
+						bytesSeen.Add(child.SizeBytes)
 						itemsMu.Lock()
-						items = append(items, ScanItem{
-							Path:         path,
-							SizeBytes:    info.Size(),
-							LastModified: info.ModTime().UTC(),
-							Device:       dev,
-							Inode:        ino,
-						})
+						items = append(items, child)
 						itemsMu.Unlock()
 					}
 				}()
@@ -215,19 +263,89 @@ func Scan(root string, opts ScanOptions) ([]ScanItem, error) {
 		q.mu.Unlock()
 	}()
 
+	stopProgress := make(chan struct{})
+	var progressDone sync.WaitGroup
+	if opts.Progress != nil {
+		every := opts.ProgressEvery
+		if every <= 0 {
+			every = 200 * time.Millisecond
+		}
+		progressDone.Add(1)
+		go func() {
+			defer progressDone.Done()
+			ticker := time.NewTicker(every)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stopProgress:
+					return
+				case <-ticker.C:
+					opts.Progress(snapshot(false))
+				}
+			}
+		}()
+	}
+
 	workers.Wait()
+	close(stopProgress)
+	progressDone.Wait()
+	if opts.Progress != nil {
+		opts.Progress(snapshot(true))
+	}
 
 	sort.Slice(items, func(i, j int) bool {
 		return items[i].Path < items[j].Path
 	})
 
 	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-		return items, context.DeadlineExceeded
+		unvisited := append(interrupted, q.dirs...)
+		sort.Strings(unvisited)
+		return items, &PartialScanError{Unvisited: unvisited, Err: context.DeadlineExceeded}
+	}
+	if ctx.Err() == nil {
+		opts.Index.prune(rootAbs)
 	}
 
 	return items, nil
 }
 
+func readChildren(dir string) ([]ScanItem, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	children := make([]ScanItem, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info == nil {
+			continue
+		}
+		children = append(children, scanItemFor(filepath.Clean(filepath.Join(dir, entry.Name())), info))
+	}
+	return children, nil
+}
+
+func scanItemFor(path string, info os.FileInfo) ScanItem {
+	dev, ino := statIdentity(info)
+	item := ScanItem{
+		Path:           path,
+		AllocatedBytes: allocatedSize(info),
+		LastModified:   info.ModTime().UTC(),
+		Device:         dev,
+		Inode:          ino,
+		UID:            fileOwner(info),
+		IsDir:          info.IsDir(),
+	}
+	if !item.IsDir {
+		item.SizeBytes = info.Size()
+		item.Links = linkCount(info)
+	}
+	return item
+}
+
 func shouldSkipMount(path, root string, opts ScanOptions, mounts map[string]string) bool {
 	if path == root {
 		return false
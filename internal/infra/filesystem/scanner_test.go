@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
@@ -136,3 +137,55 @@ func TestScanFailsWhenMountMetadataUnavailableAndSkippingRequested(t *testing.T)
 		t.Fatalf("expected scan to fail when mount metadata unavailable and skip mountpoint requested")
 	}
 }
+
+func TestScanReportsProgressAndFinalDone(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "b.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var events []ScanProgress
+	_, err := Scan(root, ScanOptions{MaxDepth: 4, Concurrency: 2, Timeout: time.Second, Progress: func(p ScanProgress) {
+		events = append(events, p)
+	}})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(events) == 0 {
+		t.Fatalf("expected progress events")
+	}
+	last := events[len(events)-1]
+	if !last.Done {
+		t.Fatalf("expected final event to be done, got %+v", last)
+	}
+	if last.DirsVisited != 2 || last.FilesSeen != 2 || last.BytesSeen != 8 {
+		t.Fatalf("unexpected final counters: %+v", last)
+	}
+}
+
+func TestScanReturnsPartialResultOnDeadline(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := Scan(root, ScanOptions{MaxDepth: 4, Concurrency: 2, Timeout: time.Second, Context: ctx})
+	var partial *PartialScanError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected partial scan error, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if len(partial.Unvisited) != 1 || partial.Unvisited[0] != root {
+		t.Fatalf("expected root to be unvisited, got %v", partial.Unvisited)
+	}
+}
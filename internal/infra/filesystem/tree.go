@@ -0,0 +1,175 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type TreeNode struct {
+	Path           string
+	IsDir          bool
+	SizeBytes      int64
+	AllocatedBytes int64
+	FileCount      int64
+	LastModified   time.Time
+	Device         uint64
+	Inode          uint64
+	UID            uint32
+	Links          uint64
+	SharedLinks    int64
+	Children       []*TreeNode
+	links          linkSet
+}
+
+func (n *TreeNode) Bytes(mode string) int64 {
+	if mode == SizeModeDisk {
+		return n.AllocatedBytes
+	}
+	return n.SizeBytes
+}
+
+func (n *TreeNode) Usage() Usage {
+	return Usage{
+		ApparentBytes:  n.SizeBytes,
+		AllocatedBytes: n.AllocatedBytes,
+		FileCount:      n.FileCount,
+		SharedLinks:    n.SharedLinks,
+		LastModified:   n.LastModified,
+		links:          n.links,
+	}
+}
+
+func ReclaimableNodes(nodes []*TreeNode) Usage {
+	usages := make([]Usage, 0, len(nodes))
+	for _, n := range nodes {
+		usages = append(usages, n.Usage())
+	}
+	return Reclaimable(usages)
+}
+
+func BuildTree(root string, items []ScanItem, maxDepth int) *TreeNode {
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		rootAbs = root
+	}
+	rootAbs = filepath.Clean(rootAbs)
+	rootNode := &TreeNode{Path: rootAbs, IsDir: true}
+	if info, err := os.Lstat(rootAbs); err == nil {
+		rootNode.LastModified = info.ModTime().UTC()
+		rootNode.Device, rootNode.Inode = statIdentity(info)
+		rootNode.UID = fileOwner(info)
+		rootNode.AllocatedBytes = allocatedSize(info)
+	}
+
+	index := map[string]*TreeNode{rootAbs: rootNode}
+	var dirNode func(path string) *TreeNode
+	dirNode = func(path string) *TreeNode {
+		if n, ok := index[path]; ok {
+			return n
+		}
+		parentPath := filepath.Dir(path)
+		if parentPath == path || !withinRoot(path, rootAbs) {
+			return rootNode
+		}
+		parent := dirNode(parentPath)
+		n := &TreeNode{Path: path, IsDir: true}
+		parent.Children = append(parent.Children, n)
+		index[path] = n
+		return n
+	}
+
+	for _, it := range items {
+		path := filepath.Clean(it.Path)
+		if path == rootAbs || !withinRoot(path, rootAbs) {
+			continue
+		}
+		if maxDepth > 0 && depth(rootAbs, path) > maxDepth {
+			n := dirNode(ancestorAtDepth(rootAbs, path, maxDepth))
+			if it.IsDir {
+				n.AllocatedBytes += it.AllocatedBytes
+			} else {
+				n.FileCount++
+				if isHardLinked(it.Links, it.Device, it.Inode) {
+					if n.links == nil {
+						n.links = linkSet{}
+					}
+					if !n.links.add(it.Device, it.Inode, it.Links, it.SizeBytes, it.AllocatedBytes) {
+						continue
+					}
+				}
+				n.SizeBytes += it.SizeBytes
+				n.AllocatedBytes += it.AllocatedBytes
+			}
+			if it.LastModified.After(n.LastModified) {
+				n.LastModified = it.LastModified
+			}
+			continue
+		}
+		if it.IsDir {
+			n := dirNode(path)
+			n.Device, n.Inode = it.Device, it.Inode
+			n.UID = it.UID
+			n.AllocatedBytes += it.AllocatedBytes
+			if it.LastModified.After(n.LastModified) {
+				n.LastModified = it.LastModified
+			}
+			continue
+		}
+		parent := dirNode(filepath.Dir(path))
+		leaf := &TreeNode{
+			Path:           path,
+			SizeBytes:      it.SizeBytes,
+			AllocatedBytes: it.AllocatedBytes,
+			FileCount:      1,
+			LastModified:   it.LastModified,
+			Device:         it.Device,
+			Inode:          it.Inode,
+			UID:            it.UID,
+			Links:          it.Links,
+		}
+		if isHardLinked(it.Links, it.Device, it.Inode) {
+			leaf.links = linkSet{}
+			leaf.links.add(it.Device, it.Inode, it.Links, it.SizeBytes, it.AllocatedBytes)
+		}
+		parent.Children = append(parent.Children, leaf)
+	}
+
+	aggregate(rootNode)
+	return rootNode
+}
+
+func aggregate(n *TreeNode) {
+	for _, c := range n.Children {
+		aggregate(c)
+		n.SizeBytes += c.SizeBytes
+		n.AllocatedBytes += c.AllocatedBytes
+		n.FileCount += c.FileCount
+		if c.LastModified.After(n.LastModified) {
+			n.LastModified = c.LastModified
+		}
+		if c.links != nil {
+			if n.links == nil {
+				n.links = linkSet{}
+			}
+			dupApparent, dupAllocated := n.links.merge(c.links)
+			n.SizeBytes -= dupApparent
+			n.AllocatedBytes -= dupAllocated
+		}
+	}
+	n.links = n.links.prune()
+	n.SharedLinks, _, _ = n.links.outside()
+}
+
+func ancestorAtDepth(root, path string, d int) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return root
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if len(parts) > d {
+		parts = parts[:d]
+	}
+	return filepath.Join(append([]string{root}, parts...)...)
+}
@@ -0,0 +1,66 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildTreeAggregatesAndFoldsBelowDepth(t *testing.T) {
+	root := "/r"
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []ScanItem{
+		{Path: "/r/a", IsDir: true, LastModified: t0},
+		{Path: "/r/a/b", IsDir: true, LastModified: t0},
+		{Path: "/r/a/b/c", IsDir: true, LastModified: t0},
+		{Path: "/r/a/b/c/deep.bin", SizeBytes: 100, LastModified: t0.Add(time.Hour)},
+		{Path: "/r/a/b/f.bin", SizeBytes: 10, LastModified: t0},
+		{Path: "/r/top.bin", SizeBytes: 1, LastModified: t0},
+	}
+	tree := BuildTree(root, items, 2)
+
+	if tree.SizeBytes != 111 || tree.FileCount != 3 {
+		t.Fatalf("unexpected root totals: size=%d files=%d", tree.SizeBytes, tree.FileCount)
+	}
+	if len(tree.Children) != 2 {
+		t.Fatalf("expected two root children, got %d", len(tree.Children))
+	}
+	var a *TreeNode
+	for _, c := range tree.Children {
+		if c.Path == filepath.Clean("/r/a") {
+			a = c
+		}
+	}
+	if a == nil || len(a.Children) != 1 {
+		t.Fatalf("expected /r/a with one child, got %+v", a)
+	}
+	b := a.Children[0]
+	if b.Path != "/r/a/b" || len(b.Children) != 0 {
+		t.Fatalf("expected /r/a/b to be folded at depth 2, got %+v", b)
+	}
+	if b.SizeBytes != 110 || b.FileCount != 2 || !b.LastModified.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("unexpected folded totals: %+v", b)
+	}
+	if a.SizeBytes != 110 || !a.LastModified.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("expected cumulative size and newest mtime on /r/a, got %+v", a)
+	}
+}
+
+func TestBuildTreeAggregatesAllocatedBytes(t *testing.T) {
+	items := []ScanItem{
+		{Path: "/r/a", IsDir: true, AllocatedBytes: 4096},
+		{Path: "/r/a/sparse.img", SizeBytes: 1 << 20, AllocatedBytes: 8192},
+		{Path: "/r/a/b/deep.txt", SizeBytes: 10, AllocatedBytes: 4096},
+	}
+	tree := BuildTree("/r", items, 1)
+	if len(tree.Children) != 1 {
+		t.Fatalf("expected one root child, got %d", len(tree.Children))
+	}
+	a := tree.Children[0]
+	if a.SizeBytes != 1<<20+10 || a.AllocatedBytes != 16384 {
+		t.Fatalf("unexpected /r/a totals: apparent=%d allocated=%d", a.SizeBytes, a.AllocatedBytes)
+	}
+	if a.Bytes(SizeModeDisk) != a.AllocatedBytes || a.Bytes(SizeModeApparent) != a.SizeBytes {
+		t.Fatalf("Bytes did not follow size mode: %+v", a)
+	}
+}
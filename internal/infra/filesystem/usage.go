@@ -0,0 +1,70 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+const (
+	SizeModeApparent = "apparent"
+	SizeModeDisk     = "disk"
+)
+
+type Usage struct {
+	ApparentBytes  int64
+	AllocatedBytes int64
+	FileCount      int64
+	SharedLinks    int64
+	LastModified   time.Time
+	links          linkSet
+}
+
+func (u Usage) Bytes(mode string) int64 {
+	if mode == SizeModeDisk {
+		return u.AllocatedBytes
+	}
+	return u.ApparentBytes
+}
+
+func ValidateSizeMode(mode string) error {
+	switch mode {
+	case "", SizeModeApparent, SizeModeDisk:
+		return nil
+	}
+	return fmt.Errorf("size mode must be %s or %s, got %q", SizeModeApparent, SizeModeDisk, mode)
+}
+
+func PathUsage(path string) Usage {
+	var u Usage
+	links := linkSet{}
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().After(u.LastModified) {
+			u.LastModified = info.ModTime()
+		}
+		if d.IsDir() {
+			u.AllocatedBytes += allocatedSize(info)
+			return nil
+		}
+		u.FileCount++
+		if dev, ino := statIdentity(info); isHardLinked(linkCount(info), dev, ino) {
+			if !links.add(dev, ino, linkCount(info), info.Size(), allocatedSize(info)) {
+				return nil
+			}
+		}
+		u.ApparentBytes += info.Size()
+		u.AllocatedBytes += allocatedSize(info)
+		return nil
+	})
+	u.links = links.prune()
+	u.SharedLinks, _, _ = u.links.outside()
+	return u
+}
@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathUsageReportsApparentAndAllocatedSize(t *testing.T) {
+	dir := t.TempDir()
+	sparse := filepath.Join(dir, "sparse.img")
+	f, err := os.Create(sparse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(64 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "small.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(sparse, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	u := PathUsage(dir)
+	if u.ApparentBytes != 64<<20+5 || u.FileCount != 2 {
+		t.Fatalf("unexpected apparent usage: %+v", u)
+	}
+	if u.Bytes(SizeModeApparent) != u.ApparentBytes || u.Bytes("") != u.ApparentBytes || u.Bytes(SizeModeDisk) != u.AllocatedBytes {
+		t.Fatalf("Bytes did not follow size mode: %+v", u)
+	}
+	if u.AllocatedBytes >= u.ApparentBytes {
+		t.Skipf("filesystem does not keep sparse files sparse: %+v", u)
+	}
+}
+
+func TestPathUsageMissingPathIsZero(t *testing.T) {
+	u := PathUsage(filepath.Join(t.TempDir(), "missing"))
+	if u.ApparentBytes != 0 || u.AllocatedBytes != 0 || u.FileCount != 0 || !u.LastModified.IsZero() {
+		t.Fatalf("expected zero usage, got %+v", u)
+	}
+}
+
+func TestValidateSizeMode(t *testing.T) {
+	for _, mode := range []string{"", SizeModeApparent, SizeModeDisk} {
+		if err := ValidateSizeMode(mode); err != nil {
+			t.Fatalf("mode %q: %v", mode, err)
+		}
+	}
+	if err := ValidateSizeMode("blocks"); err == nil {
+		t.Fatal("expected error for unknown size mode")
+	}
+}
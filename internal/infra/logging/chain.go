@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"talpa/internal/domain/model"
+)
+
+var chainGenesis = strings.Repeat("0", sha256.Size*2)
+
+type chainHead struct {
+	LastHash  string    `json:"last_hash"`
+	Anchor    string    `json:"anchor,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type VerifyReport struct {
+	Files          []string     `json:"files"`
+	Entries        int          `json:"entries"`
+	ChainedEntries int          `json:"chained_entries"`
+	HeadChecked    bool         `json:"head_checked"`
+	Valid          bool         `json:"valid"`
+	Issues         []ChainIssue `json:"issues,omitempty"`
+}
+
+type ChainIssue struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+func chainHeadPath(path string) string {
+	return path + ".head"
+}
+
+func writeChainHead(path, lastHash, anchor string, at time.Time) error {
+	b, err := json.Marshal(chainHead{LastHash: lastHash, Anchor: anchor, UpdatedAt: at.UTC()})
+	if err != nil {
+		return err
+	}
+	tmp := chainHeadPath(path) + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, chainHeadPath(path))
+}
+
+func readChainHead(path string) (chainHead, bool, error) {
+	b, err := os.ReadFile(chainHeadPath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return chainHead{}, false, nil
+		}
+		return chainHead{}, false, err
+	}
+	var head chainHead
+	if err := json.Unmarshal(b, &head); err != nil {
+		return chainHead{}, true, err
+	}
+	return head, true, nil
+}
+
+func VerifyChain(ctx context.Context, path string) (VerifyReport, error) {
+	files, err := OperationLogFiles(path)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	report := VerifyReport{Files: []string{}}
+	addIssue := func(file string, line int, reason string) {
+		report.Issues = append(report.Issues, ChainIssue{File: file, Line: line, Reason: reason})
+	}
+	head, headFound, headErr := readChainHead(path)
+	anchor := head.Anchor
+	if anchor == "" {
+		anchor = chainGenesis
+	}
+	prevHash := ""
+	chainStarted := false
+	for _, file := range files {
+		r, closeFn, err := openLogFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return VerifyReport{}, err
+		}
+		report.Files = append(report.Files, file)
+		err = scanLogLines(ctx, r, func(lineNo int, line []byte) error {
+			report.Entries++
+			var entry model.OperationLogEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				addIssue(file, lineNo, "malformed entry")
+			} else if entry.PrevHash != "" {
+				report.ChainedEntries++
+				if !chainStarted && prevHash == "" && entry.PrevHash != anchor {
+					addIssue(file, lineNo, "missing predecessor: earlier entries were removed from the start of the log")
+				} else if prevHash != "" && entry.PrevHash != prevHash {
+					addIssue(file, lineNo, "prev_hash mismatch: previous entry was edited, removed, or reordered")
+				}
+				chainStarted = true
+			} else if chainStarted {
+				addIssue(file, lineNo, "missing prev_hash after hash chain start")
+			}
+			prevHash = hashLine(line)
+			return nil
+		})
+		closeFn()
+		if err != nil {
+			return VerifyReport{}, err
+		}
+	}
+
+	if headErr != nil {
+		addIssue(chainHeadPath(path), 0, "unreadable chain head")
+	} else if headFound {
+		report.HeadChecked = true
+		if head.LastHash != prevHash {
+			addIssue(chainHeadPath(path), 0, "chain head mismatch: log was truncated or appended outside talpa")
+		}
+	}
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+// +build !unix
+
+package logging
+
+import "os"
+
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -1,8 +1,12 @@
 package logging
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -21,45 +25,85 @@ func (n noopLogger) Log(context.Context, model.OperationLogEntry) error { return
 
 func NewNoopLogger() Logger { return noopLogger{} }
 
+type Options struct {
+	Path      string
+	MaxBytes  int64
+	MaxAge    time.Duration
+	Retention time.Duration
+	HashChain bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxBytes: 10 << 20,
+		MaxAge:   30 * 24 * time.Hour,
+	}
+}
+
 type operationLogger struct {
-	mu   sync.Mutex
-	file *os.File
+	mu       sync.Mutex
+	file     *os.File
+	lock     *os.File
+	opts     Options
+	size     int64
+	started  time.Time
+	lastHash string
+	anchor   string
+	now      func() time.Time
 }
 
-func NewOperationLogger(ctx context.Context, disabled bool) (Logger, error) {
+func NewOperationLoggerWithOptions(ctx context.Context, disabled bool, opts Options) (Logger, error) {
 	if disabled {
 		return noopLogger{}, nil
 	}
 
-	configHome := os.Getenv("XDG_CONFIG_HOME")
-	if configHome == "" {
-		home, err := os.UserHomeDir()
+	if opts.Path == "" {
+		path, err := OperationLogPath()
 		if err != nil {
 			return nil, err
 		}
-		configHome = filepath.Join(home, ".config")
+		opts.Path = path
 	}
-
-	dir := filepath.Join(configHome, "talpa")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
 		return nil, err
 	}
 
-	f, err := os.OpenFile(filepath.Join(dir, "operations.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	lock, err := os.OpenFile(opts.Path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
 	if err != nil {
 		return nil, err
 	}
+	l := &operationLogger{opts: opts, lock: lock, now: func() time.Time { return time.Now().UTC() }}
+	if err := l.open(); err != nil {
+		_ = lock.Close()
+		return nil, err
+	}
 
 	_ = ctx
-	return &operationLogger{file: f}, nil
+	return l, nil
 }
 
 func (l *operationLogger) Log(_ context.Context, entry model.OperationLogEntry) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if err := lockFile(l.lock); err != nil {
+		return err
+	}
+	defer func() { _ = unlockFile(l.lock) }()
+	if err := l.sync(); err != nil {
+		return err
+	}
 
 	if entry.Timestamp.IsZero() {
-		entry.Timestamp = time.Now().UTC()
+		entry.Timestamp = l.now()
+	}
+	if l.opts.HashChain {
+		if head, ok, err := readChainHead(l.opts.Path); err == nil && ok {
+			l.anchor = head.Anchor
+		}
+		entry.PrevHash = l.lastHash
+		if entry.PrevHash == "" {
+			entry.PrevHash = chainGenesis
+		}
 	}
 
 	b, err := json.Marshal(entry)
@@ -67,6 +111,187 @@ func (l *operationLogger) Log(_ context.Context, entry model.OperationLogEntry)
 		return err
 	}
 
-	_, err = l.file.Write(append(b, '\n'))
-	return err
+	if l.shouldRotate(int64(len(b)+1), entry.Timestamp) {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(append(b, '\n'))
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if l.started.IsZero() {
+		l.started = entry.Timestamp
+	}
+	l.lastHash = hashLine(b)
+	if l.opts.HashChain {
+		return writeChainHead(l.opts.Path, l.lastHash, l.anchor, entry.Timestamp)
+	}
+	return nil
+}
+
+func (l *operationLogger) sync() error {
+	st, err := os.Stat(l.opts.Path)
+	if err == nil {
+		if cur, err := l.file.Stat(); err == nil && os.SameFile(st, cur) {
+			if st.Size() == l.size {
+				return nil
+			}
+			return l.loadTail()
+		}
+	}
+	_ = l.file.Close()
+	return l.open()
+}
+
+func (l *operationLogger) open() error {
+	f, err := os.OpenFile(l.opts.Path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	l.file = f
+	if err := l.loadTail(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return nil
+}
+
+func (l *operationLogger) loadTail() error {
+	st, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	l.size = st.Size()
+	l.started = time.Time{}
+	l.lastHash = ""
+	if l.size > 0 {
+		if first, err := readFirstLine(l.file); err == nil {
+			var e model.OperationLogEntry
+			if json.Unmarshal(first, &e) == nil {
+				l.started = e.Timestamp
+			}
+		}
+		if last, err := readLastLine(l.file, l.size); err == nil && len(last) > 0 {
+			l.lastHash = hashLine(last)
+		}
+		return nil
+	}
+	if l.opts.HashChain {
+		l.lastHash = lastArchivedHash(l.opts.Path)
+	}
+	return nil
+}
+
+func OperationLogPath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "talpa", "operations.log"), nil
+}
+
+func ReadOperationLog(ctx context.Context, path string) ([]model.OperationLogEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return decodeOperationLog(ctx, f)
+}
+
+func ReadAllOperationLogs(ctx context.Context, path string) ([]model.OperationLogEntry, error) {
+	files, err := OperationLogFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []model.OperationLogEntry
+	for _, file := range files {
+		r, closeFn, err := openLogFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		entries, err := decodeOperationLog(ctx, r)
+		closeFn()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, entries...)
+	}
+	return out, nil
+}
+
+func decodeOperationLog(ctx context.Context, r io.Reader) ([]model.OperationLogEntry, error) {
+	var out []model.OperationLogEntry
+	err := scanLogLines(ctx, r, func(_ int, line []byte) error {
+		var entry model.OperationLogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil
+		}
+		out = append(out, entry)
+		return nil
+	})
+	return out, err
+}
+
+func scanLogLines(ctx context.Context, r io.Reader, fn func(lineNo int, line []byte) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(lineNo, line); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+func readFirstLine(f *os.File) ([]byte, error) {
+	r := bufio.NewReader(io.NewSectionReader(f, 0, 4*1024*1024))
+	line, err := r.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return bytes.TrimSpace(line), nil
+}
+
+func readLastLine(f *os.File, size int64) ([]byte, error) {
+	chunk := int64(64 * 1024)
+	for {
+		if chunk > size {
+			chunk = size
+		}
+		buf := make([]byte, chunk)
+		if _, err := f.ReadAt(buf, size-chunk); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		trimmed := bytes.TrimRight(buf, "\r\n\t ")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return bytes.TrimSpace(trimmed[i+1:]), nil
+		}
+		if chunk == size {
+			return bytes.TrimSpace(trimmed), nil
+		}
+		chunk *= 2
+	}
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"talpa/internal/domain/model"
 )
@@ -13,7 +15,7 @@ func TestOperationLoggerWritesJSONL(t *testing.T) {
 	configHome := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", configHome)
 
-	logger, err := NewOperationLogger(context.Background(), false)
+	logger, err := NewOperationLoggerWithOptions(context.Background(), false, DefaultOptions())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -37,3 +39,293 @@ func TestOperationLoggerWritesJSONL(t *testing.T) {
 		t.Fatal("expected log content")
 	}
 }
+
+func TestReadOperationLogSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	raw := `{"plan_id":"p1","command":"analyze","action":"trash","path":"/tmp/a","trash_path":"/tmp/t/files/a","result":"trashed"}
+not-json
+
+{"plan_id":"p2","command":"clean","action":"delete","path":"/tmp/b","result":"deleted"}
+`
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadOperationLog(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected two decoded entries, got %d", len(entries))
+	}
+	if entries[0].TrashPath != "/tmp/t/files/a" || entries[1].PlanID != "p2" {
+		t.Fatalf("unexpected decoded entries: %+v", entries)
+	}
+}
+
+func TestReadOperationLogMissingFile(t *testing.T) {
+	entries, err := ReadOperationLog(context.Background(), filepath.Join(t.TempDir(), "missing.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no entries, got %d", len(entries))
+	}
+}
+
+func TestOperationLoggerRotatesBySizeAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "operations.log")
+	logger, err := NewOperationLoggerWithOptions(context.Background(), false, Options{Path: path, MaxBytes: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		if err := logger.Log(context.Background(), model.OperationLogEntry{PlanID: "p1", Command: "clean", Action: "delete", Path: "/tmp/x", Result: "deleted"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archives, err := filepath.Glob(filepath.Join(dir, "operations-*.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) == 0 {
+		t.Fatalf("expected compressed archives after size rotation")
+	}
+	entries, err := ReadAllOperationLogs(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("expected all six entries across archives, got %d", len(entries))
+	}
+}
+
+func TestOperationLoggerRotatesByAgeAndPrunesRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "operations.log")
+	stale := filepath.Join(dir, "operations-20000101T000000Z.log.gz")
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewOperationLoggerWithOptions(context.Background(), false, Options{Path: path, MaxAge: 24 * time.Hour, Retention: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	if err := l.Log(context.Background(), model.OperationLogEntry{Timestamp: old, Command: "clean"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Log(context.Background(), model.OperationLogEntry{Command: "purge"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected archive beyond retention to be pruned")
+	}
+	current, err := ReadOperationLog(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) != 1 || current[0].Command != "purge" {
+		t.Fatalf("expected only the new entry in the active log, got %+v", current)
+	}
+}
+
+func TestVerifyChainDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "operations.log")
+	logger, err := NewOperationLoggerWithOptions(context.Background(), false, Options{Path: path, MaxBytes: 400, HashChain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := logger.Log(context.Background(), model.OperationLogEntry{Command: "clean", Path: "/tmp/x", SizeBytes: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := VerifyChain(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || !report.HeadChecked || report.Entries != 5 {
+		t.Fatalf("expected intact chain across rotation, got %+v", report)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) < 1 {
+		t.Fatalf("expected active log lines")
+	}
+	truncated := strings.Join(lines[:len(lines)-1], "\n")
+	if truncated != "" {
+		truncated += "\n"
+	}
+	if err := os.WriteFile(path, []byte(truncated), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	report, err = VerifyChain(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid {
+		t.Fatalf("expected truncation to be detected")
+	}
+}
+
+func TestVerifyChainDetectsEditedEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	logger, err := NewOperationLoggerWithOptions(context.Background(), false, Options{Path: path, HashChain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int64{10, 20, 30} {
+		if err := logger.Log(context.Background(), model.OperationLogEntry{Command: "purge", SizeBytes: size}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(raw), `"size_bytes":20`, `"size_bytes":2`, 1)
+	if err := os.WriteFile(path, []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	report, err := VerifyChain(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid || len(report.Issues) != 1 || report.Issues[0].Line != 3 {
+		t.Fatalf("expected mismatch reported at line 3, got %+v", report)
+	}
+}
+
+func TestVerifyChainDetectsTruncatedHead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	logger, err := NewOperationLoggerWithOptions(context.Background(), false, Options{Path: path, HashChain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int64{10, 20, 30} {
+		if err := logger.Log(context.Background(), model.OperationLogEntry{Command: "purge", SizeBytes: size}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err := VerifyChain(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || report.ChainedEntries != 3 {
+		t.Fatalf("expected every entry to be chained from genesis, got %+v", report)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rest, _ := strings.Cut(string(raw), "\n")
+	if err := os.WriteFile(path, []byte(rest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, dropHead := range []bool{false, true} {
+		if dropHead {
+			if err := os.Remove(chainHeadPath(path)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		report, err = VerifyChain(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Valid || len(report.Issues) != 1 || report.Issues[0].Line != 1 || !strings.Contains(report.Issues[0].Reason, "missing predecessor") {
+			t.Fatalf("expected missing predecessor at line 1 (head file removed: %v), got %+v", dropHead, report)
+		}
+	}
+}
+
+func TestVerifyChainAcceptsArchivesPrunedByRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	logger, err := NewOperationLoggerWithOptions(context.Background(), false, Options{Path: path, MaxAge: 24 * time.Hour, Retention: 30 * 24 * time.Hour, HashChain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := logger.(*operationLogger)
+	now := time.Now().UTC()
+	for _, at := range []time.Time{now.Add(-40 * 24 * time.Hour), now.Add(-39 * 24 * time.Hour), now} {
+		l.now = func() time.Time { return at }
+		if err := l.Log(context.Background(), model.OperationLogEntry{Timestamp: at, Command: "clean"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archives, err := archiveFiles(path)
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("expected the oldest archive to be pruned, got %v (%v)", archives, err)
+	}
+	report, err := VerifyChain(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || report.Entries != 2 {
+		t.Fatalf("expected retention pruning to keep the chain valid, got %+v", report)
+	}
+}
+
+func TestOperationLoggerChainsAcrossLoggersSharingAFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	opts := Options{Path: path, MaxBytes: 500, HashChain: true}
+	first, err := NewOperationLoggerWithOptions(context.Background(), false, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewOperationLoggerWithOptions(context.Background(), false, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		logger := first
+		if i%2 == 1 {
+			logger = second
+		}
+		if err := logger.Log(context.Background(), model.OperationLogEntry{Command: "clean", Path: "/tmp/x", SizeBytes: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err := VerifyChain(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || report.ChainedEntries != 6 || len(report.Files) < 2 {
+		t.Fatalf("expected one chain across interleaved loggers and rotations, got %+v", report)
+	}
+}
+
+func TestOperationLoggerLeavesChainHeadWhenChainIsOff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations.log")
+	head := []byte(`{"last_hash":"abc","updated_at":"2026-01-01T00:00:00Z"}` + "\n")
+	if err := os.WriteFile(chainHeadPath(path), head, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logger, err := NewOperationLoggerWithOptions(context.Background(), false, Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Log(context.Background(), model.OperationLogEntry{Command: "clean"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(chainHeadPath(path))
+	if err != nil || string(got) != string(head) {
+		t.Fatalf("expected the chain head to be left alone, got %q (%v)", got, err)
+	}
+}
+
+func TestDefaultOptionsKeepArchivesForever(t *testing.T) {
+	if r := DefaultOptions().Retention; r != 0 {
+		t.Fatalf("expected retention to be opt-in, got %s", r)
+	}
+}
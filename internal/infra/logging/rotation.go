@@ -0,0 +1,251 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const archiveTimeLayout = "20060102T150405Z"
+
+func (l *operationLogger) shouldRotate(next int64, at time.Time) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.opts.MaxBytes > 0 && l.size+next > l.opts.MaxBytes {
+		return true
+	}
+	if l.opts.MaxAge > 0 && !l.started.IsZero() && at.Sub(l.started) >= l.opts.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (l *operationLogger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	now := l.now()
+	archive, err := reserveArchivePath(l.opts.Path, now)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(l.opts.Path, archive); err != nil {
+		if reopenErr := l.open(); reopenErr != nil {
+			return reopenErr
+		}
+		return err
+	}
+	lastHash := l.lastHash
+	if err := l.open(); err != nil {
+		return err
+	}
+	l.lastHash = lastHash
+	if err := compressArchive(archive); err != nil {
+		return err
+	}
+	pruned, err := pruneArchives(l.opts.Path, l.opts.Retention, now)
+	if pruned != "" {
+		l.anchor = pruned
+	}
+	return err
+}
+
+func archivePrefix(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "-"
+}
+
+func reserveArchivePath(path string, now time.Time) (string, error) {
+	dir := filepath.Dir(path)
+	stem := archivePrefix(path) + now.UTC().Format(archiveTimeLayout)
+	for i := 0; i < 32; i++ {
+		name := stem
+		if i > 0 {
+			name = stem + "-" + strconv.Itoa(i)
+		}
+		candidate := filepath.Join(dir, name+".log")
+		if _, err := os.Lstat(candidate); err == nil {
+			continue
+		}
+		if _, err := os.Lstat(candidate + ".gz"); err == nil {
+			continue
+		}
+		return candidate, nil
+	}
+	return "", os.ErrExist
+}
+
+func compressArchive(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, errCopy := io.Copy(zw, src)
+	errZip := zw.Close()
+	errClose := dst.Close()
+	for _, err := range []error{errCopy, errZip, errClose} {
+		if err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+func pruneArchives(path string, retention time.Duration, now time.Time) (string, error) {
+	if retention <= 0 {
+		return "", nil
+	}
+	archives, err := archiveFiles(path)
+	if err != nil {
+		return "", err
+	}
+	cutoff := now.Add(-retention)
+	lastPruned := ""
+	for _, a := range archives {
+		ts, ok := archiveTime(path, a)
+		if !ok || !ts.Before(cutoff) {
+			continue
+		}
+		last := lastLineHash(a)
+		if err := os.Remove(a); err != nil && !os.IsNotExist(err) {
+			return lastPruned, err
+		}
+		if last != "" {
+			lastPruned = last
+		}
+	}
+	return lastPruned, nil
+}
+
+func archiveTime(path, archive string) (time.Time, bool) {
+	ts, _, ok := archiveOrder(path, archive)
+	return ts, ok
+}
+
+func archiveOrder(path, archive string) (time.Time, int, bool) {
+	name := strings.TrimPrefix(filepath.Base(archive), archivePrefix(path))
+	if len(name) < len(archiveTimeLayout) {
+		return time.Time{}, 0, false
+	}
+	ts, err := time.Parse(archiveTimeLayout, name[:len(archiveTimeLayout)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	rest := name[len(archiveTimeLayout):]
+	seq := 0
+	if strings.HasPrefix(rest, "-") {
+		digits, _, _ := strings.Cut(rest[1:], ".")
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		seq = n
+	}
+	return ts, seq, true
+}
+
+func archiveFiles(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	prefix := archivePrefix(path)
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if !strings.HasSuffix(name, ".log") && !strings.HasSuffix(name, ".log.gz") {
+			continue
+		}
+		if _, ok := archiveTime(path, name); !ok {
+			continue
+		}
+		out = append(out, filepath.Join(filepath.Dir(path), name))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ti, si, _ := archiveOrder(path, out[i])
+		tj, sj, _ := archiveOrder(path, out[j])
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return si < sj
+	})
+	return out, nil
+}
+
+func OperationLogFiles(path string) ([]string, error) {
+	archives, err := archiveFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return append(archives, path), nil
+}
+
+func openLogFile(path string) (io.Reader, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, func() { _ = f.Close() }, nil
+	}
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return zr, func() {
+		_ = zr.Close()
+		_ = f.Close()
+	}, nil
+}
+
+func lastArchivedHash(path string) string {
+	archives, err := archiveFiles(path)
+	if err != nil || len(archives) == 0 {
+		return ""
+	}
+	return lastLineHash(archives[len(archives)-1])
+}
+
+func lastLineHash(file string) string {
+	r, closeFn, err := openLogFile(file)
+	if err != nil {
+		return ""
+	}
+	defer closeFn()
+	var last []byte
+	_ = scanLogLines(context.Background(), r, func(_ int, line []byte) error {
+		last = append(last[:0], line...)
+		return nil
+	})
+	if len(last) == 0 {
+		return ""
+	}
+	return hashLine(last)
+}
@@ -0,0 +1,109 @@
+package planstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"talpa/internal/domain/model"
+)
+
+type Store interface {
+	Save(plan model.Plan) error
+	Load(planID string) (model.Plan, error)
+	MarkApplied(planID string, at time.Time) error
+}
+
+type fileStore struct {
+	dir string
+}
+
+var validPlanID = regexp.MustCompile(`^plan-[A-Za-z0-9-]+$`)
+
+func NewFileStore() (Store, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStoreAt(dir), nil
+}
+
+func NewFileStoreAt(dir string) Store { return &fileStore{dir: dir} }
+
+func DefaultDir() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "talpa", "plans"), nil
+}
+
+func (s *fileStore) Save(plan model.Plan) error {
+	path, err := s.path(plan.PlanID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) Load(planID string) (model.Plan, error) {
+	path, err := s.path(planID)
+	if err != nil {
+		return model.Plan{}, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return model.Plan{}, fmt.Errorf("plan %s not found", planID)
+		}
+		return model.Plan{}, err
+	}
+	var plan model.Plan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return model.Plan{}, fmt.Errorf("plan %s is corrupt: %w", planID, err)
+	}
+	if plan.PlanID != planID {
+		return model.Plan{}, fmt.Errorf("plan %s is corrupt: id mismatch", planID)
+	}
+	return plan, nil
+}
+
+func (s *fileStore) MarkApplied(planID string, at time.Time) error {
+	plan, err := s.Load(planID)
+	if err != nil {
+		return err
+	}
+	applied := at.UTC()
+	plan.AppliedAt = &applied
+	return s.Save(plan)
+}
+
+func (s *fileStore) path(planID string) (string, error) {
+	if !validPlanID.MatchString(planID) {
+		return "", errors.New("PLAN_INVALID: malformed plan id")
+	}
+	return filepath.Join(s.dir, planID+".json"), nil
+}
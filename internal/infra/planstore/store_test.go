@@ -0,0 +1,66 @@
+package planstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"talpa/internal/domain/model"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "plans")
+	s := NewFileStoreAt(dir)
+	plan := model.Plan{
+		SchemaVersion: "1.0",
+		PlanID:        "plan-clean-20260101T000000Z-abcd1234",
+		Command:       "clean",
+		CreatedAt:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Items: []model.PlanItem{{
+			CandidateItem: model.CandidateItem{ID: "clean-1", Path: "/tmp/x", SizeBytes: 42, Selected: true},
+			Action:        "delete",
+			Device:        7,
+			Inode:         99,
+		}},
+	}
+	if err := s.Save(plan); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(filepath.Join(dir, plan.PlanID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o600 {
+		t.Fatalf("expected plan file mode 0600, got %v", st.Mode().Perm())
+	}
+
+	got, err := s.Load(plan.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Command != "clean" || len(got.Items) != 1 || got.Items[0].Inode != 99 || got.Items[0].SizeBytes != 42 {
+		t.Fatalf("unexpected loaded plan: %+v", got)
+	}
+
+	applied := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := s.MarkApplied(plan.PlanID, applied); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.Load(plan.PlanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AppliedAt == nil || !got.AppliedAt.Equal(applied) {
+		t.Fatalf("expected applied_at %v, got %v", applied, got.AppliedAt)
+	}
+}
+
+func TestFileStoreRejectsMalformedIDs(t *testing.T) {
+	s := NewFileStoreAt(t.TempDir())
+	for _, id := range []string{"", "../plan-x", "plan-a/b", "clean"} {
+		if _, err := s.Load(id); err == nil {
+			t.Fatalf("expected %q to be rejected", id)
+		}
+	}
+}
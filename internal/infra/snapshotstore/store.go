@@ -0,0 +1,143 @@
+package snapshotstore
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"talpa/internal/domain/model"
+)
+
+const fileSuffix = ".json.gz"
+
+type Store interface {
+	Save(snap model.Snapshot) error
+	Load(name string) (model.Snapshot, error)
+	List() ([]string, error)
+}
+
+type fileStore struct {
+	dir string
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func NewFileStore() (Store, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStoreAt(dir), nil
+}
+
+func NewFileStoreAt(dir string) Store { return &fileStore{dir: dir} }
+
+func DefaultDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "talpa", "snapshots"), nil
+}
+
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("SNAPSHOT_INVALID: snapshot name %q must start with a letter or digit and use only letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+func (s *fileStore) Save(snap model.Snapshot) error {
+	path, err := s.path(snap.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(zw).Encode(snap); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *fileStore) Load(name string) (model.Snapshot, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return model.Snapshot{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return model.Snapshot{}, fmt.Errorf("snapshot %s not found", name)
+		}
+		return model.Snapshot{}, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return model.Snapshot{}, fmt.Errorf("snapshot %s is corrupt: %w", name, err)
+	}
+	defer zr.Close()
+	var snap model.Snapshot
+	if err := json.NewDecoder(zr).Decode(&snap); err != nil {
+		return model.Snapshot{}, fmt.Errorf("snapshot %s is corrupt: %w", name, err)
+	}
+	if snap.Name != name {
+		return model.Snapshot{}, fmt.Errorf("snapshot %s is corrupt: name mismatch", name)
+	}
+	return snap, nil
+}
+
+func (s *fileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), fileSuffix); ok && validName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *fileStore) path(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, name+fileSuffix), nil
+}
@@ -0,0 +1,71 @@
+package snapshotstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"talpa/internal/domain/model"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	s := NewFileStoreAt(dir)
+	snap := model.Snapshot{
+		SchemaVersion: "1.0",
+		Name:          "before-upgrade",
+		Root:          "/var",
+		CreatedAt:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Entries: []model.SnapshotEntry{
+			{Path: "/var/log", IsDir: true, SizeBytes: 300, AllocatedBytes: 4096, FileCount: 2},
+			{Path: "/var/log/syslog", SizeBytes: 300, AllocatedBytes: 4096, FileCount: 1},
+		},
+	}
+	if err := s.Save(snap); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(filepath.Join(dir, "before-upgrade.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o600 {
+		t.Fatalf("expected snapshot file mode 0600, got %v", st.Mode().Perm())
+	}
+	got, err := s.Load("before-upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Root != "/var" || len(got.Entries) != 2 || got.Entries[1].Path != "/var/log/syslog" || !got.CreatedAt.Equal(snap.CreatedAt) {
+		t.Fatalf("unexpected round trip: %+v", got)
+	}
+	names, err := s.List()
+	if err != nil || len(names) != 1 || names[0] != "before-upgrade" {
+		t.Fatalf("unexpected list: %v %v", names, err)
+	}
+}
+
+func TestFileStoreRejectsBadNamesAndMissingSnapshots(t *testing.T) {
+	s := NewFileStoreAt(t.TempDir())
+	for _, name := range []string{"", "../etc", ".hidden", "a/b"} {
+		if err := s.Save(model.Snapshot{Name: name}); err == nil {
+			t.Fatalf("expected invalid name error for %q", name)
+		}
+	}
+	if _, err := s.Load("missing"); err == nil {
+		t.Fatalf("expected not found error")
+	}
+	if names, err := s.List(); err != nil || len(names) != 0 {
+		t.Fatalf("expected empty list, got %v %v", names, err)
+	}
+}
+
+func TestFileStoreRejectsCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.json.gz"), []byte("not gzip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStoreAt(dir).Load("bad"); err == nil {
+		t.Fatalf("expected corrupt snapshot error")
+	}
+}